@@ -1,25 +1,33 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
 )
 
 type DataGenerator struct {
 	switchCount    int
 	dataCache      []byte
+	gzipCache      []byte
+	zstdCache      []byte
+	zstdEncoder    *zstd.Encoder
 	cacheTime      time.Time
 	mutex          sync.RWMutex
 	generating     bool
 	stopChan       chan struct{}
 	generationID   int64
+	etag           string
 	lastGeneration time.Time
 	ready          bool
 }
@@ -32,9 +40,16 @@ func main() {
 
 	fmt.Printf("Starting eagerptive CSV generator on port %s with %d switches\n", port, switchCount)
 
+	zstdEncoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		fmt.Printf("Failed to create zstd encoder: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Init eager data generator
 	generator = &DataGenerator{
 		switchCount: switchCount,
+		zstdEncoder: zstdEncoder,
 		stopChan:    make(chan struct{}),
 		ready:       false,
 	}
@@ -106,7 +121,7 @@ func (dg *DataGenerator) generateData() {
 	dg.mutex.Unlock()
 
 	now := time.Now()
-	dg.generationID = now.UnixNano()
+	generationID := now.UnixNano()
 
 	estimatedSize := 100 + dg.switchCount*50*150 // ~150 chars per record
 	buf := make([]byte, 0, estimatedSize)
@@ -167,18 +182,91 @@ func (dg *DataGenerator) generateData() {
 		}
 	}
 
+	// Compress once per generation instead of once per request
+	gzipped, err := gzipBytes(buf)
+	if err != nil {
+		fmt.Printf("Failed to gzip generation gen_%d: %v\n", generationID, err)
+	}
+	zstded := dg.zstdEncoder.EncodeAll(buf, nil)
+
 	// Update cache atomically
 	dg.mutex.Lock()
 	dg.dataCache = make([]byte, len(buf))
 	copy(dg.dataCache, buf)
+	dg.gzipCache = gzipped
+	dg.zstdCache = zstded
+	// The ID only changes along with the data it identifies
+	dg.generationID = generationID
+	dg.etag = fmt.Sprintf(`"gen_%d"`, generationID)
 	dg.cacheTime = now
 	dg.lastGeneration = now
 	dg.generating = false
 	dg.ready = true
 	dg.mutex.Unlock()
 
-	fmt.Printf("Generated new batch: %d bytes, %d switches, generation_id=gen_%d at %s\n",
-		len(buf), dg.switchCount, dg.generationID, now.Format(time.RFC3339))
+	fmt.Printf("Generated new batch: %d bytes (gzip %d, zstd %d), %d switches, generation_id=gen_%d at %s\n",
+		len(buf), len(gzipped), len(zstded), dg.switchCount, generationID, now.Format(time.RFC3339))
+}
+
+// gzipBytes compresses data with gzip
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodedBody picks the pre-compressed representation for an Accept-Encoding
+// header by q-value, preferring zstd over gzip on a tie. A coding refused with
+// q=0 is never picked, not even through "*". An empty encoding means identity.
+func (dg *DataGenerator) encodedBody(acceptEncoding string) (string, []byte) {
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		weight := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if q, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if value, err := strconv.ParseFloat(q, 64); err == nil {
+					weight = value
+				}
+			}
+		}
+		weights[coding] = weight
+	}
+
+	// An explicit entry overrides the wildcard
+	weight := func(coding string) float64 {
+		if value, ok := weights[coding]; ok {
+			return value
+		}
+		return weights["*"]
+	}
+
+	zstdWeight, gzipWeight := 0.0, 0.0
+	if len(dg.zstdCache) > 0 {
+		zstdWeight = weight("zstd")
+	}
+	if len(dg.gzipCache) > 0 {
+		gzipWeight = weight("gzip")
+	}
+
+	switch {
+	case zstdWeight > 0 && zstdWeight >= gzipWeight:
+		return "zstd", dg.zstdCache
+	case gzipWeight > 0:
+		return "gzip", dg.gzipCache
+	default:
+		return "", dg.dataCache
+	}
 }
 
 // handleCounters serves pre-generated CSV data immediately
@@ -196,17 +284,47 @@ func handleCounters(c *gin.Context) {
 		return
 	}
 
+	encoding, body := generator.encodedBody(c.GetHeader("Accept-Encoding"))
+
+	// Unchanged generations cost one round-trip without a body. Each encoding
+	// is a separate representation, so it gets its own ETag.
+	etag := generator.etag
+	if encoding != "" {
+		etag = strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+	}
+	c.Header("ETag", etag)
+	c.Header("Vary", "Accept-Encoding")
+	c.Header("X-Generation-ID", fmt.Sprintf("gen_%d", generator.generationID))
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	// Set CSV headers
 	c.Header("Content-Type", "text/csv")
-	c.Header("X-Generation-ID", fmt.Sprintf("gen_%d", generator.generationID))
 	c.Header("X-Data-Timestamp", generator.cacheTime.Format(time.RFC3339Nano))
 	c.Header("X-Switch-Count", strconv.Itoa(generator.switchCount))
 	c.Header("X-Pre-Generated", "true")
 	c.Header("X-Data-Size", strconv.Itoa(len(generator.dataCache)))
 	c.Header("X-Last-Generation", generator.lastGeneration.Format(time.RFC3339))
 
+	if encoding != "" {
+		c.Header("Content-Encoding", encoding)
+	}
+
 	// Serve the pre-generated data immediately mocking the UFM API
-	c.Data(http.StatusOK, "text/csv", generator.dataCache)
+	c.Data(http.StatusOK, "text/csv", body)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
 }
 
 func getEnv(key, defaultValue string) string {
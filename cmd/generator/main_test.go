@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodedBody(t *testing.T) {
+	generator := &DataGenerator{
+		dataCache: []byte("plain"),
+		gzipCache: []byte("gzip"),
+		zstdCache: []byte("zstd"),
+	}
+
+	tests := []struct {
+		name             string
+		acceptEncoding   string
+		expectedEncoding string
+	}{
+		{"no header", "", ""},
+		{"identity only", "identity", ""},
+		{"gzip only", "gzip", "gzip"},
+		{"zstd preferred on a tie", "gzip, zstd", "zstd"},
+		{"case and spaces", " GZIP ;q=1 ,ZSTD", "zstd"},
+		{"higher q wins", "zstd;q=0.5, gzip;q=0.8", "gzip"},
+		{"wildcard", "*", "zstd"},
+		{"refused coding is not matched by wildcard", "zstd;q=0, *", "gzip"},
+		{"everything refused", "zstd;q=0, gzip;q=0, *;q=0", ""},
+		{"wildcard refused", "*;q=0, gzip", "gzip"},
+		{"q among other parameters", "zstd;level=3;q=0, gzip", "gzip"},
+		{"unknown codings only", "br, deflate", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoding, body := generator.encodedBody(tt.acceptEncoding)
+			assert.Equal(t, tt.expectedEncoding, encoding)
+			if tt.expectedEncoding == "" {
+				assert.Equal(t, "plain", string(body))
+			} else {
+				assert.Equal(t, tt.expectedEncoding, string(body))
+			}
+		})
+	}
+}
+
+func TestEncodedBody_SkipsMissingCaches(t *testing.T) {
+	generator := &DataGenerator{dataCache: []byte("plain"), gzipCache: []byte("gzip")}
+
+	encoding, body := generator.encodedBody("zstd, gzip;q=0.1")
+	assert.Equal(t, "gzip", encoding)
+	assert.Equal(t, "gzip", string(body))
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		expected    bool
+	}{
+		{"empty", "", false},
+		{"exact", `"gen_7"`, true},
+		{"weak", `W/"gen_7"`, true},
+		{"list", `"gen_6", "gen_7"`, true},
+		{"wildcard", "*", true},
+		{"other generation", `"gen_6"`, false},
+		{"unquoted", "gen_7", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, etagMatches(tt.ifNoneMatch, `"gen_7"`))
+		})
+	}
+}
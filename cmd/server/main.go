@@ -34,6 +34,11 @@ func main() {
 		os.Stdout,
 	)
 
+	// "ufm migrate ..." manages the database schema instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(ctx, os.Args[2:], cfg.Database.URL, logger))
+	}
+
 	application := app.NewApp(ctx, logger)
 	application.Start()
 	defer application.Stop()
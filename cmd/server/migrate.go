@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/migrate"
+	"github.com/ufm/scripts/migrations"
+)
+
+const migrateUsage = `Usage: ufm migrate [-url DATABASE_URL] <command>
+
+Commands:
+  up        apply all pending migrations
+  down [N]  roll back the last N applied migrations (default 1)
+  status    list migrations and when they were applied
+`
+
+// runMigrate implements the "migrate" subcommand and returns the process exit code
+func runMigrate(ctx context.Context, args []string, defaultURL string, logger log.Logger) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	url := flags.String("url", defaultURL, "PostgreSQL connection URL (defaults to database.url)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	loaded, err := migrate.Load(migrations.FS)
+	if err != nil {
+		logger.Errorf("Failed to load migrations: %v", err)
+		return 1
+	}
+
+	db, err := sql.Open("postgres", *url)
+	if err != nil {
+		logger.Errorf("Failed to connect to database: %v", err)
+		return 1
+	}
+	defer db.Close()
+
+	runner := migrate.NewRunner(db, loaded, logger)
+
+	switch command := flags.Arg(0); command {
+	case "up":
+		applied, err := runner.Up(ctx)
+		if err != nil {
+			logger.Errorf("Migration failed: %v", err)
+			return 1
+		}
+		logger.Infof("Applied %d migrations", applied)
+
+	case "down":
+		steps := 1
+		if flags.NArg() > 1 {
+			steps, err = strconv.Atoi(flags.Arg(1))
+			if err != nil || steps <= 0 {
+				logger.Errorf("Invalid step count %q", flags.Arg(1))
+				return 2
+			}
+		}
+		rolledBack, err := runner.Down(ctx, steps)
+		if err != nil {
+			logger.Errorf("Rollback failed: %v", err)
+			return 1
+		}
+		logger.Infof("Rolled back %d migrations", rolledBack)
+
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			logger.Errorf("Failed to read migration status: %v", err)
+			return 1
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%03d_%-30s %s\n", status.Version, status.Name, applied)
+		}
+
+	default:
+		logger.Errorf("Unknown migrate command %q", command)
+		flags.Usage()
+		return 2
+	}
+
+	return 0
+}
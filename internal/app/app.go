@@ -3,9 +3,14 @@ package app
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"io"
+	"maps"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
@@ -16,13 +21,20 @@ import (
 	"github.com/ufm/internal/http/handler"
 	"github.com/ufm/internal/http/middleware"
 	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/migrate"
 	"github.com/ufm/internal/monitoring"
 	"github.com/ufm/internal/monitoring/tracing"
 	"github.com/ufm/internal/service"
 	"github.com/ufm/internal/telemetry"
+	"github.com/ufm/internal/telemetry/alerting"
+	"github.com/ufm/internal/telemetry/anomaly"
 	"github.com/ufm/internal/telemetry/client"
+	"github.com/ufm/internal/telemetry/models"
 	"github.com/ufm/internal/telemetry/queue"
+	"github.com/ufm/internal/telemetry/retention"
 	"github.com/ufm/internal/telemetry/storage"
+	"github.com/ufm/internal/telemetry/stream"
+	"github.com/ufm/scripts/migrations"
 )
 
 const (
@@ -268,28 +280,112 @@ func newServices(ctx service.Context) *AppServices {
 	var telemetryService telemetry.TelemetryService
 	var telemetryHandler handler.TelemetryHandler
 	var generatorClient client.GeneratorClientInterface
+	var systemOptions []handler.SystemHandlerOption
 
 	if ctx.Config().Get().Telemetry.Enabled {
-		// Initialize database connection
-		db, err := sql.Open("postgres", ctx.Config().Get().Database.URL)
-		if err != nil {
-			logger.Fatalf("Failed to connect to database for telemetry: %v", err)
-		}
-
-		// Test database connection
-		if err := db.PingContext(ctx); err != nil {
-			logger.Warnf("Database ping failed for telemetry (continuing without telemetry): %v", err)
+		// Initialize the persistent store selected by database.type
+		repository, alertHistory, err := newTelemetryRepository(ctx, ctx.Config().Get().Database, ctx.Home().DataDir(), logger)
+		if errors.Is(err, errSchemaMigration) {
+			// Serving against a half-migrated schema is worse than not starting at all
+			logger.Fatalf("Database migration failed: %v", err)
+		} else if err != nil {
+			logger.Warnf("Telemetry storage unavailable (continuing without telemetry, set database.type to memory to run without a database): %v", err)
 		} else {
 			// Create storage components
 			cache := storage.NewInMemoryCache()
-			repository := storage.NewPostgreSQLRepository(db)
+
+			// Live updates are fanned out from the store to SSE/WebSocket clients
+			var storeOptions []storage.HybridStoreOption
+			var serviceOptions []telemetry.ServiceOption
+			if streamConfig := ctx.Config().Get().Telemetry.Stream; streamConfig.Enabled {
+				hub := stream.NewHub(stream.Config{
+					BufferSize:     streamConfig.BufferSize,
+					MaxSubscribers: streamConfig.MaxClients,
+				}, logger)
+				storeOptions = append(storeOptions, storage.WithUpdatePublisher(hub))
+				serviceOptions = append(serviceOptions, telemetry.WithStreamHub(hub))
+			}
+
+			// Batches the database cannot take are buffered on disk and written back once it recovers
+			if walConfig := ctx.Config().Get().Telemetry.Storage.WAL; walConfig.Enabled {
+				wal, drainInterval, err := newWAL(walConfig, ctx.Home().DataDir(), logger)
+				if err != nil {
+					logger.Errorf("Failed to open write-ahead log (continuing without it): %v", err)
+				} else {
+					storeOptions = append(storeOptions, storage.WithWAL(wal, drainInterval))
+				}
+			}
+
+			// A database that keeps timing out is failed fast, so the WAL and cache absorb the load
+			storeRepository := storage.TelemetryRepository(repository)
+			if breakerConfig := ctx.Config().Get().Telemetry.Storage.CircuitBreaker; breakerConfig.Enabled {
+				breaker, err := newCircuitBreaker(breakerConfig, repository, logger)
+				if err != nil {
+					logger.Errorf("Failed to configure database circuit breaker (continuing without it): %v", err)
+				} else {
+					storeRepository = breaker
+					serviceOptions = append(serviceOptions, telemetry.WithCircuitBreaker(breaker))
+				}
+			}
 
 			// Configure hybrid store
-			hybridConfig := storage.DefaultHybridStoreConfig()
-			store := storage.NewHybridStore(cache, repository, hybridConfig, logger)
+			hybridConfig, err := newHybridStoreConfig(ctx.Config().Get().Telemetry.Storage)
+			if err != nil {
+				logger.Errorf("Invalid telemetry storage settings (continuing with defaults): %v", err)
+				hybridConfig = storage.DefaultHybridStoreConfig()
+			}
+			store := storage.NewHybridStore(cache, storeRepository, hybridConfig, logger, storeOptions...)
+
+			// Flush, cache and pool settings follow system.yaml without a restart
+			ctx.Config().AddUpdateListener(newStorageUpdateListener(store, repository))
+
+			// Database reachability and pool usage feed the health checks and metrics
+			healthCheckInterval, err := parseOptionalDuration(ctx.Config().Get().Database.HealthCheckInterval)
+			if err != nil {
+				logger.Errorf("Invalid database.healthCheckInterval (continuing with %v): %v", storage.DefaultHealthCheckInterval, err)
+			}
+			databaseMonitor := storage.NewDatabaseMonitor(repository, healthCheckInterval, logger)
+			serviceOptions = append(serviceOptions, telemetry.WithDatabaseMonitor(databaseMonitor))
+			systemOptions = append(systemOptions, handler.WithDatabaseHealth(databaseMonitor))
+
+			// Alerting is optional, an invalid rule set disables it rather than telemetry as a whole
+			if alertsConfig := ctx.Config().Get().Telemetry.Alerts; alertsConfig.Enabled {
+				engine, err := newAlertEngine(alertsConfig, ctx.Home().DataDir(), alertHistory, store, logger)
+				if err != nil {
+					logger.Errorf("Failed to configure alerting (continuing without alerts): %v", err)
+				} else {
+					serviceOptions = append(serviceOptions, telemetry.WithAlertEngine(engine))
+					logger.Infof("Alerting enabled with %d rules", len(engine.Rules()))
+				}
+			}
+
+			// Anomaly detection scores samples against per-switch baselines learned from the ingest stream
+			if anomalyConfig := ctx.Config().Get().Telemetry.Anomaly; anomalyConfig.Enabled {
+				detector, err := anomaly.NewDetector(anomaly.Config{
+					Alpha:         anomalyConfig.Alpha,
+					ZThreshold:    anomalyConfig.ZThreshold,
+					WarmupSamples: anomalyConfig.WarmupSamples,
+					MaxAnomalies:  anomalyConfig.MaxAnomalies,
+				}, logger)
+				if err != nil {
+					logger.Errorf("Failed to configure anomaly detection (continuing without it): %v", err)
+				} else {
+					serviceOptions = append(serviceOptions, telemetry.WithAnomalyDetector(detector))
+				}
+			}
+
+			// Retention keeps the store bounded; a bad setting disables it rather than telemetry
+			if retentionConfig := ctx.Config().Get().Telemetry.Retention; retentionConfig.Enabled {
+				manager, err := newRetentionManager(retentionConfig, repository, logger)
+				if err != nil {
+					logger.Errorf("Failed to configure retention (continuing without retention): %v", err)
+				} else {
+					serviceOptions = append(serviceOptions, telemetry.WithRetentionManager(manager))
+				}
+			}
 
 			// Create base telemetry service
-			baseService := telemetry.NewTelemetryService(store, logger)
+			baseService := telemetry.NewTelemetryService(store, logger, serviceOptions...)
 
 			// In case of know high load, enable queuing for telemetry requests
 			// this is the last resort to avoid losing telemetry data in service level
@@ -309,36 +405,349 @@ func newServices(ctx service.Context) *AppServices {
 				logger.Infof("Telemetry service initialized without queuing (recommended for normal loads)")
 			}
 
-			telemetryHandler = handler.NewTelemetryHandler(ctx, telemetryService)
+			var telemetryOptions []handler.TelemetryHandlerOption
 
 			// Since it configurable during runtime, it is possible to enable or disable telemetry ingestion
-			if ctx.Config().Get().Telemetry.Ingestion.Enabled {
-				clientConfig := client.GeneratorClientConfig{
-					GeneratorURL:   ctx.Config().Get().Telemetry.Ingestion.GeneratorURL,
-					PollInterval:   parseDuration(ctx.Config().Get().Telemetry.Ingestion.PollInterval),
-					Timeout:        parseDuration(ctx.Config().Get().Telemetry.Ingestion.Timeout),
-					MaxRetries:     ctx.Config().Get().Telemetry.Ingestion.MaxRetries,
-					StartupDelay:   parseDuration(ctx.Config().Get().Telemetry.Ingestion.StartupDelay),
-					ReadinessCheck: ctx.Config().Get().Telemetry.Ingestion.ReadinessCheck,
+			if ingestion := ctx.Config().Get().Telemetry.Ingestion; ingestion.Enabled {
+				sources := newIngestionSources(ingestion)
+				manager, err := client.NewSourceManager(sources, telemetryService, logger)
+				if err != nil {
+					logger.Errorf("Failed to configure ingestion sources: %v (continuing without ingestion)", err)
+				} else {
+					generatorClient = manager
+					telemetryOptions = append(telemetryOptions, handler.WithSources(manager))
+					for _, source := range sources {
+						logger.Infof("Generator client %s configured to poll %s every %s",
+							source.Name, source.GeneratorURL, source.PollInterval)
+					}
 				}
-				generatorClient = client.NewGeneratorClient(clientConfig, telemetryService, logger)
-				logger.Infof("Generator client configured to poll %s every %s with %s startup delay",
-					clientConfig.GeneratorURL, clientConfig.PollInterval, clientConfig.StartupDelay)
 			}
 
+			telemetryHandler = handler.NewTelemetryHandler(ctx, telemetryService, telemetryOptions...)
+
 			logger.Infof("Telemetry services initialized successfully")
 		}
 	}
 
 	return &AppServices{
 		HttpServer:       http.NewServer(ctx, tenantMiddlewares),
-		SystemHandler:    handler.NewSystemHandler(ctx),
+		SystemHandler:    handler.NewSystemHandler(ctx, systemOptions...),
 		TelemetryService: telemetryService,
 		TelemetryHandler: telemetryHandler,
 		GeneratorClient:  generatorClient,
 	}
 }
 
+// newIngestionSources builds one client config per configured ingestion source.
+// Sources inherit the top-level poll interval, timeout and retries unless they
+// set their own; without a sources list the generator_url is the only source.
+func newIngestionSources(ingestion config.TelemetryIngestionConfig) []client.GeneratorClientConfig {
+	sources := ingestion.Sources
+	if len(sources) == 0 {
+		sources = []config.IngestionSourceConfig{{Name: "generator", URL: ingestion.GeneratorURL}}
+	}
+
+	configs := make([]client.GeneratorClientConfig, 0, len(sources))
+	for _, source := range sources {
+		pollInterval := source.PollInterval
+		if pollInterval == "" {
+			pollInterval = ingestion.PollInterval
+		}
+		timeout := source.Timeout
+		if timeout == "" {
+			timeout = ingestion.Timeout
+		}
+		maxRetries := source.MaxRetries
+		if maxRetries == 0 {
+			maxRetries = ingestion.MaxRetries
+		}
+		aliases := make(map[string]string, len(ingestion.ColumnAliases)+len(source.ColumnAliases))
+		maps.Copy(aliases, ingestion.ColumnAliases)
+		maps.Copy(aliases, source.ColumnAliases)
+
+		configs = append(configs, client.GeneratorClientConfig{
+			Name:           source.Name,
+			GeneratorURL:   source.URL,
+			PollInterval:   parseDuration(pollInterval),
+			Timeout:        parseDuration(timeout),
+			MaxRetries:     maxRetries,
+			StartupDelay:   parseDuration(ingestion.StartupDelay),
+			ReadinessCheck: ingestion.ReadinessCheck,
+			ChunkSize:      ingestion.ChunkSize,
+			ColumnAliases:  aliases,
+			Optional:       slices.Concat(ingestion.OptionalColumns, source.OptionalColumns),
+			AcceptEncoding: ingestion.AcceptEncoding,
+			Auth: client.SourceAuth{
+				Token:    source.Auth.Token,
+				Username: source.Auth.Username,
+				Password: source.Auth.Password,
+				Headers:  source.Auth.Headers,
+			},
+			Tags: source.Tags,
+		})
+	}
+	return configs
+}
+
+// errSchemaMigration marks a failure to bring the PostgreSQL schema up to date
+var errSchemaMigration = errors.New("schema migration failed")
+
+// newTelemetryRepository opens the persistent store selected by database.type.
+// Alert history is only persisted in PostgreSQL, so the returned history store
+// is nil for the embedded backends. Pending PostgreSQL migrations are applied
+// first when database.autoMigrate is set.
+func newTelemetryRepository(ctx context.Context, dbConfig config.DatabaseConfig, dataDir string, logger log.Logger) (storage.TelemetryRepository, alerting.HistoryStore, error) {
+	switch dbConfig.Type {
+	case "postgres", "":
+		db, err := sql.Open("postgres", dbConfig.URL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		if err := db.PingContext(ctx); err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("database ping failed: %w", err)
+		}
+		if dbConfig.AutoMigrate {
+			if err := migrateUp(ctx, db, logger); err != nil {
+				db.Close()
+				return nil, nil, fmt.Errorf("%w: %v", errSchemaMigration, err)
+			}
+		}
+
+		var options []storage.PostgreSQLOption
+		if dbConfig.PartitionInterval != "none" {
+			interval, err := storage.ParsePartitionInterval(dbConfig.PartitionInterval)
+			if err != nil {
+				db.Close()
+				return nil, nil, fmt.Errorf("invalid database.partitionInterval: %w", err)
+			}
+			options = append(options, storage.WithPartitioning(storage.PartitionConfig{
+				Interval: interval,
+				Ahead:    dbConfig.PartitionsAhead,
+			}, logger))
+		}
+		options = append(options, storage.WithQueryTimeout(time.Duration(dbConfig.QueryTimeoutSecs)*time.Second))
+
+		repository := storage.NewPostgreSQLRepository(db, options...)
+		repository.SetPool(newPoolConfig(dbConfig))
+		return repository, storage.NewPostgreSQLAlertRepository(db), nil
+
+	case "sqlite":
+		path := dbConfig.Path
+		if path == "" {
+			path = filepath.Join(dataDir, "telemetry.db")
+		}
+		db, err := storage.OpenSQLiteDB(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		repository, err := storage.NewSQLiteRepository(ctx, db)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return repository, nil, nil
+
+	case "file":
+		path := dbConfig.Path
+		if path == "" {
+			path = filepath.Join(dataDir, "telemetry")
+		}
+		repository, err := storage.NewSegmentRepository(path, int64(dbConfig.SegmentMaxMB)<<20)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repository, nil, nil
+
+	case "memory":
+		return storage.NewMemoryRepository(dbConfig.MemoryMaxRows), nil, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown database type %q (expected postgres, sqlite, file or memory)", dbConfig.Type)
+	}
+}
+
+// newHybridStoreConfig converts the telemetry.storage settings, unset values
+// fall back to the hybrid store defaults
+func newHybridStoreConfig(storageConfig config.TelemetryStorageConfig) (storage.HybridStoreConfig, error) {
+	hybridConfig := storage.HybridStoreConfig{
+		BatchSize:    storageConfig.BatchSize,
+		MaxRetries:   storageConfig.MaxRetries,
+		FlushWorkers: storageConfig.FlushWorkers,
+	}
+	durations := map[string]struct {
+		value string
+		dest  *time.Duration
+	}{
+		"flush_interval": {storageConfig.FlushInterval, &hybridConfig.FlushInterval},
+		"cache_ttl":      {storageConfig.CacheTTL, &hybridConfig.CacheTTL},
+	}
+	for name, duration := range durations {
+		value, err := parseOptionalDuration(duration.value)
+		if err != nil {
+			return storage.HybridStoreConfig{}, fmt.Errorf("invalid telemetry.storage.%s: %w", name, err)
+		}
+		*duration.dest = value
+	}
+	return hybridConfig, nil
+}
+
+// newPoolConfig converts the database connection pool settings
+func newPoolConfig(dbConfig config.DatabaseConfig) storage.PoolConfig {
+	return storage.PoolConfig{
+		MaxOpenConnections: dbConfig.MaxOpenConnections,
+		MaxIdleConnections: dbConfig.MaxIdleConnections,
+		ConnMaxLifetime:    time.Duration(dbConfig.ConnectionMaxLifetimeSecs) * time.Second,
+	}
+}
+
+// newStorageUpdateListener applies changed storage, pool and query timeout
+// settings to the running store. The database type and URL require a restart.
+func newStorageUpdateListener(store *storage.HybridStore, repository storage.TelemetryRepository) config.UpdateListener {
+	return config.UpdateListener{
+		Name: "telemetry-storage",
+		OnUpdate: func(_ context.Context, updated config.Config) error {
+			hybridConfig, err := newHybridStoreConfig(updated.Telemetry.Storage)
+			if err != nil {
+				return err
+			}
+			store.UpdateConfig(hybridConfig)
+
+			if postgres, ok := repository.(*storage.PostgreSQLRepository); ok {
+				postgres.SetPool(newPoolConfig(updated.Database))
+				postgres.SetQueryTimeout(time.Duration(updated.Database.QueryTimeoutSecs) * time.Second)
+			}
+			return nil
+		},
+	}
+}
+
+// newCircuitBreaker wraps repository in a circuit breaker configured from telemetry.storage.circuit_breaker
+func newCircuitBreaker(breakerConfig config.TelemetryCircuitBreakerConfig, repository storage.TelemetryRepository, logger log.Logger) (*storage.CircuitBreakerRepository, error) {
+	openTimeout, err := parseOptionalDuration(breakerConfig.OpenTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid telemetry.storage.circuit_breaker.open_timeout: %w", err)
+	}
+	return storage.NewCircuitBreakerRepository(repository, storage.CircuitBreakerConfig{
+		FailureThreshold: breakerConfig.FailureThreshold,
+		OpenTimeout:      openTimeout,
+		HalfOpenRequests: breakerConfig.HalfOpenRequests,
+	}, logger), nil
+}
+
+// newWAL opens the hybrid store's write-ahead log in <data dir>/wal
+func newWAL(walConfig config.TelemetryWALConfig, dataDir string, logger log.Logger) (*storage.WAL, time.Duration, error) {
+	drainInterval, err := parseOptionalDuration(walConfig.DrainInterval)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid telemetry.storage.wal.drain_interval: %w", err)
+	}
+
+	wal, err := storage.OpenWAL(filepath.Join(dataDir, "wal"), storage.WALConfig{
+		MaxBytes:     int64(walConfig.MaxSizeMB) << 20,
+		SegmentBytes: int64(walConfig.SegmentSizeMB) << 20,
+	}, logger)
+	if err != nil {
+		return nil, 0, err
+	}
+	return wal, drainInterval, nil
+}
+
+// newAlertEngine builds the alerting engine from the configured rules and notifiers
+func newAlertEngine(alertsConfig config.TelemetryAlertsConfig, dataDir string, history alerting.HistoryStore, switches alerting.SwitchLister, logger log.Logger) (*alerting.Engine, error) {
+	rules := make([]alerting.Rule, 0, len(alertsConfig.Rules))
+	for _, ruleConfig := range alertsConfig.Rules {
+		forDuration, err := parseOptionalDuration(ruleConfig.For)
+		if err != nil {
+			return nil, fmt.Errorf("alert rule %s: invalid 'for' duration: %w", ruleConfig.Name, err)
+		}
+
+		rules = append(rules, alerting.Rule{
+			Name:       ruleConfig.Name,
+			Metric:     models.MetricType(ruleConfig.Metric),
+			Comparator: alerting.Comparator(ruleConfig.Comparator),
+			Threshold:  ruleConfig.Threshold,
+			For:        forDuration,
+			Severity:   ruleConfig.Severity,
+			SwitchIDs:  ruleConfig.SwitchIDs,
+			Location:   ruleConfig.Location,
+		})
+	}
+
+	notifiers, err := newAlertNotifiers(alertsConfig.Notifiers, dataDir, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return alerting.NewEngine(rules, history, switches, logger, alerting.WithNotifiers(notifiers...))
+}
+
+// newAlertNotifiers builds the configured notification sinks. Undeliverable
+// webhook events are dead-lettered under <dataDir>/alerts.
+func newAlertNotifiers(notifierConfigs []config.AlertNotifierConfig, dataDir string, logger log.Logger) ([]alerting.Notifier, error) {
+	notifiers := make([]alerting.Notifier, 0, len(notifierConfigs))
+	for _, notifierConfig := range notifierConfigs {
+		switch notifierConfig.Type {
+		case "log":
+			name := notifierConfig.Name
+			if name == "" {
+				name = "log"
+			}
+			notifiers = append(notifiers, alerting.NewLogNotifier(name, logger))
+		case "webhook":
+			timeout, err := parseOptionalDuration(notifierConfig.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("alert notifier %s: invalid timeout: %w", notifierConfig.Name, err)
+			}
+			backoff, err := parseOptionalDuration(notifierConfig.Backoff)
+			if err != nil {
+				return nil, fmt.Errorf("alert notifier %s: invalid backoff: %w", notifierConfig.Name, err)
+			}
+			webhook, err := alerting.NewWebhookNotifier(alerting.WebhookConfig{
+				Name:           notifierConfig.Name,
+				URL:            notifierConfig.URL,
+				Secret:         notifierConfig.Secret,
+				Headers:        notifierConfig.Headers,
+				Timeout:        timeout,
+				MaxRetries:     notifierConfig.MaxRetries,
+				Backoff:        backoff,
+				DeadLetterFile: filepath.Join(dataDir, "alerts", notifierConfig.Name+"-dead-letter.jsonl"),
+			}, logger)
+			if err != nil {
+				return nil, err
+			}
+			notifiers = append(notifiers, webhook)
+		default:
+			return nil, fmt.Errorf("alert notifier %s: unknown type %q (expected webhook or log)", notifierConfig.Name, notifierConfig.Type)
+		}
+	}
+	return notifiers, nil
+}
+
+// newRetentionManager builds the retention manager from configuration
+func newRetentionManager(retentionConfig config.TelemetryRetentionConfig, repository storage.TelemetryRepository, logger log.Logger) (*retention.Manager, error) {
+	var managerConfig retention.Config
+	durations := map[string]struct {
+		value string
+		dest  *time.Duration
+	}{
+		"raw":          {retentionConfig.Raw, &managerConfig.RawRetention},
+		"rollup_1m":    {retentionConfig.Rollup1m, &managerConfig.MinuteRetention},
+		"rollup_1h":    {retentionConfig.Rollup1h, &managerConfig.HourRetention},
+		"interval":     {retentionConfig.Interval, &managerConfig.Interval},
+		"rollup_delay": {retentionConfig.RollupDelay, &managerConfig.RollupDelay},
+	}
+	for name, duration := range durations {
+		value, err := parseOptionalDuration(duration.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid telemetry.retention.%s: %w", name, err)
+		}
+		*duration.dest = value
+	}
+	managerConfig.BatchSize = retentionConfig.BatchSize
+
+	return retention.NewManager(repository, managerConfig, logger)
+}
+
 // Helper function to parse duration strings
 func parseDuration(s string) time.Duration {
 	d, err := time.ParseDuration(s)
@@ -347,3 +756,27 @@ func parseDuration(s string) time.Duration {
 	}
 	return d
 }
+
+// parseOptionalDuration parses a duration string, an empty string yields zero
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// migrateUp applies the embedded migrations that have not run yet
+func migrateUp(ctx context.Context, db *sql.DB, logger log.Logger) error {
+	loaded, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return err
+	}
+	applied, err := migrate.NewRunner(db, loaded, logger).Up(ctx)
+	if err != nil {
+		return err
+	}
+	if applied > 0 {
+		logger.Infof("Applied %d database migrations", applied)
+	}
+	return nil
+}
@@ -22,10 +22,17 @@ type DatabaseConfig struct {
 	URL                       string `yaml:"url" env:"DATABASE_URL"`
 	Username                  string `yaml:"username" env:"DATABASE_USERNAME"`
 	Password                  string `yaml:"password" env:"DATABASE_PASSWORD"`
+	Path                      string `yaml:"path" env:"DATABASE_PATH"` // sqlite file or segment directory, defaults under the data dir
+	AutoMigrate               bool   `yaml:"autoMigrate"`              // apply pending postgres migrations at startup
+	PartitionInterval         string `yaml:"partitionInterval"`        // postgres telemetry_metrics partition width: day, hour or none
+	PartitionsAhead           int    `yaml:"partitionsAhead"`
+	SegmentMaxMB              int    `yaml:"segmentMaxMB"`
+	MemoryMaxRows             int    `yaml:"memoryMaxRows"`
 	QueryTimeoutSecs          int    `yaml:"queryTimeoutSecs"`
 	MaxIdleConnections        int    `yaml:"maxIdleConnections"`
 	MaxOpenConnections        int    `yaml:"maxOpenConnections"`
 	ConnectionMaxLifetimeSecs int    `yaml:"connectionMaxLifetimeSecs"`
+	HealthCheckInterval       string `yaml:"healthCheckInterval"` // how often the database is checked for /health and /readiness
 }
 
 type LoggingConfig struct {
@@ -65,13 +72,34 @@ type TelemetryConfig struct {
 	Queue     TelemetryQueueConfig     `yaml:"queue"`
 	Simulator TelemetrySimulatorConfig `yaml:"simulator"`
 	Ingestion TelemetryIngestionConfig `yaml:"ingestion"`
+	Alerts    TelemetryAlertsConfig    `yaml:"alerts"`
+	Anomaly   TelemetryAnomalyConfig   `yaml:"anomaly"`
+	Stream    TelemetryStreamConfig    `yaml:"stream"`
+	Retention TelemetryRetentionConfig `yaml:"retention"`
 }
 
 type TelemetryStorageConfig struct {
-	CacheTTL      string `yaml:"cache_ttl"`
-	BatchSize     int    `yaml:"batch_size"`
-	FlushInterval string `yaml:"flush_interval"`
-	MaxRetries    int    `yaml:"max_retries"`
+	CacheTTL       string                        `yaml:"cache_ttl"`
+	BatchSize      int                           `yaml:"batch_size"`
+	FlushInterval  string                        `yaml:"flush_interval"`
+	MaxRetries     int                           `yaml:"max_retries"`
+	FlushWorkers   int                           `yaml:"flush_workers"` // fixed at startup
+	WAL            TelemetryWALConfig            `yaml:"wal"`
+	CircuitBreaker TelemetryCircuitBreakerConfig `yaml:"circuit_breaker"`
+}
+
+type TelemetryWALConfig struct {
+	Enabled       bool   `yaml:"enabled" env:"TELEMETRY_WAL_ENABLED"`
+	MaxSizeMB     int    `yaml:"max_size_mb"`     // oldest batches are discarded beyond this
+	SegmentSizeMB int    `yaml:"segment_size_mb"` // segment file rotation size
+	DrainInterval string `yaml:"drain_interval"`
+}
+
+type TelemetryCircuitBreakerConfig struct {
+	Enabled          bool   `yaml:"enabled" env:"TELEMETRY_CIRCUIT_BREAKER_ENABLED"`
+	FailureThreshold int    `yaml:"failure_threshold"`  // consecutive failures that open the circuit
+	OpenTimeout      string `yaml:"open_timeout"`       // time spent failing fast before a trial call
+	HalfOpenRequests int    `yaml:"half_open_requests"` // trial calls allowed at once
 }
 
 type TelemetryQueueConfig struct {
@@ -96,4 +124,86 @@ type TelemetryIngestionConfig struct {
 	MaxRetries     int    `yaml:"max_retries" env:"TELEMETRY_MAX_RETRIES"`
 	StartupDelay   string `yaml:"startup_delay" env:"TELEMETRY_STARTUP_DELAY"`
 	ReadinessCheck bool   `yaml:"readiness_check" env:"TELEMETRY_READINESS_CHECK"`
+	ChunkSize      int    `yaml:"chunk_size" env:"TELEMETRY_INGESTION_CHUNK_SIZE"`           // rows per IngestBatch call while streaming
+	AcceptEncoding string `yaml:"accept_encoding" env:"TELEMETRY_INGESTION_ACCEPT_ENCODING"` // "identity" disables compression
+
+	// ColumnAliases maps CSV column names used by the sources to the telemetry column names
+	ColumnAliases map[string]string `yaml:"column_aliases"`
+
+	// OptionalColumns lists the metric columns that read as zero when a source omits them
+	OptionalColumns []string `yaml:"optional_columns"`
+
+	// Sources replaces generator_url with several endpoints polled concurrently
+	Sources []IngestionSourceConfig `yaml:"sources"`
+}
+
+type IngestionSourceConfig struct {
+	Name            string              `yaml:"name"`
+	URL             string              `yaml:"url"`
+	PollInterval    string              `yaml:"poll_interval"` // defaults to telemetry.ingestion.poll_interval
+	Timeout         string              `yaml:"timeout"`       // defaults to telemetry.ingestion.timeout
+	MaxRetries      int                 `yaml:"max_retries"`
+	Auth            IngestionAuthConfig `yaml:"auth"`
+	Tags            map[string]string   `yaml:"tags"`             // "site" becomes the location of the source's switches
+	ColumnAliases   map[string]string   `yaml:"column_aliases"`   // merged over telemetry.ingestion.column_aliases
+	OptionalColumns []string            `yaml:"optional_columns"` // added to telemetry.ingestion.optional_columns
+}
+
+type IngestionAuthConfig struct {
+	Token    string            `yaml:"token"` // sent as a bearer token
+	Username string            `yaml:"username"`
+	Password string            `yaml:"password"`
+	Headers  map[string]string `yaml:"headers"`
+}
+
+type TelemetryAlertsConfig struct {
+	Enabled   bool                  `yaml:"enabled" env:"TELEMETRY_ALERTS_ENABLED"`
+	Rules     []AlertRuleConfig     `yaml:"rules"`
+	Notifiers []AlertNotifierConfig `yaml:"notifiers"`
+}
+
+type TelemetryAnomalyConfig struct {
+	Enabled       bool    `yaml:"enabled" env:"TELEMETRY_ANOMALY_ENABLED"`
+	Alpha         float64 `yaml:"alpha"`
+	ZThreshold    float64 `yaml:"z_threshold"`
+	WarmupSamples int     `yaml:"warmup_samples"`
+	MaxAnomalies  int     `yaml:"max_anomalies"`
+}
+
+type TelemetryStreamConfig struct {
+	Enabled    bool `yaml:"enabled" env:"TELEMETRY_STREAM_ENABLED"`
+	BufferSize int  `yaml:"buffer_size"`
+	MaxClients int  `yaml:"max_clients"`
+}
+
+type TelemetryRetentionConfig struct {
+	Enabled     bool   `yaml:"enabled" env:"TELEMETRY_RETENTION_ENABLED"`
+	Raw         string `yaml:"raw"`       // raw samples, "0" keeps them forever
+	Rollup1m    string `yaml:"rollup_1m"` // 1-minute rollups
+	Rollup1h    string `yaml:"rollup_1h"` // 1-hour rollups
+	Interval    string `yaml:"interval"`
+	BatchSize   int    `yaml:"batch_size"`
+	RollupDelay string `yaml:"rollup_delay"`
+}
+
+type AlertRuleConfig struct {
+	Name       string   `yaml:"name"`
+	Metric     string   `yaml:"metric"`
+	Comparator string   `yaml:"comparator"`
+	Threshold  float64  `yaml:"threshold"`
+	For        string   `yaml:"for"`
+	Severity   string   `yaml:"severity"`
+	SwitchIDs  []string `yaml:"switch_ids"`
+	Location   string   `yaml:"location"`
+}
+
+type AlertNotifierConfig struct {
+	Name       string            `yaml:"name"`
+	Type       string            `yaml:"type"` // "webhook" or "log"
+	URL        string            `yaml:"url"`
+	Secret     string            `yaml:"secret"`
+	Headers    map[string]string `yaml:"headers"`
+	Timeout    string            `yaml:"timeout"`
+	MaxRetries int               `yaml:"max_retries"`
+	Backoff    string            `yaml:"backoff"`
 }
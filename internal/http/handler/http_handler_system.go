@@ -8,6 +8,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/ufm/internal/log"
 	"github.com/ufm/internal/service"
+	"github.com/ufm/internal/telemetry/storage"
 )
 
 type SystemHandler interface {
@@ -18,16 +19,36 @@ type SystemHandler interface {
 	Metrics(c *gin.Context)
 }
 
+// DatabaseHealthSource reports the state of the telemetry database
+type DatabaseHealthSource interface {
+	Health() storage.DatabaseHealth
+}
+
 type systemHandler struct {
-	logger log.Logger
-	ctx    service.Context
+	logger   log.Logger
+	ctx      service.Context
+	database DatabaseHealthSource
 }
 
-func NewSystemHandler(ctx service.Context) SystemHandler {
-	return &systemHandler{
+// SystemHandlerOption configures optional system handler checks
+type SystemHandlerOption func(*systemHandler)
+
+// WithDatabaseHealth reports the database state from source in the health and readiness checks
+func WithDatabaseHealth(source DatabaseHealthSource) SystemHandlerOption {
+	return func(h *systemHandler) {
+		h.database = source
+	}
+}
+
+func NewSystemHandler(ctx service.Context, options ...SystemHandlerOption) SystemHandler {
+	h := &systemHandler{
 		logger: ctx.LoggerFactory().(log.LoggerFactory).GetLogger("system-handler"),
 		ctx:    ctx,
 	}
+	for _, option := range options {
+		option(h)
+	}
+	return h
 }
 
 func (h *systemHandler) Ping(c *gin.Context) {
@@ -38,31 +59,29 @@ func (h *systemHandler) Ping(c *gin.Context) {
 	})
 }
 
+// Health reports the process as alive. A database outage degrades it without
+// failing the check, since the cache keeps serving and writes are buffered.
 func (h *systemHandler) Health(c *gin.Context) {
-	// Perform health checks here (database, external services, etc.)
-	healthy := true
 	status := "healthy"
-	statusCode := http.StatusOK
-
-	if !healthy {
-		status = "unhealthy"
-		statusCode = http.StatusServiceUnavailable
+	databaseStatus := h.databaseStatus()
+	if databaseStatus == storage.DatabaseStatusUnreachable {
+		status = "degraded"
 	}
 
-	c.JSON(statusCode, gin.H{
+	c.JSON(http.StatusOK, gin.H{
 		"status":    status,
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 		"service":   service.PrettyName,
 		"checks": gin.H{
-			"database": "ok",
-			// Add more health checks as needed
+			"database": databaseStatus,
 		},
 	})
 }
 
+// Readiness fails while the database is unreachable so traffic moves to healthy instances
 func (h *systemHandler) Readiness(c *gin.Context) {
-	// Perform readiness checks here
-	ready := true
+	databaseStatus := h.databaseStatus()
+	ready := databaseStatus != storage.DatabaseStatusUnreachable
 	status := "ready"
 	statusCode := http.StatusOK
 
@@ -75,9 +94,20 @@ func (h *systemHandler) Readiness(c *gin.Context) {
 		"status":    status,
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 		"service":   service.PrettyName,
+		"checks": gin.H{
+			"database": databaseStatus,
+		},
 	})
 }
 
+// databaseStatus returns the latest database check result, "disabled" without a database
+func (h *systemHandler) databaseStatus() string {
+	if h.database == nil {
+		return "disabled"
+	}
+	return h.database.Health().Status
+}
+
 func (h *systemHandler) Version(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"service": service.PrettyName,
@@ -15,6 +15,7 @@ import (
 	"github.com/ufm/internal/log"
 	"github.com/ufm/internal/monitoring/tracing"
 	"github.com/ufm/internal/service"
+	"github.com/ufm/internal/telemetry/storage"
 )
 
 // Mock implementations
@@ -242,7 +243,53 @@ func TestSystemHandler_Health(t *testing.T) {
 	// Verify checks
 	checks, ok := response["checks"].(map[string]interface{})
 	assert.True(t, ok)
-	assert.Equal(t, "ok", checks["database"])
+	assert.Equal(t, "disabled", checks["database"])
+}
+
+type stubDatabaseHealth struct {
+	status string
+}
+
+func (s stubDatabaseHealth) Health() storage.DatabaseHealth {
+	return storage.DatabaseHealth{Status: s.status}
+}
+
+func TestSystemHandler_DatabaseHealth(t *testing.T) {
+	tests := []struct {
+		name              string
+		databaseStatus    string
+		expectedHealth    string
+		expectedReadiness int
+	}{
+		{"reachable", storage.DatabaseStatusOK, "healthy", http.StatusOK},
+		{"unreachable", storage.DatabaseStatusUnreachable, "degraded", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtx := &mockContext{}
+			mockLoggerFactory := &mockLoggerFactory{}
+			mockCtx.On("LoggerFactory").Return(mockLoggerFactory)
+			mockLoggerFactory.On("GetLogger", "system-handler").Return(&mockLogger{})
+
+			handler := NewSystemHandler(mockCtx, WithDatabaseHealth(stubDatabaseHealth{status: tt.databaseStatus}))
+			router := setupTestRouter(handler)
+
+			// A database outage degrades health without failing it
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedHealth, response["status"])
+			assert.Equal(t, tt.databaseStatus, response["checks"].(map[string]interface{})["database"])
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/readiness", nil))
+			assert.Equal(t, tt.expectedReadiness, w.Code)
+		})
+	}
 }
 
 func TestSystemHandler_Readiness(t *testing.T) {
@@ -1,9 +1,17 @@
 package handler
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,18 +20,63 @@ import (
 	"github.com/ufm/internal/service"
 	"github.com/ufm/internal/telemetry"
 	"github.com/ufm/internal/telemetry/models"
+	"github.com/ufm/internal/telemetry/storage"
+	"github.com/ufm/internal/telemetry/stream"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// defaultHistoryWindow is the lookback used when a history query omits 'from'
+	defaultHistoryWindow = time.Hour
+	// defaultSummaryWindow is the lookback used when a summary query omits 'window'
+	defaultSummaryWindow = 24 * time.Hour
+	// maxSummaryWindow bounds how far back a summary may scan
+	maxSummaryWindow = 90 * 24 * time.Hour
+	// defaultTopK and maxTopK bound the size of ranking responses
+	defaultTopK = 10
+	maxTopK     = 1000
+	// defaultAnomalyLimit and maxAnomalyLimit bound the size of anomaly responses
+	defaultAnomalyLimit = 100
+	maxAnomalyLimit     = 1000
+	// maxIngestBodyBytes and maxIngestRows bound the size of a single push ingestion request
+	maxIngestBodyBytes = 10 << 20
+	maxIngestRows      = 10000
+	// streamHeartbeatInterval is how often an idle SSE stream sends a keepalive comment
+	streamHeartbeatInterval = 15 * time.Second
 )
 
 // EXPORTED TYPES AND FUNCTIONS
 
 type TelemetryHandler interface {
-	GetMetric(c *gin.Context)   // GET /telemetry/metrics/:switchId/:metricType
-	ListMetrics(c *gin.Context) // GET /telemetry/metrics/:switchId or /telemetry/metrics
+	// Push ingestion
+	IngestMetrics(c *gin.Context) // POST /telemetry/ingest
+	GetMetric(c *gin.Context)     // GET /telemetry/metrics/:switchId/:metricType
+	ListMetrics(c *gin.Context)   // GET /telemetry/metrics/:switchId or /telemetry/metrics
+	// Historical queries
+	GetMetricHistory(c *gin.Context) // GET /telemetry/metrics/:switchId/history
+	GetSwitchSummary(c *gin.Context) // GET /telemetry/switches/:id/summary
+	// Fabric-wide analytics
+	GetFabricStatistics(c *gin.Context) // GET /telemetry/stats
+	GetTopSwitches(c *gin.Context)      // GET /telemetry/top
+	// Alerting
+	GetAlerts(c *gin.Context)       // GET /telemetry/alerts
+	GetAlertHistory(c *gin.Context) // GET /telemetry/alerts/history
+	// Anomaly detection
+	GetAnomalies(c *gin.Context) // GET /telemetry/anomalies
+	// Live updates
+	StreamMetrics(c *gin.Context)          // GET /telemetry/stream (Server-Sent Events)
+	StreamMetricsWebSocket(c *gin.Context) // GET /telemetry/stream/ws
 	// Observability
 	GetPerformanceMetrics(c *gin.Context) // GET /telemetry/performance
 	GetHealthStatus(c *gin.Context)       // GET /telemetry/health
 	GetSwitchList(c *gin.Context)         // GET /telemetry/switches
 	GetMetricTypes(c *gin.Context)        // GET /telemetry/metric-types
+	GetSources(c *gin.Context)            // GET /telemetry/sources
+}
+
+// SourceStatsProvider reports the state of every polled ingestion source
+type SourceStatsProvider interface {
+	SourceStats() []map[string]interface{}
 }
 
 type telemetryHandler struct {
@@ -31,15 +84,408 @@ type telemetryHandler struct {
 	ctx       service.Context
 	service   telemetry.TelemetryService
 	startTime time.Time
+
+	// Switches known to be registered, so pushed samples only register new ones
+	knownSwitches sync.Map
+
+	sources SourceStatsProvider
 }
 
-func NewTelemetryHandler(ctx service.Context, telemetryService telemetry.TelemetryService) TelemetryHandler {
-	return &telemetryHandler{
+// TelemetryHandlerOption configures optional telemetry handler components
+type TelemetryHandlerOption func(*telemetryHandler)
+
+// WithSources reports the polled ingestion sources on GET /telemetry/sources
+func WithSources(sources SourceStatsProvider) TelemetryHandlerOption {
+	return func(h *telemetryHandler) {
+		h.sources = sources
+	}
+}
+
+func NewTelemetryHandler(ctx service.Context, telemetryService telemetry.TelemetryService, options ...TelemetryHandlerOption) TelemetryHandler {
+	h := &telemetryHandler{
 		logger:    ctx.LoggerFactory().(log.LoggerFactory).GetLogger("telemetry-handler"),
 		ctx:       ctx,
 		service:   telemetryService,
 		startTime: time.Now(),
 	}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+// IngestMetrics handles POST /telemetry/ingest. The body is a single JSON sample, a JSON
+// array of samples, or CSV in the generator's /counters layout (text/csv). Every row is
+// validated on its own and the response reports which rows were accepted or rejected.
+func (h *telemetryHandler) IngestMetrics(c *gin.Context) {
+	startTime := time.Now()
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIngestBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.RespondWithError(c, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("request body exceeds %d bytes", maxIngestBodyBytes))
+			return
+		}
+		utils.RespondWithError(c, http.StatusBadRequest, "failed to read request body: "+err.Error())
+		return
+	}
+
+	samples, results, err := parseIngestBody(c.ContentType(), body)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errUnsupportedIngestFormat) {
+			status = http.StatusUnsupportedMediaType
+		}
+		utils.RespondWithError(c, status, err.Error())
+		return
+	}
+	if len(samples) == 0 {
+		utils.RespondWithError(c, http.StatusBadRequest, "request body contains no samples")
+		return
+	}
+	if len(samples) > maxIngestRows {
+		utils.RespondWithError(c, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("request contains %d samples, at most %d are allowed", len(samples), maxIngestRows))
+		return
+	}
+
+	// Validate every row that parsed
+	valid := make([]models.TelemetryData, 0, len(samples))
+	validRows := make([]int, 0, len(samples))
+	for i := range samples {
+		if results[i].Status == models.IngestStatusRejected {
+			continue
+		}
+		if samples[i].Timestamp.IsZero() {
+			samples[i].Timestamp = startTime
+		}
+		if err := h.service.ValidateMetricData(samples[i]); err != nil {
+			results[i].Status = models.IngestStatusRejected
+			results[i].Reason = err.Error()
+			continue
+		}
+		valid = append(valid, samples[i])
+		validRows = append(validRows, i)
+	}
+
+	// Samples reference their switch, so unknown switches are registered first
+	unregistered := h.registerSwitches(valid)
+	accepted := make([]models.TelemetryData, 0, len(valid))
+	acceptedRows := make([]int, 0, len(valid))
+	for j, sample := range valid {
+		if reason, failed := unregistered[sample.SwitchID]; failed {
+			results[validRows[j]].Status = models.IngestStatusRejected
+			results[validRows[j]].Reason = reason
+			continue
+		}
+		accepted = append(accepted, sample)
+		acceptedRows = append(acceptedRows, validRows[j])
+	}
+
+	if len(accepted) > 0 {
+		if err := h.service.IngestBatch(accepted); err != nil {
+			h.logger.Errorf("Failed to ingest %d pushed samples: %v", len(accepted), err)
+			utils.RespondWithError(c, http.StatusInternalServerError, "failed to ingest metrics: "+err.Error())
+			return
+		}
+		for _, row := range acceptedRows {
+			results[row].Status = models.IngestStatusAccepted
+		}
+	}
+
+	response := &models.IngestResponse{
+		Results:   results,
+		Timestamp: time.Now(),
+	}
+	for _, result := range results {
+		if result.Status == models.IngestStatusAccepted {
+			response.Accepted++
+		} else {
+			response.Rejected++
+		}
+	}
+
+	duration := time.Since(startTime)
+	c.Header("X-Response-Time", duration.String())
+	c.Header("X-Accepted-Count", strconv.Itoa(response.Accepted))
+	c.Header("X-Rejected-Count", strconv.Itoa(response.Rejected))
+
+	if response.Accepted == 0 {
+		utils.RespondWithErrorData(c, http.StatusUnprocessableEntity, "no samples were accepted", response)
+		return
+	}
+	utils.RespondWithSuccess(c, response)
+}
+
+// registerSwitches registers switches that are not yet known and returns the
+// reason registration failed for each switch that could not be registered
+func (h *telemetryHandler) registerSwitches(samples []models.TelemetryData) map[string]string {
+	var unknown []string
+	seen := make(map[string]bool)
+	for _, sample := range samples {
+		if seen[sample.SwitchID] {
+			continue
+		}
+		seen[sample.SwitchID] = true
+		if _, known := h.knownSwitches.Load(sample.SwitchID); !known {
+			unknown = append(unknown, sample.SwitchID)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	// Refresh from the store once so existing switches keep their name and location
+	switches, err := h.service.GetSwitches()
+	if err != nil {
+		h.logger.Warnf("Failed to list switches before ingestion: %v", err)
+	}
+	for _, sw := range switches {
+		h.knownSwitches.Store(sw.ID, true)
+	}
+
+	failed := make(map[string]string)
+	for _, switchID := range unknown {
+		if _, known := h.knownSwitches.Load(switchID); known {
+			continue
+		}
+		if err := h.service.RegisterSwitch(models.Switch{
+			ID:      switchID,
+			Name:    switchID,
+			Created: time.Now(),
+		}); err != nil {
+			failed[switchID] = "failed to register switch: " + err.Error()
+			continue
+		}
+		h.knownSwitches.Store(switchID, true)
+	}
+	return failed
+}
+
+// errUnsupportedIngestFormat is returned for ingest bodies that are neither JSON nor CSV
+var errUnsupportedIngestFormat = errors.New("unsupported content type, expected application/json or text/csv")
+
+// parseIngestBody decodes a push ingestion body. results has one entry per sample;
+// rows that failed to parse are already marked rejected.
+func parseIngestBody(contentType string, body []byte) ([]models.TelemetryData, []models.IngestRowResult, error) {
+	trimmed := bytes.TrimSpace(body)
+
+	switch contentType {
+	case "application/json":
+		return parseIngestJSON(trimmed)
+	case "text/csv", "application/csv":
+		return parseIngestCSV(trimmed)
+	case "":
+		// Sniff the format when the client did not declare one
+		if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			return parseIngestJSON(trimmed)
+		}
+		return parseIngestCSV(trimmed)
+	default:
+		return nil, nil, errUnsupportedIngestFormat
+	}
+}
+
+func parseIngestJSON(body []byte) ([]models.TelemetryData, []models.IngestRowResult, error) {
+	var rows []json.RawMessage
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &rows); err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+	} else if len(body) > 0 {
+		rows = []json.RawMessage{body}
+	}
+
+	samples := make([]models.TelemetryData, len(rows))
+	results := make([]models.IngestRowResult, len(rows))
+	for i, row := range rows {
+		results[i].Row = i + 1
+		if err := json.Unmarshal(row, &samples[i]); err != nil {
+			results[i].Status = models.IngestStatusRejected
+			results[i].Reason = "invalid JSON sample: " + err.Error()
+			continue
+		}
+		results[i].SwitchID = samples[i].SwitchID
+	}
+	return samples, results, nil
+}
+
+func parseIngestCSV(body []byte) ([]models.TelemetryData, []models.IngestRowResult, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1 // Column count is checked per row
+	reader.TrimLeadingSpace = true
+
+	var samples []models.TelemetryData
+	var results []models.IngestRowResult
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if len(samples) == 0 && len(results) == 0 && models.IsCSVHeader(record) {
+			continue
+		}
+
+		result := models.IngestRowResult{Row: len(results) + 1, SwitchID: strings.TrimSpace(record[0])}
+		sample, err := models.ParseCSVRecord(record)
+		if err != nil {
+			result.Status = models.IngestStatusRejected
+			result.Reason = err.Error()
+		}
+		samples = append(samples, sample)
+		results = append(results, result)
+	}
+	return samples, results, nil
+}
+
+// StreamMetrics handles GET /telemetry/stream?switch_ids=&metrics= as Server-Sent Events.
+// Every update is sent as a "metrics" event; a "dropped" event reports events lost
+// because the client fell behind.
+func (h *telemetryHandler) StreamMetrics(c *gin.Context) {
+	sub, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, streamHeartbeatInterval)
+		events, dropped, err := sub.Next(waitCtx)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				if _, err := io.WriteString(c.Writer, ": keepalive\n\n"); err != nil {
+					return
+				}
+				c.Writer.Flush()
+				continue
+			}
+			return
+		}
+
+		if dropped > 0 {
+			if err := writeSSE(c.Writer, "", "dropped", models.StreamDropped{Dropped: dropped}); err != nil {
+				return
+			}
+		}
+		for _, event := range events {
+			if err := writeSSE(c.Writer, strconv.FormatUint(event.Seq, 10), "metrics", event); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// StreamMetricsWebSocket handles GET /telemetry/stream/ws?switch_ids=&metrics=.
+// Messages are JSON StreamMessage frames with the same events as the SSE stream.
+func (h *telemetryHandler) StreamMetricsWebSocket(c *gin.Context) {
+	sub, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	server := websocket.Server{
+		// websocket.Handler answers 403 to clients without an Origin header, which
+		// excludes most non-browser clients, so a missing Origin is accepted here
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			var err error
+			config.Origin, err = websocket.Origin(config, req)
+			return err
+		},
+	}
+	server.Handler = func(ws *websocket.Conn) {
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+
+		// Clients are not expected to send anything, a read error means they went away
+		go func() {
+			var discard []byte
+			for {
+				if err := websocket.Message.Receive(ws, &discard); err != nil {
+					cancel()
+					return
+				}
+			}
+		}()
+
+		for {
+			events, dropped, err := sub.Next(ctx)
+			if err != nil {
+				return
+			}
+
+			if dropped > 0 {
+				message := models.StreamMessage{Type: "dropped", Data: models.StreamDropped{Dropped: dropped}}
+				if err := websocket.JSON.Send(ws, message); err != nil {
+					return
+				}
+			}
+			for _, event := range events {
+				if err := websocket.JSON.Send(ws, models.StreamMessage{Type: "metrics", Data: event}); err != nil {
+					return
+				}
+			}
+		}
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// subscribe parses the stream filter and registers a subscription, responding with an error on failure
+func (h *telemetryHandler) subscribe(c *gin.Context) (*stream.Subscription, bool) {
+	metricTypes, err := h.parseMetricTypes(c.Query("metrics"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	var switchIDs []string
+	for _, switchID := range strings.Split(c.Query("switch_ids"), ",") {
+		if switchID = strings.TrimSpace(switchID); switchID != "" {
+			switchIDs = append(switchIDs, switchID)
+		}
+	}
+
+	sub, err := h.service.Subscribe(stream.Filter{SwitchIDs: switchIDs, MetricTypes: metricTypes})
+	if err != nil {
+		if errors.Is(err, telemetry.ErrStreamingDisabled) || errors.Is(err, stream.ErrTooManySubscribers) ||
+			errors.Is(err, stream.ErrClosed) {
+			utils.RespondWithError(c, http.StatusServiceUnavailable, err.Error())
+			return nil, false
+		}
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return sub, true
+}
+
+// writeSSE writes a single Server-Sent Event with a JSON payload
+func writeSSE(w io.Writer, id, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
 }
 
 // GetMetric handles GET /telemetry/metrics/:switchId/:metricType
@@ -64,7 +510,7 @@ func (h *telemetryHandler) GetMetric(c *gin.Context) {
 	metricType := models.MetricType(metricTypeStr)
 
 	// Validate metric type
-	if !h.isValidMetricType(metricType) {
+	if !metricType.IsValid() {
 		utils.RespondWithError(c, http.StatusBadRequest, "invalid metric type: "+metricTypeStr)
 		return
 	}
@@ -162,7 +608,7 @@ func (h *telemetryHandler) ListMetricsByType(c *gin.Context) {
 		}
 
 		metricType := models.MetricType(metricTypeStr)
-		if !h.isValidMetricType(metricType) {
+		if !metricType.IsValid() {
 			utils.RespondWithError(c, http.StatusBadRequest, "invalid metric type: "+metricTypeStr)
 			return
 		}
@@ -236,6 +682,387 @@ func (h *telemetryHandler) ListMetricsByType(c *gin.Context) {
 	utils.RespondWithSuccess(c, response)
 }
 
+// GetMetricHistory handles GET /telemetry/metrics/:switchId/history?from=&to=&metrics=&limit=&cursor=&step=&agg=
+func (h *telemetryHandler) GetMetricHistory(c *gin.Context) {
+	startTime := time.Now()
+
+	switchID := c.Param("switchId")
+	if switchID == "" {
+		utils.RespondWithError(c, http.StatusBadRequest, "switchId is required")
+		return
+	}
+
+	now := time.Now()
+	to, err := utils.ParseTimeParam(c.Query("to"), now, now)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "invalid 'to' parameter: "+err.Error())
+		return
+	}
+
+	from, err := utils.ParseTimeParam(c.Query("from"), now, to.Add(-defaultHistoryWindow))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "invalid 'from' parameter: "+err.Error())
+		return
+	}
+
+	if from.After(to) {
+		utils.RespondWithError(c, http.StatusBadRequest, "'from' must not be after 'to'")
+		return
+	}
+
+	metricTypes, err := h.parseMetricTypes(c.Query("metrics"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := storage.DefaultHistoryPageSize
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > storage.MaxHistoryPageSize {
+			utils.RespondWithError(c, http.StatusBadRequest,
+				fmt.Sprintf("limit must be between 1 and %d", storage.MaxHistoryPageSize))
+			return
+		}
+	}
+
+	var after *models.HistoryCursor
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err = models.ParseHistoryCursor(cursor)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "invalid cursor: "+err.Error())
+			return
+		}
+	}
+
+	var step time.Duration
+	if stepStr := c.Query("step"); stepStr != "" {
+		step, err = utils.ParseDurationParam(stepStr)
+		if err != nil || step < time.Second {
+			utils.RespondWithError(c, http.StatusBadRequest, "invalid step: must be a duration of at least 1s, e.g. 1m, 5m, 1h")
+			return
+		}
+		if buckets := int(to.Sub(from)/step) + 1; buckets > storage.MaxHistoryBuckets {
+			utils.RespondWithError(c, http.StatusBadRequest,
+				fmt.Sprintf("step %s yields %d windows, maximum is %d", step, buckets, storage.MaxHistoryBuckets))
+			return
+		}
+	}
+
+	var aggregation models.Aggregation
+	if aggStr := c.Query("agg"); aggStr != "" {
+		if step == 0 {
+			utils.RespondWithError(c, http.StatusBadRequest, "'agg' requires 'step'")
+			return
+		}
+		aggregation, err = models.ParseAggregation(aggStr)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	response, err := h.service.GetMetricHistory(models.HistoryQuery{
+		SwitchID:    switchID,
+		From:        from,
+		To:          to,
+		Metrics:     metricTypes,
+		Limit:       limit,
+		After:       after,
+		Step:        step,
+		Aggregation: aggregation,
+	})
+	if errors.Is(err, telemetry.ErrInvalidQuery) {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("Failed to get metric history for switch %s: %v", switchID, err)
+		utils.RespondWithError(c, http.StatusInternalServerError, "failed to retrieve metric history: "+err.Error())
+		return
+	}
+
+	duration := time.Since(startTime)
+	c.Header("X-Response-Time", duration.String())
+	c.Header("X-Switch-ID", switchID)
+	c.Header("X-Row-Count", strconv.Itoa(response.Count))
+
+	h.logger.Debugf("GetMetricHistory: switch=%s, from=%s, to=%s, rows=%d, duration=%v",
+		switchID, from.Format(time.RFC3339), to.Format(time.RFC3339), response.Count, duration)
+	utils.RespondWithSuccess(c, response)
+}
+
+// GetSwitchSummary handles GET /telemetry/switches/:id/summary?window=24h
+func (h *telemetryHandler) GetSwitchSummary(c *gin.Context) {
+	startTime := time.Now()
+
+	switchID := c.Param("id")
+	if switchID == "" {
+		utils.RespondWithError(c, http.StatusBadRequest, "switch id is required")
+		return
+	}
+
+	window := defaultSummaryWindow
+	if windowStr := c.Query("window"); windowStr != "" {
+		var err error
+		window, err = utils.ParseDurationParam(windowStr)
+		if err != nil || window <= 0 || window > maxSummaryWindow {
+			utils.RespondWithError(c, http.StatusBadRequest,
+				fmt.Sprintf("invalid window: must be a positive duration up to %s, e.g. 1h, 24h, 7d", maxSummaryWindow))
+			return
+		}
+	}
+
+	summary, err := h.service.GetSwitchSummary(switchID, window)
+	if err != nil {
+		h.logger.Errorf("Failed to get summary for switch %s: %v", switchID, err)
+		utils.RespondWithError(c, http.StatusInternalServerError, "failed to retrieve switch summary: "+err.Error())
+		return
+	}
+
+	duration := time.Since(startTime)
+	c.Header("X-Response-Time", duration.String())
+	c.Header("X-Switch-ID", switchID)
+
+	h.logger.Debugf("GetSwitchSummary: switch=%s, window=%s, samples=%d, duration=%v",
+		switchID, window, summary.SampleCount, duration)
+	utils.RespondWithSuccess(c, summary)
+}
+
+// GetFabricStatistics handles GET /telemetry/stats?group_by=location
+func (h *telemetryHandler) GetFabricStatistics(c *gin.Context) {
+	startTime := time.Now()
+
+	groupBy := c.Query("group_by")
+	if groupBy != "" && groupBy != models.GroupByLocation {
+		utils.RespondWithError(c, http.StatusBadRequest, "invalid group_by: supported value is 'location'")
+		return
+	}
+
+	stats, err := h.service.GetFabricStatistics(groupBy)
+	if err != nil {
+		h.logger.Errorf("Failed to compute fabric statistics: %v", err)
+		utils.RespondWithError(c, http.StatusInternalServerError, "failed to compute fabric statistics: "+err.Error())
+		return
+	}
+
+	duration := time.Since(startTime)
+	c.Header("X-Response-Time", duration.String())
+	c.Header("X-Switch-Count", strconv.Itoa(stats.SwitchCount))
+
+	utils.RespondWithSuccess(c, stats)
+}
+
+// GetTopSwitches handles GET /telemetry/top?metric=temperature_c&k=10&order=desc&window=&agg=
+func (h *telemetryHandler) GetTopSwitches(c *gin.Context) {
+	startTime := time.Now()
+
+	metricType := models.MetricType(c.Query("metric"))
+	if metricType == "" {
+		utils.RespondWithError(c, http.StatusBadRequest, "metric is required")
+		return
+	}
+	if !metricType.IsValid() {
+		utils.RespondWithError(c, http.StatusBadRequest, "invalid metric type: "+string(metricType))
+		return
+	}
+
+	k := defaultTopK
+	if kStr := c.Query("k"); kStr != "" {
+		var err error
+		k, err = strconv.Atoi(kStr)
+		if err != nil || k <= 0 || k > maxTopK {
+			utils.RespondWithError(c, http.StatusBadRequest, fmt.Sprintf("k must be between 1 and %d", maxTopK))
+			return
+		}
+	}
+
+	descending := true
+	switch order := c.DefaultQuery("order", "desc"); order {
+	case "desc":
+	case "asc":
+		descending = false
+	default:
+		utils.RespondWithError(c, http.StatusBadRequest, "order must be 'asc' or 'desc'")
+		return
+	}
+
+	query := models.TopSwitchesQuery{
+		Metric:     metricType,
+		K:          k,
+		Descending: descending,
+	}
+
+	if windowStr := c.Query("window"); windowStr != "" {
+		window, err := utils.ParseDurationParam(windowStr)
+		if err != nil || window <= 0 || window > maxSummaryWindow {
+			utils.RespondWithError(c, http.StatusBadRequest,
+				fmt.Sprintf("invalid window: must be a positive duration up to %s", maxSummaryWindow))
+			return
+		}
+		query.Window = window
+	}
+
+	if aggStr := c.Query("agg"); aggStr != "" {
+		if query.Window == 0 {
+			utils.RespondWithError(c, http.StatusBadRequest, "'agg' requires 'window'")
+			return
+		}
+		aggregation, err := models.ParseAggregation(aggStr)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		query.Aggregation = aggregation
+	}
+
+	response, err := h.service.GetTopSwitches(query)
+	if err != nil {
+		h.logger.Errorf("Failed to rank switches by %s: %v", metricType, err)
+		utils.RespondWithError(c, http.StatusInternalServerError, "failed to rank switches: "+err.Error())
+		return
+	}
+
+	duration := time.Since(startTime)
+	c.Header("X-Response-Time", duration.String())
+	c.Header("X-Switch-Count", strconv.Itoa(response.Count))
+
+	utils.RespondWithSuccess(c, response)
+}
+
+// GetAlerts handles GET /telemetry/alerts?switch_id=&state=pending|firing
+func (h *telemetryHandler) GetAlerts(c *gin.Context) {
+	startTime := time.Now()
+
+	state := models.AlertState(c.Query("state"))
+	if state != "" && state != models.AlertStatePending && state != models.AlertStateFiring {
+		utils.RespondWithError(c, http.StatusBadRequest, "state must be 'pending' or 'firing'")
+		return
+	}
+
+	response, err := h.service.GetActiveAlerts(c.Query("switch_id"), state)
+	if err != nil {
+		h.respondWithAlertError(c, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	c.Header("X-Response-Time", duration.String())
+	c.Header("X-Alert-Count", strconv.Itoa(response.Count))
+
+	utils.RespondWithSuccess(c, response)
+}
+
+// GetAlertHistory handles GET /telemetry/alerts/history?switch_id=&rule=&from=&to=&limit=
+func (h *telemetryHandler) GetAlertHistory(c *gin.Context) {
+	startTime := time.Now()
+
+	now := time.Now()
+	to, err := utils.ParseTimeParam(c.Query("to"), now, now)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "invalid 'to' parameter: "+err.Error())
+		return
+	}
+
+	from, err := utils.ParseTimeParam(c.Query("from"), now, to.Add(-defaultSummaryWindow))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "invalid 'from' parameter: "+err.Error())
+		return
+	}
+
+	if from.After(to) {
+		utils.RespondWithError(c, http.StatusBadRequest, "'from' must not be after 'to'")
+		return
+	}
+
+	limit := storage.DefaultAlertHistoryLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > storage.MaxAlertHistoryLimit {
+			utils.RespondWithError(c, http.StatusBadRequest,
+				fmt.Sprintf("limit must be between 1 and %d", storage.MaxAlertHistoryLimit))
+			return
+		}
+	}
+
+	response, err := h.service.GetAlertHistory(models.AlertHistoryQuery{
+		SwitchID: c.Query("switch_id"),
+		RuleName: c.Query("rule"),
+		From:     from,
+		To:       to,
+		Limit:    limit,
+	})
+	if err != nil {
+		h.respondWithAlertError(c, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	c.Header("X-Response-Time", duration.String())
+	c.Header("X-Alert-Count", strconv.Itoa(response.Count))
+
+	utils.RespondWithSuccess(c, response)
+}
+
+// respondWithAlertError maps alerting errors to HTTP responses
+func (h *telemetryHandler) respondWithAlertError(c *gin.Context, err error) {
+	if errors.Is(err, telemetry.ErrAlertingDisabled) {
+		utils.RespondWithError(c, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	h.logger.Errorf("Failed to query alerts: %v", err)
+	utils.RespondWithError(c, http.StatusInternalServerError, "failed to query alerts: "+err.Error())
+}
+
+// GetAnomalies handles GET /telemetry/anomalies?switch_id=&metric=&since=&limit=
+func (h *telemetryHandler) GetAnomalies(c *gin.Context) {
+	startTime := time.Now()
+
+	metricType := models.MetricType(c.Query("metric"))
+	if metricType != "" && !metricType.IsValid() {
+		utils.RespondWithError(c, http.StatusBadRequest, "invalid metric type: "+string(metricType))
+		return
+	}
+
+	since, err := utils.ParseTimeParam(c.Query("since"), startTime, time.Time{})
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "invalid 'since' parameter: "+err.Error())
+		return
+	}
+
+	limit := defaultAnomalyLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxAnomalyLimit {
+			utils.RespondWithError(c, http.StatusBadRequest,
+				fmt.Sprintf("limit must be between 1 and %d", maxAnomalyLimit))
+			return
+		}
+	}
+
+	response, err := h.service.GetAnomalies(models.AnomalyQuery{
+		SwitchID: c.Query("switch_id"),
+		Metric:   metricType,
+		Since:    since,
+		Limit:    limit,
+	})
+	if err != nil {
+		if errors.Is(err, telemetry.ErrAnomalyDetectionDisabled) {
+			utils.RespondWithError(c, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		h.logger.Errorf("Failed to query anomalies: %v", err)
+		utils.RespondWithError(c, http.StatusInternalServerError, "failed to query anomalies: "+err.Error())
+		return
+	}
+
+	duration := time.Since(startTime)
+	c.Header("X-Response-Time", duration.String())
+	c.Header("X-Anomaly-Count", strconv.Itoa(response.Count))
+
+	utils.RespondWithSuccess(c, response)
+}
+
 // GetPerformanceMetrics handles GET /telemetry/performance
 func (h *telemetryHandler) GetPerformanceMetrics(c *gin.Context) {
 	startTime := time.Now()
@@ -301,6 +1128,29 @@ func (h *telemetryHandler) GetSwitchList(c *gin.Context) {
 	utils.RespondWithSuccess(c, response)
 }
 
+// GetSources handles GET /telemetry/sources. Each source reports its poll
+// counters, deduplication state and a status of pending, ok or failing.
+func (h *telemetryHandler) GetSources(c *gin.Context) {
+	sources := []map[string]interface{}{}
+	if h.sources != nil {
+		sources = h.sources.SourceStats()
+	}
+
+	failing := 0
+	for _, source := range sources {
+		if source["status"] == "failing" {
+			failing++
+		}
+	}
+
+	utils.RespondWithSuccess(c, map[string]interface{}{
+		"sources":   sources,
+		"count":     len(sources),
+		"failing":   failing,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
 // GetMetricTypes handles GET /telemetry/metric-types
 func (h *telemetryHandler) GetMetricTypes(c *gin.Context) {
 	metricTypes := []string{
@@ -333,7 +1183,7 @@ func (h *telemetryHandler) handleMetricsByType(c *gin.Context, metricTypesStr st
 		}
 
 		metricType := models.MetricType(metricTypeStr)
-		if !h.isValidMetricType(metricType) {
+		if !metricType.IsValid() {
 			utils.RespondWithError(c, http.StatusBadRequest, "invalid metric type: "+metricTypeStr)
 			return
 		}
@@ -407,21 +1257,25 @@ func (h *telemetryHandler) handleMetricsByType(c *gin.Context, metricTypesStr st
 	utils.RespondWithSuccess(c, response)
 }
 
-// isValidMetricType validates if the metric type is supported
-func (h *telemetryHandler) isValidMetricType(metricType models.MetricType) bool {
-	validTypes := []models.MetricType{
-		models.MetricBandwidth,
-		models.MetricLatency,
-		models.MetricPacketErrors,
-		models.MetricUtilization,
-		models.MetricTemperature,
+// parseMetricTypes parses a comma-separated list of metric types, empty input yields nil
+func (h *telemetryHandler) parseMetricTypes(metricTypesStr string) ([]models.MetricType, error) {
+	if metricTypesStr == "" {
+		return nil, nil
 	}
 
-	for _, validType := range validTypes {
-		if metricType == validType {
-			return true
+	var metricTypes []models.MetricType
+	for _, metricTypeStr := range strings.Split(metricTypesStr, ",") {
+		metricTypeStr = strings.TrimSpace(metricTypeStr)
+		if metricTypeStr == "" {
+			continue
 		}
+
+		metricType := models.MetricType(metricTypeStr)
+		if !metricType.IsValid() {
+			return nil, fmt.Errorf("invalid metric type: %s", metricTypeStr)
+		}
+		metricTypes = append(metricTypes, metricType)
 	}
 
-	return false
+	return metricTypes, nil
 }
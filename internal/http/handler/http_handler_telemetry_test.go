@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/telemetry"
+	"github.com/ufm/internal/telemetry/models"
+	"github.com/ufm/internal/telemetry/storage"
+	"github.com/ufm/internal/telemetry/stream"
+)
+
+func TestParseIngestBody_JSONObject(t *testing.T) {
+	body := `{"switch_id":"switch-001","bandwidth_mbps":1000,"latency_ms":1.5,"temperature_c":45}`
+
+	samples, results, err := parseIngestBody("application/json", []byte(body))
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "switch-001", samples[0].SwitchID)
+	assert.Equal(t, 1000.0, samples[0].BandwidthMbps)
+	assert.Equal(t, 1, results[0].Row)
+	assert.Empty(t, results[0].Status)
+}
+
+func TestParseIngestBody_JSONArrayRejectsBadRows(t *testing.T) {
+	body := `[
+		{"switch_id":"switch-001","bandwidth_mbps":1000},
+		{"switch_id":"switch-002","bandwidth_mbps":"fast"}
+	]`
+
+	samples, results, err := parseIngestBody("application/json", []byte(body))
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	assert.Empty(t, results[0].Status)
+	assert.Equal(t, models.IngestStatusRejected, results[1].Status)
+	assert.Equal(t, 2, results[1].Row)
+	assert.Contains(t, results[1].Reason, "invalid JSON sample")
+}
+
+func TestParseIngestBody_InvalidJSONArray(t *testing.T) {
+	_, _, err := parseIngestBody("application/json", []byte(`[{"switch_id":`))
+	assert.Error(t, err)
+}
+
+func TestParseIngestBody_CSV(t *testing.T) {
+	body := "switch_id,timestamp,bandwidth_mbps,latency_ms,packet_errors,utilization_pct,temperature_c\n" +
+		"switch-001,2024-01-01T00:00:00Z,1000,1.5,3,50,45\n" +
+		"switch-002,not-a-time,1000,1.5,3,50,45\n" +
+		"switch-003,2024-01-01T00:00:00Z,1000\n"
+
+	samples, results, err := parseIngestBody("text/csv", []byte(body))
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+
+	assert.Equal(t, "switch-001", samples[0].SwitchID)
+	assert.Equal(t, int64(3), samples[0].PacketErrors)
+	assert.Empty(t, results[0].Status)
+
+	assert.Equal(t, models.IngestStatusRejected, results[1].Status)
+	assert.Equal(t, "switch-002", results[1].SwitchID)
+	assert.Contains(t, results[1].Reason, "invalid timestamp")
+
+	assert.Equal(t, models.IngestStatusRejected, results[2].Status)
+	assert.Contains(t, results[2].Reason, "insufficient columns")
+}
+
+func TestParseIngestBody_SniffsFormat(t *testing.T) {
+	samples, _, err := parseIngestBody("", []byte(`[{"switch_id":"switch-001"}]`))
+	require.NoError(t, err)
+	assert.Len(t, samples, 1)
+
+	samples, _, err = parseIngestBody("", []byte("switch-001,2024-01-01T00:00:00Z,1000,1.5,3,50,45"))
+	require.NoError(t, err)
+	assert.Len(t, samples, 1)
+}
+
+func TestParseIngestBody_UnsupportedContentType(t *testing.T) {
+	_, _, err := parseIngestBody("application/xml", []byte("<sample/>"))
+	assert.ErrorIs(t, err, errUnsupportedIngestFormat)
+}
+
+// newTestTelemetryHandler creates a handler over telemetryService with a mocked service context
+func newTestTelemetryHandler(telemetryService telemetry.TelemetryService, options ...TelemetryHandlerOption) TelemetryHandler {
+	mockCtx := &mockContext{}
+	mockLoggerFactory := &mockLoggerFactory{}
+	mockCtx.On("LoggerFactory").Return(mockLoggerFactory)
+	mockLoggerFactory.On("GetLogger", "telemetry-handler").Return(log.DefaultLogger)
+	return NewTelemetryHandler(mockCtx, telemetryService, options...)
+}
+
+func TestTelemetryHandler_GetSwitchSummary(t *testing.T) {
+	repository := storage.NewMemoryRepository(100)
+	require.NoError(t, repository.CreateSwitch(context.Background(), models.Switch{ID: "switch-001", Name: "switch-001"}))
+	require.NoError(t, repository.StoreMetrics(context.Background(), []models.TelemetryData{
+		{SwitchID: "switch-001", Timestamp: time.Now().Add(-time.Minute), TemperatureC: 40},
+		{SwitchID: "switch-001", Timestamp: time.Now().Add(-2 * time.Hour), TemperatureC: 80},
+	}))
+	store := storage.NewHybridStore(storage.NewInMemoryCache(), repository, storage.DefaultHybridStoreConfig(), nil)
+	handler := newTestTelemetryHandler(telemetry.NewTelemetryService(store, nil))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/telemetry/switches/:id/summary", handler.GetSwitchSummary)
+
+	tests := []struct {
+		name            string
+		query           string
+		expectedCode    int
+		expectedSamples int64
+	}{
+		{"default window", "", http.StatusOK, 2},
+		{"explicit window", "?window=1h", http.StatusOK, 1},
+		{"day suffix", "?window=7d", http.StatusOK, 2},
+		{"invalid window", "?window=soon", http.StatusBadRequest, 0},
+		{"negative window", "?window=-1h", http.StatusBadRequest, 0},
+		{"window beyond maximum", "?window=91d", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/telemetry/switches/switch-001/summary"+tt.query, nil))
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+
+			var response struct {
+				Success bool                 `json:"success"`
+				Data    models.SwitchSummary `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedCode == http.StatusOK, response.Success)
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, "switch-001", response.Data.SwitchID)
+				assert.Equal(t, tt.expectedSamples, response.Data.SampleCount)
+				assert.Equal(t, "switch-001", w.Header().Get("X-Switch-ID"))
+			}
+		})
+	}
+}
+
+func TestTelemetryHandler_StreamMetricsWebSocketHandshake(t *testing.T) {
+	store := storage.NewHybridStore(storage.NewInMemoryCache(), storage.NewMemoryRepository(100), storage.DefaultHybridStoreConfig(), nil)
+	hub := stream.NewHub(stream.DefaultConfig(), nil)
+	defer hub.Close()
+	handler := newTestTelemetryHandler(telemetry.NewTelemetryService(store, nil, telemetry.WithStreamHub(hub)))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/telemetry/stream/ws", handler.StreamMetricsWebSocket)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// Browsers send an Origin header, command-line and service clients usually do not
+	for name, origin := range map[string]string{"without origin": "", "with origin": "http://dashboard.example.com"} {
+		t.Run(name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", server.Listener.Addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+
+			request := "GET /telemetry/stream/ws HTTP/1.1\r\n" +
+				"Host: " + server.Listener.Addr().String() + "\r\n" +
+				"Upgrade: websocket\r\n" +
+				"Connection: Upgrade\r\n" +
+				"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+				"Sec-WebSocket-Version: 13\r\n"
+			if origin != "" {
+				request += "Origin: " + origin + "\r\n"
+			}
+			_, err = conn.Write([]byte(request + "\r\n"))
+			require.NoError(t, err)
+
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+			response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+		})
+	}
+}
+
+// stubHistoryService answers every history query with err
+type stubHistoryService struct {
+	telemetry.TelemetryService
+	err error
+}
+
+func (s stubHistoryService) GetMetricHistory(query models.HistoryQuery) (*models.MetricHistoryResponse, error) {
+	return nil, s.err
+}
+
+func TestTelemetryHandler_GetMetricHistoryErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{"invalid query", fmt.Errorf("%w: step 1s yields 90001 windows, maximum is 10000", telemetry.ErrInvalidQuery), http.StatusBadRequest},
+		{"storage failure", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestTelemetryHandler(stubHistoryService{err: tt.err})
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.GET("/telemetry/metrics/:switchId/history", handler.GetMetricHistory)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/telemetry/metrics/switch-001/history", nil))
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.err.Error())
+		})
+	}
+}
+
+// stubSources reports a fixed set of source stats
+type stubSources []map[string]interface{}
+
+func (s stubSources) SourceStats() []map[string]interface{} {
+	return s
+}
+
+func TestTelemetryHandler_GetSources(t *testing.T) {
+	tests := []struct {
+		name            string
+		options         []TelemetryHandlerOption
+		expectedCount   int
+		expectedFailing int
+	}{
+		{"ingestion disabled", nil, 0, 0},
+		{"mixed statuses", []TelemetryHandlerOption{WithSources(stubSources{
+			{"name": "dc-east", "status": "ok"},
+			{"name": "dc-west", "status": "failing"},
+			{"name": "dc-north", "status": "pending"},
+		})}, 3, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestTelemetryHandler(nil, tt.options...)
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.GET("/telemetry/sources", handler.GetSources)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/telemetry/sources", nil))
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response struct {
+				Data struct {
+					Sources []map[string]interface{} `json:"sources"`
+					Count   int                      `json:"count"`
+					Failing int                      `json:"failing"`
+				} `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.NotNil(t, response.Data.Sources, "sources is an empty list, not null")
+			assert.Len(t, response.Data.Sources, tt.expectedCount)
+			assert.Equal(t, tt.expectedCount, response.Data.Count)
+			assert.Equal(t, tt.expectedFailing, response.Data.Failing)
+		})
+	}
+}
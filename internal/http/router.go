@@ -27,6 +27,11 @@ func RegisterHandlers(
 
 	// Root level telemetry routes for convenience (optional)
 	telemetryRoot := engine.Group("/telemetry")
+	telemetryRoot.POST("/ingest", telemetryMiddlewareFunc, metricsMiddlewareFunc(), telemetryHandler.IngestMetrics)
+	// Streams are long-lived, so they skip the request duration metrics
+	telemetryRoot.GET("/stream", telemetryMiddlewareFunc, telemetryHandler.StreamMetrics)
+	telemetryRoot.GET("/stream/ws", telemetryMiddlewareFunc, telemetryHandler.StreamMetricsWebSocket)
+	telemetryRoot.GET("/metrics/:switchId/history", telemetryMiddlewareFunc, metricsMiddlewareFunc(), telemetryHandler.GetMetricHistory)
 	telemetryRoot.GET("/metrics/:switchId/:metricType", telemetryMiddlewareFunc, metricsMiddlewareFunc(), telemetryHandler.GetMetric)
 	telemetryRoot.GET("/metrics/:switchId", telemetryMiddlewareFunc, metricsMiddlewareFunc(), telemetryHandler.ListMetrics)
 	telemetryRoot.GET("/metrics", telemetryMiddlewareFunc, metricsMiddlewareFunc(), telemetryHandler.ListMetrics)
@@ -34,5 +39,12 @@ func RegisterHandlers(
 	telemetryRoot.GET("/performance", metricsMiddlewareFunc(), telemetryHandler.GetPerformanceMetrics)
 	telemetryRoot.GET("/health", metricsMiddlewareFunc(), telemetryHandler.GetHealthStatus)
 	telemetryRoot.GET("/switches", metricsMiddlewareFunc(), telemetryHandler.GetSwitchList)
+	telemetryRoot.GET("/sources", metricsMiddlewareFunc(), telemetryHandler.GetSources)
+	telemetryRoot.GET("/switches/:id/summary", metricsMiddlewareFunc(), telemetryHandler.GetSwitchSummary)
 	telemetryRoot.GET("/metric-types", metricsMiddlewareFunc(), telemetryHandler.GetMetricTypes)
+	telemetryRoot.GET("/stats", metricsMiddlewareFunc(), telemetryHandler.GetFabricStatistics)
+	telemetryRoot.GET("/top", metricsMiddlewareFunc(), telemetryHandler.GetTopSwitches)
+	telemetryRoot.GET("/alerts", metricsMiddlewareFunc(), telemetryHandler.GetAlerts)
+	telemetryRoot.GET("/alerts/history", metricsMiddlewareFunc(), telemetryHandler.GetAlertHistory)
+	telemetryRoot.GET("/anomalies", metricsMiddlewareFunc(), telemetryHandler.GetAnomalies)
 }
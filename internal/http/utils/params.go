@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTimeParam parses a query time value relative to now.
+// Accepted forms are RFC3339 timestamps, "now", and negative offsets such as "-15m", "-2h" or "-7d".
+// An empty value yields fallback.
+func ParseTimeParam(value string, now time.Time, fallback time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fallback, nil
+	}
+
+	if value == "now" {
+		return now, nil
+	}
+
+	if strings.HasPrefix(value, "-") {
+		offset, err := ParseDurationParam(value[1:])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time %q: %w", value, err)
+		}
+		return now.Add(-offset), nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected RFC3339 or relative offset like -15m", value)
+	}
+	return t, nil
+}
+
+// ParseDurationParam parses a Go duration string, additionally accepting a "d" suffix for days
+func ParseDurationParam(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid day count: %w", err)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
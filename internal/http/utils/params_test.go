@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimeParam(t *testing.T) {
+	now := time.Date(2025, 8, 2, 12, 0, 0, 0, time.UTC)
+	fallback := now.Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		value       string
+		expected    time.Time
+		expectError bool
+	}{
+		{name: "empty uses fallback", value: "", expected: fallback},
+		{name: "now", value: "now", expected: now},
+		{name: "relative minutes", value: "-15m", expected: now.Add(-15 * time.Minute)},
+		{name: "relative hours", value: "-2h", expected: now.Add(-2 * time.Hour)},
+		{name: "relative days", value: "-7d", expected: now.Add(-7 * 24 * time.Hour)},
+		{name: "rfc3339", value: "2025-08-01T10:30:00Z", expected: time.Date(2025, 8, 1, 10, 30, 0, 0, time.UTC)},
+		{name: "invalid relative", value: "-abc", expectError: true},
+		{name: "invalid absolute", value: "yesterday", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseTimeParam(tt.value, now, fallback)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, tt.expected.Equal(result), "expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseDurationParam(t *testing.T) {
+	d, err := ParseDurationParam("5m")
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, d)
+
+	d, err = ParseDurationParam("2d")
+	assert.NoError(t, err)
+	assert.Equal(t, 48*time.Hour, d)
+
+	_, err = ParseDurationParam("xd")
+	assert.Error(t, err)
+}
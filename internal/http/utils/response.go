@@ -29,3 +29,15 @@ func RespondWithError(c *gin.Context, statusCode int, message string) {
 
 	c.JSON(statusCode, response)
 }
+
+// RespondWithErrorData sends an error JSON response that also carries data, such as per-item results
+func RespondWithErrorData(c *gin.Context, statusCode int, message string, data interface{}) {
+	response := models.APIResponse{
+		Success:   false,
+		Data:      data,
+		Error:     message,
+		Timestamp: time.Now(),
+	}
+
+	c.JSON(statusCode, response)
+}
@@ -0,0 +1,301 @@
+// Package migrate applies versioned SQL schema migrations and records them in
+// a schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ufm/internal/log"
+)
+
+// lockID identifies the PostgreSQL advisory lock held while migrations run, so
+// concurrent server replicas starting together apply each version exactly once
+const lockID int64 = 0x75666d5f6d6967 // "ufm_mig"
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_]+)\.sql$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string // Empty when the migration cannot be rolled back
+}
+
+// Status reports whether a known migration has been applied
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time // nil when pending
+}
+
+// Load reads migrations from fsys. Up scripts are NNN_name.sql at the root and
+// rollbacks are down/NNN_name.sql. Migrations are returned in version order.
+func Load(fsys fs.FS) ([]Migration, error) {
+	upFiles, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(upFiles))
+	seen := make(map[int64]string, len(upFiles))
+	for _, file := range upFiles {
+		version, name, err := parseFileName(file)
+		if err != nil {
+			return nil, err
+		}
+		if previous, ok := seen[version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, previous, file)
+		}
+		seen[version] = file
+
+		up, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		down, err := fs.ReadFile(fsys, path.Join("down", file))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read rollback for %s: %w", file, err)
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    name,
+			Up:      string(up),
+			Down:    string(down),
+		})
+	}
+
+	downFiles, err := fs.Glob(fsys, "down/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rollbacks: %w", err)
+	}
+	for _, file := range downFiles {
+		if _, ok := seen[mustVersion(file)]; !ok {
+			return nil, fmt.Errorf("rollback %s has no matching migration", file)
+		}
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Runner applies and rolls back migrations against a PostgreSQL database,
+// recording applied versions in the schema_migrations table
+type Runner struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     log.Logger
+}
+
+// NewRunner creates a migration runner
+func NewRunner(db *sql.DB, migrations []Migration, logger log.Logger) *Runner {
+	if logger == nil {
+		logger = log.DefaultLogger
+	}
+	return &Runner{
+		db:         db,
+		migrations: migrations,
+		logger:     logger,
+	}
+}
+
+// Up applies every pending migration in version order and returns how many ran
+func (r *Runner) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := r.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range r.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			r.logger.Infof("Applying migration %03d_%s", migration.Version, migration.Name)
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx,
+					`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+					migration.Version, migration.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %03d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down rolls back the most recently applied migrations, at most steps of them,
+// and returns how many were rolled back
+func (r *Runner) Down(ctx context.Context, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, fmt.Errorf("rollback steps must be positive")
+	}
+
+	count := 0
+	err := r.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(r.migrations) - 1; i >= 0 && count < steps; i-- {
+			migration := r.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %03d_%s has no rollback", migration.Version, migration.Name)
+			}
+
+			r.logger.Infof("Rolling back migration %03d_%s", migration.Version, migration.Name)
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("rollback of %03d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Status lists every known migration and when it was applied
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := r.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		statuses = make([]Status, 0, len(r.migrations))
+		for _, migration := range r.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if appliedAt, ok := applied[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory lock.
+// The schema_migrations table is created first if it does not exist.
+func (r *Runner) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire database connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Session-level advisory locks belong to the connection, so lock and unlock on the same one
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID); err != nil {
+			r.logger.Warnf("Failed to release migration lock: %v", err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// appliedVersions returns the applied migration versions and when they ran
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// inTx runs fn in a transaction on conn, so a failing script leaves no partial changes
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func parseFileName(file string) (int64, string, error) {
+	match := fileNamePattern.FindStringSubmatch(path.Base(file))
+	if match == nil {
+		return 0, "", fmt.Errorf("invalid migration file name %s (expected NNN_name.sql)", file)
+	}
+	version, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid migration version in %s: %w", file, err)
+	}
+	return version, match[2], nil
+}
+
+// mustVersion returns the version of a file name, or -1 when it does not parse
+func mustVersion(file string) int64 {
+	version, _, err := parseFileName(file)
+	if err != nil {
+		return -1
+	}
+	return version
+}
@@ -0,0 +1,129 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ufm/scripts/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_add_index.sql":         {Data: []byte("CREATE INDEX i ON t (c);")},
+		"001_create_table.sql":      {Data: []byte("CREATE TABLE t (c INT);")},
+		"down/001_create_table.sql": {Data: []byte("DROP TABLE t;")},
+		"README.md":                 {Data: []byte("not a migration")},
+	}
+
+	loaded, err := Load(fsys)
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+
+	assert.Equal(t, int64(1), loaded[0].Version)
+	assert.Equal(t, "create_table", loaded[0].Name)
+	assert.Equal(t, "DROP TABLE t;", loaded[0].Down)
+	assert.Equal(t, int64(2), loaded[1].Version)
+	assert.Empty(t, loaded[1].Down)
+}
+
+func TestLoad_RejectsInvalidSets(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"001_a.sql":  {Data: []byte("SELECT 1;")},
+				"0001_b.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+		{
+			name: "bad file name",
+			fsys: fstest.MapFS{"create.sql": {Data: []byte("SELECT 1;")}},
+		},
+		{
+			name: "orphan rollback",
+			fsys: fstest.MapFS{
+				"001_a.sql":      {Data: []byte("SELECT 1;")},
+				"down/002_b.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoad_EmbeddedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+
+	for i, migration := range loaded {
+		assert.Equal(t, int64(i+1), migration.Version, "versions should be contiguous")
+		assert.NotEmpty(t, migration.Down, "migration %03d_%s should have a rollback", migration.Version, migration.Name)
+	}
+}
+
+// TestRunner_UpDown needs a PostgreSQL database in UFM_TEST_DATABASE_URL. It uses
+// versions far above the real migrations so it leaves their bookkeeping alone.
+func TestRunner_UpDown(t *testing.T) {
+	dsn := os.Getenv("UFM_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("UFM_TEST_DATABASE_URL not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	runner := NewRunner(db, []Migration{
+		{Version: 9001, Name: "create_probe", Up: "CREATE TABLE migrate_probe (id INT);", Down: "DROP TABLE migrate_probe;"},
+		{Version: 9002, Name: "add_column", Up: "ALTER TABLE migrate_probe ADD COLUMN name TEXT;", Down: "ALTER TABLE migrate_probe DROP COLUMN name;"},
+	}, nil)
+
+	cleanup := func() {
+		_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS migrate_probe; DELETE FROM schema_migrations WHERE version IN (9001, 9002)")
+		require.NoError(t, err)
+	}
+	// Status creates schema_migrations, so clean up only once it exists
+	_, err = runner.Status(ctx)
+	require.NoError(t, err)
+	cleanup()
+	defer cleanup()
+
+	applied, err := runner.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+
+	applied, err = runner.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied, "second run should be a no-op")
+
+	rolledBack, err := runner.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rolledBack)
+
+	statuses, err := runner.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+
+	rolledBack, err = runner.Down(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rolledBack)
+}
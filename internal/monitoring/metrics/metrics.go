@@ -84,6 +84,31 @@ var (
 		[]string{"operation", "table"},
 	)
 
+	TelemetryFlushDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "telemetry_flush_duration_seconds",
+			Help:    "Time to write one coalesced telemetry batch to the database, including retries",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"status"},
+	)
+
+	TelemetryFlushLatency = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "telemetry_flush_latency_seconds",
+			Help:    "Time from buffering the oldest row of a batch until the batch is written",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		},
+	)
+
+	TelemetryFlushBatchRows = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "telemetry_flush_batch_rows",
+			Help:    "Number of rows per telemetry batch written to the database",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+		},
+	)
+
 	DatabaseConnectionsActive = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "database_connections_active",
@@ -91,6 +116,63 @@ var (
 		},
 	)
 
+	DatabaseConnectionsOpen = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "database_connections_open",
+			Help: "Number of open database connections, in use and idle",
+		},
+	)
+
+	DatabaseConnectionsIdle = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "database_connections_idle",
+			Help: "Number of idle database connections",
+		},
+	)
+
+	DatabaseConnectionsMax = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "database_connections_max",
+			Help: "Maximum number of open database connections, 0 for unlimited",
+		},
+	)
+
+	DatabaseConnectionWaitsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "database_connection_waits_total",
+			Help: "Total number of times a query waited for a free database connection",
+		},
+	)
+
+	DatabaseConnectionWaitSeconds = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "database_connection_wait_seconds_total",
+			Help: "Total time spent waiting for a free database connection in seconds",
+		},
+	)
+
+	CircuitBreakerState = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "database_circuit_breaker_state",
+			Help: "State of the database circuit breaker: 0 closed, 1 half-open, 2 open",
+		},
+	)
+
+	CircuitBreakerTransitionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "database_circuit_breaker_transitions_total",
+			Help: "Total number of database circuit breaker state changes",
+		},
+		[]string{"state"},
+	)
+
+	CircuitBreakerRejectedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "database_circuit_breaker_rejected_total",
+			Help: "Total number of database calls failed fast while the circuit breaker was open",
+		},
+	)
+
 	// Queue Metrics
 	QueueDepth = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -159,6 +241,64 @@ var (
 		},
 	)
 
+	// Ingestion Metrics
+	IngestionRowsParsedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ingestion_rows_parsed_total",
+			Help: "Total number of CSV rows parsed from ingestion sources",
+		},
+		[]string{"source"},
+	)
+
+	IngestionRowsSkippedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ingestion_rows_skipped_total",
+			Help: "Total number of invalid CSV rows skipped from ingestion sources",
+		},
+		[]string{"source"},
+	)
+
+	IngestionWireBytesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ingestion_wire_bytes_total",
+			Help: "Total number of response bytes received from ingestion sources before decompression",
+		},
+		[]string{"source"},
+	)
+
+	IngestionDecodedBytesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ingestion_decoded_bytes_total",
+			Help: "Total number of CSV bytes read from ingestion sources after decompression",
+		},
+		[]string{"source"},
+	)
+
+	IngestionNotModifiedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ingestion_not_modified_total",
+			Help: "Total number of polls answered with 304 Not Modified by ingestion sources",
+		},
+		[]string{"source"},
+	)
+
+	IngestionParseDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ingestion_parse_duration_seconds",
+			Help:    "Time to stream, parse and ingest one CSV payload in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"source"},
+	)
+
+	IngestionParseRowsPerSecond = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ingestion_parse_rows_per_second",
+			Help: "Rows per second parsed from the last CSV payload of each source",
+		},
+		[]string{"source"},
+	)
+
 	// Cache Metrics
 	CacheHitsTotal = promauto.NewCounter(
 		prometheus.CounterOpts{
@@ -197,6 +337,95 @@ var (
 		[]string{"switch_id"},
 	)
 
+	// Alerting Metrics
+	AlertsFiring = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "alerts_firing",
+			Help: "Number of switches currently firing per alert rule",
+		},
+		[]string{"rule", "severity"},
+	)
+
+	AlertTransitionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "alert_transitions_total",
+			Help: "Total number of alert state transitions",
+		},
+		[]string{"rule", "state"},
+	)
+
+	AlertNotificationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "alert_notifications_total",
+			Help: "Total number of alert notification attempts by outcome",
+		},
+		[]string{"notifier", "status"},
+	)
+
+	// Anomaly Detection Metrics
+	AnomaliesDetectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "anomalies_detected_total",
+			Help: "Total number of samples flagged as deviating from their switch baseline",
+		},
+		[]string{"metric_type", "direction"},
+	)
+
+	// Streaming Metrics
+	StreamSubscribers = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "stream_subscribers",
+			Help: "Number of connected SSE and WebSocket stream clients",
+		},
+	)
+
+	StreamEventsDroppedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "stream_events_dropped_total",
+			Help: "Total number of stream events dropped because a client fell behind",
+		},
+	)
+
+	// Retention Metrics
+	RetentionRowsPrunedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "retention_rows_pruned_total",
+			Help: "Total number of telemetry rows deleted by retention per tier",
+		},
+		[]string{"tier"},
+	)
+
+	RetentionRollupBucketsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "retention_rollup_buckets_total",
+			Help: "Total number of rollup buckets written per tier",
+		},
+		[]string{"tier"},
+	)
+
+	// Write-Ahead Log Metrics
+	WALBacklogBytes = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "telemetry_wal_backlog_bytes",
+			Help: "Bytes of telemetry batches buffered on disk waiting for the database",
+		},
+	)
+
+	WALBacklogBatches = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "telemetry_wal_backlog_batches",
+			Help: "Number of telemetry batches buffered on disk waiting for the database",
+		},
+	)
+
+	WALBatchesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "telemetry_wal_batches_total",
+			Help: "Total number of telemetry batches handled by the write-ahead log by outcome",
+		},
+		[]string{"status"},
+	)
+
 	// Error Metrics
 	ErrorsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -120,7 +120,13 @@ func (c *Config) GetBool(key string) bool {
 
 // GetInt returns configuration value as int
 func (c *Config) GetInt(key string) int {
-	if val, ok := c.data[key].(float64); ok {
+	// YAML decodes integers as int, JSON-style sources as float64
+	switch val := c.data[key].(type) {
+	case int:
+		return val
+	case int64:
+		return int(val)
+	case float64:
 		return int(val)
 	}
 	return 0
@@ -0,0 +1,406 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/monitoring/metrics"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+const (
+	// DefaultSeverity is assigned to rules that do not set one
+	DefaultSeverity = "warning"
+	// locationRefreshInterval controls how often switch locations are reloaded for location selectors
+	locationRefreshInterval = time.Minute
+	// eventQueueSize bounds the number of transitions waiting to be persisted
+	eventQueueSize = 1000
+)
+
+// HistoryStore persists alert transitions
+type HistoryStore interface {
+	RecordAlertEvents(ctx context.Context, events []models.AlertEvent) error
+	GetAlertHistory(ctx context.Context, query models.AlertHistoryQuery) ([]models.AlertEvent, error)
+}
+
+// SwitchLister resolves switch metadata used by location selectors
+type SwitchLister interface {
+	ListSwitches(ctx context.Context) ([]models.Switch, error)
+}
+
+type alertKey struct {
+	rule     string
+	switchID string
+}
+
+// Engine evaluates alert rules against ingested telemetry and tracks
+// the pending/firing state of every rule and switch pair
+type Engine struct {
+	rules    []Rule
+	history  HistoryStore
+	switches SwitchLister
+	logger   log.Logger
+
+	mu        sync.RWMutex
+	alerts    map[alertKey]*models.Alert
+	locations map[string]string
+
+	// Notification delivery, one worker per notifier
+	notifiers []*notifierWorker
+
+	// Background processing
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	events chan models.AlertEvent
+}
+
+// EngineOption configures optional alerting engine components
+type EngineOption func(*Engine)
+
+// WithNotifiers delivers every firing and resolved transition to the given notifiers
+func WithNotifiers(notifiers ...Notifier) EngineOption {
+	return func(e *Engine) {
+		for _, notifier := range notifiers {
+			e.notifiers = append(e.notifiers, newNotifierWorker(notifier, e.logger))
+		}
+	}
+}
+
+// NewEngine creates an alerting engine for the given rules.
+// history and switches may be nil, which disables persistence and location selectors.
+func NewEngine(rules []Rule, history HistoryStore, switches SwitchLister, logger log.Logger, options ...EngineOption) (*Engine, error) {
+	if logger == nil {
+		logger = log.DefaultLogger
+	}
+
+	names := make(map[string]bool, len(rules))
+	normalized := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate alert rule name: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		rule.Comparator, _ = ParseComparator(string(rule.Comparator))
+		if rule.Severity == "" {
+			rule.Severity = DefaultSeverity
+		}
+		normalized = append(normalized, rule)
+	}
+
+	e := &Engine{
+		rules:     normalized,
+		history:   history,
+		switches:  switches,
+		logger:    logger,
+		alerts:    make(map[alertKey]*models.Alert),
+		locations: make(map[string]string),
+		events:    make(chan models.AlertEvent, eventQueueSize),
+	}
+	for _, option := range options {
+		option(e)
+	}
+	return e, nil
+}
+
+// Rules returns the configured rules
+func (e *Engine) Rules() []Rule {
+	return e.rules
+}
+
+// Start loads switch locations and starts the background history writer and notifiers
+func (e *Engine) Start(ctx context.Context) error {
+	e.mu.Lock()
+	if e.ctx != nil {
+		e.mu.Unlock()
+		return fmt.Errorf("alerting engine already started")
+	}
+	e.ctx, e.cancel = context.WithCancel(ctx)
+	e.mu.Unlock()
+
+	if e.usesLocations() {
+		e.refreshLocations()
+		e.wg.Add(1)
+		go e.locationWorker()
+	}
+
+	e.wg.Add(1)
+	go e.historyWorker()
+
+	for _, worker := range e.notifiers {
+		e.wg.Add(1)
+		go worker.run(e.ctx, &e.wg)
+	}
+
+	e.logger.Infof("Alerting engine started with %d rules and %d notifiers", len(e.rules), len(e.notifiers))
+	return nil
+}
+
+// Stop persists outstanding transitions and stops background workers
+func (e *Engine) Stop(ctx context.Context) error {
+	e.mu.Lock()
+	if e.cancel == nil {
+		e.mu.Unlock()
+		return fmt.Errorf("alerting engine not started")
+	}
+	e.cancel()
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		e.logger.Infof("Alerting engine stopped")
+		return nil
+	case <-ctx.Done():
+		e.logger.Warnf("Alerting engine stop timed out: %v", ctx.Err())
+		return ctx.Err()
+	}
+}
+
+// Evaluate applies every rule to the samples and records state transitions
+func (e *Engine) Evaluate(samples []models.TelemetryData) {
+	if len(e.rules) == 0 || len(samples) == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := range samples {
+		sample := &samples[i]
+		timestamp := sample.Timestamp
+		if timestamp.IsZero() {
+			timestamp = now
+		}
+		location := e.locations[sample.SwitchID]
+
+		for _, rule := range e.rules {
+			if !rule.matches(sample.SwitchID, location) {
+				continue
+			}
+
+			value, err := sample.GetMetricFloat(rule.Metric)
+			if err != nil {
+				continue
+			}
+
+			e.evaluateRule(rule, sample.SwitchID, value, timestamp)
+		}
+	}
+}
+
+// evaluateRule advances the state of one rule and switch pair. Callers must hold e.mu.
+func (e *Engine) evaluateRule(rule Rule, switchID string, value float64, timestamp time.Time) {
+	key := alertKey{rule: rule.Name, switchID: switchID}
+	alert, exists := e.alerts[key]
+
+	if !rule.Comparator.Compare(value, rule.Threshold) {
+		if !exists {
+			return
+		}
+		delete(e.alerts, key)
+		if alert.State == models.AlertStateFiring {
+			alert.State = models.AlertStateResolved
+			alert.Value = value
+			alert.LastSeen = timestamp
+			metrics.AlertsFiring.WithLabelValues(rule.Name, rule.Severity).Dec()
+			e.emit(alert, timestamp)
+		}
+		return
+	}
+
+	if !exists {
+		alert = &models.Alert{
+			RuleName:   rule.Name,
+			SwitchID:   switchID,
+			Metric:     string(rule.Metric),
+			Comparator: string(rule.Comparator),
+			Threshold:  rule.Threshold,
+			Severity:   rule.Severity,
+			State:      models.AlertStatePending,
+			ActiveAt:   timestamp,
+		}
+		e.alerts[key] = alert
+	}
+	alert.Value = value
+	alert.LastSeen = timestamp
+
+	if alert.State == models.AlertStatePending && timestamp.Sub(alert.ActiveAt) >= rule.For {
+		firedAt := timestamp
+		alert.State = models.AlertStateFiring
+		alert.FiredAt = &firedAt
+		metrics.AlertsFiring.WithLabelValues(rule.Name, rule.Severity).Inc()
+		e.emit(alert, timestamp)
+	}
+}
+
+// emit queues a transition for persistence and notification without blocking ingestion. Callers must hold e.mu.
+func (e *Engine) emit(alert *models.Alert, timestamp time.Time) {
+	metrics.AlertTransitionsTotal.WithLabelValues(alert.RuleName, string(alert.State)).Inc()
+	e.logger.Infof("Alert %s %s for switch %s: %s=%.2f (%s %.2f)",
+		alert.RuleName, alert.State, alert.SwitchID, alert.Metric, alert.Value, alert.Comparator, alert.Threshold)
+
+	event := models.AlertEvent{
+		RuleName:   alert.RuleName,
+		SwitchID:   alert.SwitchID,
+		Metric:     alert.Metric,
+		Comparator: alert.Comparator,
+		Threshold:  alert.Threshold,
+		Severity:   alert.Severity,
+		State:      alert.State,
+		Value:      alert.Value,
+		ActiveAt:   alert.ActiveAt,
+		Timestamp:  timestamp,
+	}
+
+	for _, worker := range e.notifiers {
+		worker.enqueue(event)
+	}
+
+	select {
+	case e.events <- event:
+	default:
+		metrics.ErrorsTotal.WithLabelValues("alerting", "event_queue_full").Inc()
+		e.logger.Warnf("Alert event queue is full, dropping %s event for rule %s on switch %s",
+			event.State, event.RuleName, event.SwitchID)
+	}
+}
+
+// ActiveAlerts returns pending and firing alerts, optionally filtered by switch and state
+func (e *Engine) ActiveAlerts(switchID string, state models.AlertState) []models.Alert {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	alerts := make([]models.Alert, 0, len(e.alerts))
+	for _, alert := range e.alerts {
+		if switchID != "" && alert.SwitchID != switchID {
+			continue
+		}
+		if state != "" && alert.State != state {
+			continue
+		}
+		alerts = append(alerts, *alert)
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].ActiveAt.Equal(alerts[j].ActiveAt) {
+			if alerts[i].RuleName == alerts[j].RuleName {
+				return alerts[i].SwitchID < alerts[j].SwitchID
+			}
+			return alerts[i].RuleName < alerts[j].RuleName
+		}
+		return alerts[i].ActiveAt.Before(alerts[j].ActiveAt)
+	})
+	return alerts
+}
+
+// History returns persisted alert transitions
+func (e *Engine) History(ctx context.Context, query models.AlertHistoryQuery) ([]models.AlertEvent, error) {
+	if e.history == nil {
+		return nil, fmt.Errorf("alert history is not persisted")
+	}
+	return e.history.GetAlertHistory(ctx, query)
+}
+
+// historyWorker persists queued transitions in batches
+func (e *Engine) historyWorker() {
+	defer e.wg.Done()
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			// Persist whatever is still queued before shutdown
+			flushCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			e.persist(flushCtx, e.drainEvents(nil))
+			return
+		case event := <-e.events:
+			e.persist(e.ctx, e.drainEvents([]models.AlertEvent{event}))
+		}
+	}
+}
+
+// drainEvents appends all immediately available events to batch
+func (e *Engine) drainEvents(batch []models.AlertEvent) []models.AlertEvent {
+	for {
+		select {
+		case event := <-e.events:
+			batch = append(batch, event)
+		default:
+			return batch
+		}
+	}
+}
+
+func (e *Engine) persist(ctx context.Context, events []models.AlertEvent) {
+	if e.history == nil || len(events) == 0 {
+		return
+	}
+
+	if err := e.history.RecordAlertEvents(ctx, events); err != nil {
+		metrics.ErrorsTotal.WithLabelValues("alerting", "history_write_error").Inc()
+		e.logger.Errorf("Failed to persist %d alert events: %v", len(events), err)
+	}
+}
+
+// locationWorker periodically reloads switch locations
+func (e *Engine) locationWorker() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(locationRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+			e.refreshLocations()
+		}
+	}
+}
+
+func (e *Engine) refreshLocations() {
+	if e.switches == nil {
+		return
+	}
+
+	switches, err := e.switches.ListSwitches(e.ctx)
+	if err != nil {
+		e.logger.Warnf("Failed to load switch locations for alert rules: %v", err)
+		return
+	}
+
+	locations := make(map[string]string, len(switches))
+	for _, sw := range switches {
+		locations[sw.ID] = sw.Location
+	}
+
+	e.mu.Lock()
+	e.locations = locations
+	e.mu.Unlock()
+}
+
+func (e *Engine) usesLocations() bool {
+	for _, rule := range e.rules {
+		if rule.Location != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package alerting
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+type fakeHistoryStore struct {
+	mu     sync.Mutex
+	events []models.AlertEvent
+}
+
+func (f *fakeHistoryStore) RecordAlertEvents(ctx context.Context, events []models.AlertEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, events...)
+	return nil
+}
+
+func (f *fakeHistoryStore) GetAlertHistory(ctx context.Context, query models.AlertHistoryQuery) ([]models.AlertEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]models.AlertEvent(nil), f.events...), nil
+}
+
+func temperatureSample(switchID string, value float64, timestamp time.Time) models.TelemetryData {
+	return models.TelemetryData{SwitchID: switchID, TemperatureC: value, Timestamp: timestamp}
+}
+
+func TestEngine_FiresAfterDurationAndResolves(t *testing.T) {
+	history := &fakeHistoryStore{}
+	engine, err := NewEngine([]Rule{{
+		Name:       "hot",
+		Metric:     models.MetricTemperature,
+		Comparator: "gt",
+		Threshold:  55,
+		For:        30 * time.Second,
+	}}, history, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, engine.Start(context.Background()))
+
+	base := time.Now()
+	engine.Evaluate([]models.TelemetryData{temperatureSample("switch-001", 60, base)})
+	alerts := engine.ActiveAlerts("", "")
+	require.Len(t, alerts, 1)
+	assert.Equal(t, models.AlertStatePending, alerts[0].State)
+	assert.Equal(t, DefaultSeverity, alerts[0].Severity)
+
+	engine.Evaluate([]models.TelemetryData{temperatureSample("switch-001", 61, base.Add(30*time.Second))})
+	alerts = engine.ActiveAlerts("", models.AlertStateFiring)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, 61.0, alerts[0].Value)
+	assert.NotNil(t, alerts[0].FiredAt)
+
+	engine.Evaluate([]models.TelemetryData{temperatureSample("switch-001", 50, base.Add(40*time.Second))})
+	assert.Empty(t, engine.ActiveAlerts("", ""))
+
+	require.NoError(t, engine.Stop(context.Background()))
+	events, err := engine.History(context.Background(), models.AlertHistoryQuery{})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.AlertStateFiring, events[0].State)
+	assert.Equal(t, models.AlertStateResolved, events[1].State)
+	assert.Equal(t, ">", events[1].Comparator)
+}
+
+func TestEngine_PendingClearsWithoutEvent(t *testing.T) {
+	history := &fakeHistoryStore{}
+	engine, err := NewEngine([]Rule{{
+		Name:       "hot",
+		Metric:     models.MetricTemperature,
+		Comparator: ">",
+		Threshold:  55,
+		For:        time.Minute,
+	}}, history, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, engine.Start(context.Background()))
+
+	base := time.Now()
+	engine.Evaluate([]models.TelemetryData{temperatureSample("switch-001", 60, base)})
+	engine.Evaluate([]models.TelemetryData{temperatureSample("switch-001", 40, base.Add(10*time.Second))})
+	assert.Empty(t, engine.ActiveAlerts("", ""))
+
+	require.NoError(t, engine.Stop(context.Background()))
+	assert.Empty(t, history.events)
+}
+
+func TestEngine_SwitchSelector(t *testing.T) {
+	engine, err := NewEngine([]Rule{{
+		Name:       "errors",
+		Metric:     models.MetricPacketErrors,
+		Comparator: ">=",
+		Threshold:  10,
+		SwitchIDs:  []string{"switch-002"},
+	}}, nil, nil, nil)
+	require.NoError(t, err)
+
+	engine.Evaluate([]models.TelemetryData{
+		{SwitchID: "switch-001", PacketErrors: 50},
+		{SwitchID: "switch-002", PacketErrors: 10},
+	})
+
+	alerts := engine.ActiveAlerts("", models.AlertStateFiring)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "switch-002", alerts[0].SwitchID)
+	assert.Empty(t, engine.ActiveAlerts("switch-001", ""))
+}
+
+func TestNewEngine_InvalidRules(t *testing.T) {
+	_, err := NewEngine([]Rule{{Name: "bad", Metric: "unknown", Comparator: ">"}}, nil, nil, nil)
+	assert.Error(t, err)
+
+	_, err = NewEngine([]Rule{{Name: "bad", Metric: models.MetricTemperature, Comparator: "~"}}, nil, nil, nil)
+	assert.Error(t, err)
+
+	rule := Rule{Name: "dup", Metric: models.MetricTemperature, Comparator: ">"}
+	_, err = NewEngine([]Rule{rule, rule}, nil, nil, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,110 @@
+package alerting
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/monitoring/metrics"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// notificationQueueSize bounds the number of events waiting for each notifier
+const notificationQueueSize = 1000
+
+// Notifier delivers alert transitions to an external sink
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, event models.AlertEvent) error
+}
+
+// LogNotifier writes alert transitions to the service log
+type LogNotifier struct {
+	name   string
+	logger log.Logger
+}
+
+// NewLogNotifier creates a notifier that logs every transition
+func NewLogNotifier(name string, logger log.Logger) *LogNotifier {
+	if logger == nil {
+		logger = log.DefaultLogger
+	}
+	return &LogNotifier{name: name, logger: logger}
+}
+
+// Name returns the configured notifier name
+func (n *LogNotifier) Name() string {
+	return n.name
+}
+
+// Notify logs the transition at warning level when firing and info level when resolved
+func (n *LogNotifier) Notify(ctx context.Context, event models.AlertEvent) error {
+	if event.State == models.AlertStateFiring {
+		n.logger.Warnf("[ALERT %s] %s on switch %s: %s=%.2f (%s %.2f)",
+			event.Severity, event.RuleName, event.SwitchID, event.Metric, event.Value, event.Comparator, event.Threshold)
+	} else {
+		n.logger.Infof("[RESOLVED %s] %s on switch %s: %s=%.2f",
+			event.Severity, event.RuleName, event.SwitchID, event.Metric, event.Value)
+	}
+	return nil
+}
+
+// notifierWorker delivers events to one notifier so a slow sink never delays the others
+type notifierWorker struct {
+	notifier Notifier
+	queue    chan models.AlertEvent
+	logger   log.Logger
+}
+
+func newNotifierWorker(notifier Notifier, logger log.Logger) *notifierWorker {
+	return &notifierWorker{
+		notifier: notifier,
+		queue:    make(chan models.AlertEvent, notificationQueueSize),
+		logger:   logger,
+	}
+}
+
+// enqueue queues an event without blocking
+func (w *notifierWorker) enqueue(event models.AlertEvent) {
+	select {
+	case w.queue <- event:
+	default:
+		metrics.AlertNotificationsTotal.WithLabelValues(w.notifier.Name(), "dropped").Inc()
+		w.logger.Warnf("Notification queue for %s is full, dropping %s event for rule %s on switch %s",
+			w.notifier.Name(), event.State, event.RuleName, event.SwitchID)
+	}
+}
+
+// run delivers queued events until ctx is cancelled, then drains the queue within a short grace period
+func (w *notifierWorker) run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			drainCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			for {
+				select {
+				case event := <-w.queue:
+					w.deliver(drainCtx, event)
+				default:
+					return
+				}
+			}
+		case event := <-w.queue:
+			w.deliver(ctx, event)
+		}
+	}
+}
+
+func (w *notifierWorker) deliver(ctx context.Context, event models.AlertEvent) {
+	if err := w.notifier.Notify(ctx, event); err != nil {
+		metrics.AlertNotificationsTotal.WithLabelValues(w.notifier.Name(), "failed").Inc()
+		w.logger.Errorf("Failed to deliver %s alert %s for switch %s via %s: %v",
+			event.State, event.RuleName, event.SwitchID, w.notifier.Name(), err)
+		return
+	}
+	metrics.AlertNotificationsTotal.WithLabelValues(w.notifier.Name(), "delivered").Inc()
+}
@@ -0,0 +1,110 @@
+package alerting
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// Comparator compares a metric value against a rule threshold
+type Comparator string
+
+const (
+	ComparatorGreater      Comparator = ">"
+	ComparatorGreaterEqual Comparator = ">="
+	ComparatorLess         Comparator = "<"
+	ComparatorLessEqual    Comparator = "<="
+	ComparatorEqual        Comparator = "=="
+	ComparatorNotEqual     Comparator = "!="
+)
+
+// ParseComparator accepts symbolic (">") or named ("gt") comparators
+func ParseComparator(s string) (Comparator, error) {
+	switch s {
+	case ">", "gt":
+		return ComparatorGreater, nil
+	case ">=", "gte":
+		return ComparatorGreaterEqual, nil
+	case "<", "lt":
+		return ComparatorLess, nil
+	case "<=", "lte":
+		return ComparatorLessEqual, nil
+	case "==", "eq":
+		return ComparatorEqual, nil
+	case "!=", "ne":
+		return ComparatorNotEqual, nil
+	default:
+		return "", fmt.Errorf("unknown comparator: %s (expected >, >=, <, <=, == or !=)", s)
+	}
+}
+
+// Compare reports whether value satisfies the comparator against threshold
+func (c Comparator) Compare(value, threshold float64) bool {
+	switch c {
+	case ComparatorGreater:
+		return value > threshold
+	case ComparatorGreaterEqual:
+		return value >= threshold
+	case ComparatorLess:
+		return value < threshold
+	case ComparatorLessEqual:
+		return value <= threshold
+	case ComparatorEqual:
+		return value == threshold
+	case ComparatorNotEqual:
+		return value != threshold
+	default:
+		return false
+	}
+}
+
+// Rule describes a threshold condition on one metric.
+// A rule applies to every switch unless SwitchIDs or Location narrow it down.
+type Rule struct {
+	Name       string
+	Metric     models.MetricType
+	Comparator Comparator
+	Threshold  float64
+	For        time.Duration // How long the condition must hold before firing
+	Severity   string
+	SwitchIDs  []string // Restrict the rule to these switches
+	Location   string   // Restrict the rule to switches at this location
+}
+
+// Validate checks that the rule is complete and refers to a known metric
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("alert rule name is required")
+	}
+	if !r.Metric.IsValid() {
+		return fmt.Errorf("alert rule %s: invalid metric type: %s", r.Name, r.Metric)
+	}
+	if _, err := ParseComparator(string(r.Comparator)); err != nil {
+		return fmt.Errorf("alert rule %s: %w", r.Name, err)
+	}
+	if r.For < 0 {
+		return fmt.Errorf("alert rule %s: 'for' must not be negative", r.Name)
+	}
+	return nil
+}
+
+// matches reports whether the rule's selector includes the switch
+func (r Rule) matches(switchID, location string) bool {
+	if len(r.SwitchIDs) > 0 {
+		found := false
+		for _, id := range r.SwitchIDs {
+			if id == switchID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.Location != "" && r.Location != location {
+		return false
+	}
+	return true
+}
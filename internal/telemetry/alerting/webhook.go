@@ -0,0 +1,234 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/monitoring/metrics"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of the request body, prefixed with "sha256="
+	SignatureHeader = "X-UFM-Signature"
+	// EventHeader carries the alert state of the delivered event
+	EventHeader = "X-UFM-Event"
+
+	defaultWebhookTimeout    = 5 * time.Second
+	defaultWebhookMaxRetries = 3
+	defaultWebhookBackoff    = time.Second
+)
+
+// WebhookConfig holds configuration for a webhook notifier
+type WebhookConfig struct {
+	Name           string
+	URL            string
+	Secret         string            // HMAC key, signing is skipped when empty
+	Headers        map[string]string // Extra request headers
+	Timeout        time.Duration     // Per-attempt HTTP timeout
+	MaxRetries     int               // Retries after the first attempt, zero uses the default, negative disables
+	Backoff        time.Duration     // Initial retry delay, doubled on every retry
+	DeadLetterFile string            // Undeliverable events are appended here as JSON lines
+}
+
+// WebhookPayload is the JSON body posted for every alert transition
+type WebhookPayload struct {
+	Source string            `json:"source"`
+	Event  models.AlertEvent `json:"event"`
+	SentAt time.Time         `json:"sent_at"`
+}
+
+// WebhookNotifier posts alert transitions as signed JSON to an HTTP endpoint
+type WebhookNotifier struct {
+	config     WebhookConfig
+	httpClient *http.Client
+	logger     log.Logger
+
+	deadLetterMu sync.Mutex
+}
+
+// NewWebhookNotifier creates a webhook notifier, filling in defaults for unset limits
+func NewWebhookNotifier(config WebhookConfig, logger log.Logger) (*WebhookNotifier, error) {
+	if logger == nil {
+		logger = log.DefaultLogger
+	}
+
+	if config.Name == "" {
+		return nil, fmt.Errorf("webhook notifier name is required")
+	}
+	// The name is part of the dead-letter file name, so it must not reach outside its directory
+	if strings.ContainsAny(config.Name, `/\`) || strings.Contains(config.Name, "..") {
+		return nil, fmt.Errorf("webhook notifier %q: name must not contain path separators or '..'", config.Name)
+	}
+	parsed, err := url.Parse(config.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("webhook notifier %s: invalid url: %q", config.Name, config.URL)
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultWebhookTimeout
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	} else if config.MaxRetries == 0 {
+		config.MaxRetries = defaultWebhookMaxRetries
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = defaultWebhookBackoff
+	}
+
+	return &WebhookNotifier{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+		logger:     logger,
+	}, nil
+}
+
+// Name returns the configured notifier name
+func (n *WebhookNotifier) Name() string {
+	return n.config.Name
+}
+
+// Notify posts the event, retrying transient failures with exponential backoff.
+// Events that cannot be delivered are written to the dead-letter file.
+func (n *WebhookNotifier) Notify(ctx context.Context, event models.AlertEvent) error {
+	body, err := json.Marshal(WebhookPayload{
+		Source: "ufm",
+		Event:  event,
+		SentAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	backoff := n.config.Backoff
+	for attempt := 0; ; attempt++ {
+		var retryable bool
+		retryable, err = n.post(ctx, event, body)
+		if err == nil {
+			return nil
+		}
+
+		if !retryable || attempt >= n.config.MaxRetries {
+			break
+		}
+
+		metrics.AlertNotificationsTotal.WithLabelValues(n.config.Name, "retried").Inc()
+		n.logger.Warnf("Webhook %s delivery failed (attempt %d/%d), retrying in %v: %v",
+			n.config.Name, attempt+1, n.config.MaxRetries+1, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-time.After(backoff):
+			backoff *= 2
+			continue
+		}
+		break
+	}
+
+	if dlErr := n.writeDeadLetter(event, err); dlErr != nil {
+		n.logger.Errorf("Failed to write dead letter for webhook %s: %v", n.config.Name, dlErr)
+	}
+	return fmt.Errorf("webhook %s: %w", n.config.Name, err)
+}
+
+// post performs a single delivery attempt and reports whether a failure is worth retrying
+func (n *WebhookNotifier) post(ctx context.Context, event models.AlertEvent, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "UFM-Alerting/1.0")
+	req.Header.Set(EventHeader, string(event.State))
+	for key, value := range n.config.Headers {
+		req.Header.Set(key, value)
+	}
+	if n.config.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(n.config.Secret, body))
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retryable, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+}
+
+// deadLetter is one line of the dead-letter file
+type deadLetter struct {
+	Notifier string            `json:"notifier"`
+	URL      string            `json:"url"`
+	Error    string            `json:"error"`
+	FailedAt time.Time         `json:"failed_at"`
+	Event    models.AlertEvent `json:"event"`
+}
+
+// writeDeadLetter appends an undeliverable event to the dead-letter file as a JSON line
+func (n *WebhookNotifier) writeDeadLetter(event models.AlertEvent, cause error) error {
+	if n.config.DeadLetterFile == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(deadLetter{
+		Notifier: n.config.Name,
+		URL:      n.config.URL,
+		Error:    cause.Error(),
+		FailedAt: time.Now().UTC(),
+		Event:    event,
+	})
+	if err != nil {
+		return err
+	}
+
+	n.deadLetterMu.Lock()
+	defer n.deadLetterMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(n.config.DeadLetterFile), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(n.config.DeadLetterFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	metrics.AlertNotificationsTotal.WithLabelValues(n.config.Name, "dead_lettered").Inc()
+	return nil
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body keyed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
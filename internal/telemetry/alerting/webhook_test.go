@@ -0,0 +1,160 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+func firingEvent() models.AlertEvent {
+	return models.AlertEvent{
+		RuleName:   "hot",
+		SwitchID:   "switch-001",
+		Metric:     string(models.MetricTemperature),
+		Comparator: ">",
+		Threshold:  55,
+		Severity:   "critical",
+		State:      models.AlertStateFiring,
+		Value:      61,
+		Timestamp:  time.Now(),
+	}
+}
+
+func TestWebhookNotifier_SignsPayload(t *testing.T) {
+	var received WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "sha256="+Sign("s3cret", body), r.Header.Get(SignatureHeader))
+		assert.Equal(t, "firing", r.Header.Get(EventHeader))
+		assert.Equal(t, "team-a", r.Header.Get("X-Team"))
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(WebhookConfig{
+		Name:    "ops",
+		URL:     server.URL,
+		Secret:  "s3cret",
+		Headers: map[string]string{"X-Team": "team-a"},
+	}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, notifier.Notify(context.Background(), firingEvent()))
+	assert.Equal(t, "ufm", received.Source)
+	assert.Equal(t, "hot", received.Event.RuleName)
+}
+
+func TestWebhookNotifier_RetriesTransientFailures(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(WebhookConfig{
+		Name:       "ops",
+		URL:        server.URL,
+		MaxRetries: 3,
+		Backoff:    time.Millisecond,
+	}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, notifier.Notify(context.Background(), firingEvent()))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestWebhookNotifier_DeadLetter(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	deadLetterFile := filepath.Join(t.TempDir(), "alerts", "ops-dead-letter.jsonl")
+	notifier, err := NewWebhookNotifier(WebhookConfig{
+		Name:           "ops",
+		URL:            server.URL,
+		MaxRetries:     3,
+		Backoff:        time.Millisecond,
+		DeadLetterFile: deadLetterFile,
+	}, nil)
+	require.NoError(t, err)
+
+	assert.Error(t, notifier.Notify(context.Background(), firingEvent()))
+	// Client errors are not retried
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+
+	data, err := os.ReadFile(deadLetterFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+
+	var letter deadLetter
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &letter))
+	assert.Equal(t, "ops", letter.Notifier)
+	assert.Equal(t, "switch-001", letter.Event.SwitchID)
+	assert.Contains(t, letter.Error, "400")
+}
+
+func TestNewWebhookNotifier_InvalidURL(t *testing.T) {
+	_, err := NewWebhookNotifier(WebhookConfig{Name: "ops", URL: "ftp://example.com"}, nil)
+	assert.Error(t, err)
+}
+
+func TestNewWebhookNotifier_RejectsPathLikeNames(t *testing.T) {
+	for _, name := range []string{"../ops", "ops/hooks", `ops\hooks`, ".."} {
+		_, err := NewWebhookNotifier(WebhookConfig{Name: name, URL: "https://hooks.example.com"}, nil)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestEngine_DeliversToNotifiers(t *testing.T) {
+	delivered := make(chan WebhookPayload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		delivered <- payload
+	}))
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(WebhookConfig{Name: "ops", URL: server.URL}, nil)
+	require.NoError(t, err)
+
+	engine, err := NewEngine([]Rule{{
+		Name:       "hot",
+		Metric:     models.MetricTemperature,
+		Comparator: ">",
+		Threshold:  55,
+	}}, nil, nil, nil, WithNotifiers(notifier))
+	require.NoError(t, err)
+	require.NoError(t, engine.Start(context.Background()))
+	defer engine.Stop(context.Background())
+
+	engine.Evaluate([]models.TelemetryData{temperatureSample("switch-001", 70, time.Now())})
+
+	select {
+	case payload := <-delivered:
+		assert.Equal(t, models.AlertStateFiring, payload.Event.State)
+		assert.Equal(t, 70.0, payload.Event.Value)
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
@@ -0,0 +1,203 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/monitoring/metrics"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// Config holds configuration for the anomaly detector
+type Config struct {
+	Alpha         float64 // EWMA smoothing factor in (0, 1], higher adapts faster
+	ZThreshold    float64 // Absolute z-score at or above which a sample is anomalous
+	WarmupSamples int     // Samples required per switch and metric before flagging
+	MaxAnomalies  int     // Number of recent anomalies kept for queries
+}
+
+// DefaultConfig returns sensible defaults
+func DefaultConfig() Config {
+	return Config{
+		Alpha:         0.1,
+		ZThreshold:    3.0,
+		WarmupSamples: 30,
+		MaxAnomalies:  1000,
+	}
+}
+
+// Validate checks that the configuration is usable
+func (c Config) Validate() error {
+	if c.Alpha <= 0 || c.Alpha > 1 {
+		return fmt.Errorf("anomaly alpha must be in (0, 1], got %v", c.Alpha)
+	}
+	if c.ZThreshold <= 0 {
+		return fmt.Errorf("anomaly z_threshold must be positive, got %v", c.ZThreshold)
+	}
+	if c.WarmupSamples < 0 {
+		return fmt.Errorf("anomaly warmup_samples must not be negative")
+	}
+	if c.MaxAnomalies <= 0 {
+		return fmt.Errorf("anomaly max_anomalies must be positive")
+	}
+	return nil
+}
+
+type baselineKey struct {
+	switchID string
+	metric   models.MetricType
+}
+
+// baseline is an exponentially weighted mean and variance of one metric of one switch
+type baseline struct {
+	mean     float64
+	variance float64
+	samples  int
+}
+
+// update folds x into the baseline using the incremental EWMA/EWMVar recurrence
+func (b *baseline) update(x, alpha float64) {
+	if b.samples == 0 {
+		b.mean = x
+		b.variance = 0
+		b.samples = 1
+		return
+	}
+	diff := x - b.mean
+	increment := alpha * diff
+	b.mean += increment
+	b.variance = (1 - alpha) * (b.variance + diff*increment)
+	b.samples++
+}
+
+// Detector flags samples that deviate from each switch's rolling per-metric baseline
+type Detector struct {
+	config Config
+	logger log.Logger
+
+	mu        sync.RWMutex
+	baselines map[baselineKey]*baseline
+	recent    []models.Anomaly // Ring buffer of the most recent anomalies
+	next      int
+	full      bool
+}
+
+// NewDetector creates an anomaly detector
+func NewDetector(config Config, logger log.Logger) (*Detector, error) {
+	if logger == nil {
+		logger = log.DefaultLogger
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Detector{
+		config:    config,
+		logger:    logger,
+		baselines: make(map[baselineKey]*baseline),
+		recent:    make([]models.Anomaly, config.MaxAnomalies),
+	}, nil
+}
+
+// Observe scores every metric of the samples against its baseline, records
+// outliers and then folds the samples into the baselines
+func (d *Detector) Observe(samples []models.TelemetryData) {
+	if len(samples) == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := range samples {
+		sample := &samples[i]
+		timestamp := sample.Timestamp
+		if timestamp.IsZero() {
+			timestamp = now
+		}
+
+		for _, metricType := range models.AllMetricTypes {
+			value, err := sample.GetMetricFloat(metricType)
+			if err != nil {
+				continue
+			}
+
+			key := baselineKey{switchID: sample.SwitchID, metric: metricType}
+			b, exists := d.baselines[key]
+			if !exists {
+				b = &baseline{}
+				d.baselines[key] = b
+			}
+
+			if b.samples >= d.config.WarmupSamples && b.variance > 0 {
+				stdDev := math.Sqrt(b.variance)
+				z := (value - b.mean) / stdDev
+				if math.Abs(z) >= d.config.ZThreshold {
+					d.record(models.Anomaly{
+						SwitchID:  sample.SwitchID,
+						Metric:    string(metricType),
+						Value:     value,
+						Mean:      b.mean,
+						StdDev:    stdDev,
+						ZScore:    z,
+						Timestamp: timestamp,
+					})
+				}
+			}
+
+			b.update(value, d.config.Alpha)
+		}
+	}
+}
+
+// record stores an anomaly in the ring buffer. Callers must hold d.mu.
+func (d *Detector) record(anomaly models.Anomaly) {
+	direction := "high"
+	if anomaly.ZScore < 0 {
+		direction = "low"
+	}
+	metrics.AnomaliesDetectedTotal.WithLabelValues(anomaly.Metric, direction).Inc()
+	d.logger.Debugf("Anomaly on switch %s: %s=%.2f (mean %.2f, z=%.2f)",
+		anomaly.SwitchID, anomaly.Metric, anomaly.Value, anomaly.Mean, anomaly.ZScore)
+
+	d.recent[d.next] = anomaly
+	d.next = (d.next + 1) % len(d.recent)
+	if d.next == 0 {
+		d.full = true
+	}
+}
+
+// Anomalies returns recently detected anomalies matching the query, newest first
+func (d *Detector) Anomalies(query models.AnomalyQuery) []models.Anomaly {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	count := d.next
+	if d.full {
+		count = len(d.recent)
+	}
+
+	result := []models.Anomaly{}
+	for i := 1; i <= count; i++ {
+		anomaly := d.recent[(d.next-i+len(d.recent))%len(d.recent)]
+		if query.SwitchID != "" && anomaly.SwitchID != query.SwitchID {
+			continue
+		}
+		if query.Metric != "" && anomaly.Metric != string(query.Metric) {
+			continue
+		}
+		if !query.Since.IsZero() && anomaly.Timestamp.Before(query.Since) {
+			continue
+		}
+		result = append(result, anomaly)
+		if query.Limit > 0 && len(result) >= query.Limit {
+			break
+		}
+	}
+	return result
+}
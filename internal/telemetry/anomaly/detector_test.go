@@ -0,0 +1,120 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+func temperatureSample(switchID string, value float64, timestamp time.Time) models.TelemetryData {
+	return models.TelemetryData{
+		SwitchID:      switchID,
+		Timestamp:     timestamp,
+		TemperatureC:  value,
+		BandwidthMbps: 1000,
+		LatencyMs:     1,
+	}
+}
+
+// warmUp feeds a steady oscillating baseline around mean
+func warmUp(detector *Detector, switchID string, mean float64, samples int, start time.Time) {
+	for i := 0; i < samples; i++ {
+		value := mean + 1
+		if i%2 == 0 {
+			value = mean - 1
+		}
+		detector.Observe([]models.TelemetryData{temperatureSample(switchID, value, start.Add(time.Duration(i)*time.Second))})
+	}
+}
+
+func TestDetector_FlagsOutliers(t *testing.T) {
+	detector, err := NewDetector(DefaultConfig(), nil)
+	require.NoError(t, err)
+
+	start := time.Now().Add(-time.Hour)
+	warmUp(detector, "switch-001", 40, 50, start)
+	assert.Empty(t, detector.Anomalies(models.AnomalyQuery{}))
+
+	detector.Observe([]models.TelemetryData{temperatureSample("switch-001", 70, start.Add(time.Minute))})
+
+	anomalies := detector.Anomalies(models.AnomalyQuery{})
+	require.Len(t, anomalies, 1)
+	assert.Equal(t, "switch-001", anomalies[0].SwitchID)
+	assert.Equal(t, string(models.MetricTemperature), anomalies[0].Metric)
+	assert.Equal(t, 70.0, anomalies[0].Value)
+	assert.InDelta(t, 40, anomalies[0].Mean, 1)
+	assert.Greater(t, anomalies[0].ZScore, 3.0)
+}
+
+func TestDetector_BaselinesArePerSwitch(t *testing.T) {
+	detector, err := NewDetector(DefaultConfig(), nil)
+	require.NoError(t, err)
+
+	start := time.Now().Add(-time.Hour)
+	warmUp(detector, "switch-001", 40, 50, start)
+	warmUp(detector, "switch-002", 70, 50, start)
+
+	// 70 is normal for switch-002 but far outside switch-001's baseline
+	detector.Observe([]models.TelemetryData{
+		temperatureSample("switch-001", 41, start.Add(time.Minute)),
+		temperatureSample("switch-002", 70, start.Add(time.Minute)),
+	})
+	assert.Empty(t, detector.Anomalies(models.AnomalyQuery{}))
+
+	detector.Observe([]models.TelemetryData{temperatureSample("switch-001", 70, start.Add(2*time.Minute))})
+	anomalies := detector.Anomalies(models.AnomalyQuery{SwitchID: "switch-001"})
+	require.Len(t, anomalies, 1)
+	assert.Empty(t, detector.Anomalies(models.AnomalyQuery{SwitchID: "switch-002"}))
+}
+
+func TestDetector_RespectsWarmup(t *testing.T) {
+	config := DefaultConfig()
+	config.WarmupSamples = 100
+	detector, err := NewDetector(config, nil)
+	require.NoError(t, err)
+
+	start := time.Now()
+	warmUp(detector, "switch-001", 40, 50, start)
+	detector.Observe([]models.TelemetryData{temperatureSample("switch-001", 90, start.Add(time.Minute))})
+	assert.Empty(t, detector.Anomalies(models.AnomalyQuery{}))
+}
+
+func TestDetector_QueryFiltersAndRingBuffer(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxAnomalies = 3
+	config.Alpha = 0.01 // Keep the baseline stable across the spikes below
+	detector, err := NewDetector(config, nil)
+	require.NoError(t, err)
+
+	start := time.Now().Add(-time.Hour)
+	warmUp(detector, "switch-001", 40, 50, start)
+
+	for i := 0; i < 5; i++ {
+		detector.Observe([]models.TelemetryData{temperatureSample("switch-001", 80, start.Add(time.Duration(10+i)*time.Minute))})
+	}
+
+	anomalies := detector.Anomalies(models.AnomalyQuery{})
+	require.Len(t, anomalies, 3)
+	// Newest first
+	assert.Equal(t, start.Add(14*time.Minute), anomalies[0].Timestamp)
+	assert.Equal(t, start.Add(12*time.Minute), anomalies[2].Timestamp)
+
+	assert.Len(t, detector.Anomalies(models.AnomalyQuery{Limit: 2}), 2)
+	assert.Len(t, detector.Anomalies(models.AnomalyQuery{Since: start.Add(13 * time.Minute)}), 2)
+	assert.Empty(t, detector.Anomalies(models.AnomalyQuery{Metric: models.MetricLatency}))
+}
+
+func TestNewDetector_InvalidConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.Alpha = 0
+	_, err := NewDetector(config, nil)
+	assert.Error(t, err)
+
+	config = DefaultConfig()
+	config.ZThreshold = -1
+	_, err = NewDetector(config, nil)
+	assert.Error(t, err)
+}
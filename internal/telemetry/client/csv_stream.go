@@ -0,0 +1,200 @@
+package client
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ufm/internal/monitoring/metrics"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// DefaultChunkSize is the number of rows handed to IngestBatch at a time
+const DefaultChunkSize = 500
+
+// DefaultAcceptEncoding asks sources for compressed payloads, preferring zstd
+const DefaultAcceptEncoding = "zstd, gzip"
+
+// errDuplicateData stops a payload whose first row shows it was already ingested
+var errDuplicateData = errors.New("duplicate data")
+
+// parseResult summarizes one streamed CSV payload
+type parseResult struct {
+	rows          int    // rows ingested
+	skipped       int    // invalid rows
+	bytes         int64  // decoded CSV bytes
+	wireBytes     int64  // bytes received before decoding
+	encoding      string // Content-Encoding of the response
+	duration      time.Duration
+	dataTimestamp time.Time
+}
+
+// rowsPerSecond returns the throughput of the payload
+func (r parseResult) rowsPerSecond() float64 {
+	if r.duration <= 0 {
+		return 0
+	}
+	return float64(r.rows) / r.duration.Seconds()
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+// decodeBody decompresses a response body according to its Content-Encoding
+func (gc *GeneratorClient) decodeBody(body io.Reader, encoding string) (io.Reader, error) {
+	switch strings.ToLower(encoding) {
+	case "", "identity":
+		return body, nil
+	case "gzip":
+		reader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		return reader, nil
+	case "zstd":
+		if gc.zstdDecoder == nil {
+			decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+			}
+			gc.zstdDecoder = decoder
+		}
+		if err := gc.zstdDecoder.Reset(body); err != nil {
+			return nil, fmt.Errorf("invalid zstd body: %w", err)
+		}
+		return gc.zstdDecoder, nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// closeDecoders releases the decoders kept between polls
+func (gc *GeneratorClient) closeDecoders() {
+	if gc.zstdDecoder != nil {
+		gc.zstdDecoder.Close()
+		gc.zstdDecoder = nil
+	}
+}
+
+// csvSchema maps the payload's header row to telemetry fields. Payloads without
+// every column that is not optional are rejected rather than read by position.
+func (gc *GeneratorClient) csvSchema(header []string) (*models.CSVSchema, error) {
+	schema, err := models.NewCSVSchema(header, gc.columnAliases, gc.optional)
+	if errors.Is(err, models.ErrMissingCSVColumns) {
+		metrics.ErrorsTotal.WithLabelValues("ingestion", "missing_columns").Inc()
+		return nil, err
+	}
+	if err != nil {
+		metrics.ErrorsTotal.WithLabelValues("ingestion", "invalid_header").Inc()
+		return nil, err
+	}
+
+	// Report a changed layout once instead of on every poll
+	if joined := strings.Join(header, ","); joined != gc.lastHeader {
+		gc.lastHeader = joined
+		if len(schema.Unknown()) > 0 || len(schema.Missing()) > 0 {
+			gc.logger.Warnf("CSV columns from %s changed: ignoring unknown %v, missing optional %v read as zero",
+				gc.name, schema.Unknown(), schema.Missing())
+		}
+	}
+	return schema, nil
+}
+
+// ingestCSV parses body row by row and ingests it in chunks of chunkSize, so only
+// one chunk of a large payload is held in memory at a time. The first row must
+// be the header. Without a data timestamp header the first row's timestamp is
+// used for deduplication. If ingesting a chunk fails the earlier chunks stay
+// ingested.
+func (gc *GeneratorClient) ingestCSV(body io.Reader, generationID string, dataTimestamp time.Time) (result parseResult, err error) {
+	start := time.Now()
+	counter := &countingReader{reader: body}
+	result.dataTimestamp = dataTimestamp
+	defer func() {
+		result.bytes = counter.count
+		result.duration = time.Since(start)
+	}()
+
+	reader := csv.NewReader(counter)
+	reader.ReuseRecord = true
+	reader.FieldsPerRecord = -1 // the schema reports short rows
+
+	chunk := make([]models.TelemetryData, 0, gc.chunkSize)
+	seenSwitches := make(map[string]bool)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		gc.registerSwitchesFromData(chunk, seenSwitches)
+		if err := gc.service.IngestBatch(chunk); err != nil {
+			return err
+		}
+		result.rows += len(chunk)
+		// The service may keep the slice, so every chunk gets its own
+		chunk = make([]models.TelemetryData, 0, gc.chunkSize)
+		return nil
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return result, fmt.Errorf("CSV is empty")
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	schema, err := gc.csvSchema(header)
+	if err != nil {
+		return result, err
+	}
+
+	dataRows := 0
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to parse CSV: %w", err)
+		}
+		dataRows++
+
+		data, err := schema.Parse(record)
+		if err != nil {
+			result.skipped++
+			gc.logger.Warnf("Skipping CSV row %d from %s: %v", line, gc.name, err)
+			continue
+		}
+
+		if result.dataTimestamp.IsZero() {
+			result.dataTimestamp = data.Timestamp
+			if gc.isDuplicateData(generationID, result.dataTimestamp) {
+				return result, errDuplicateData
+			}
+		}
+
+		chunk = append(chunk, data)
+		if len(chunk) >= gc.chunkSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if dataRows == 0 {
+		return result, fmt.Errorf("CSV contains no data rows")
+	}
+	return result, flush()
+}
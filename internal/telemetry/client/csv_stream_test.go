@@ -0,0 +1,212 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// countersCSV builds a /counters payload with one row per switch
+func countersCSV(timestamp time.Time, switchIDs ...string) string {
+	var b strings.Builder
+	b.WriteString(strings.Join(models.CSVColumns, ",") + "\n")
+	for _, id := range switchIDs {
+		fmt.Fprintf(&b, "%s,%s,950,1.2,0,40,44\n", id, timestamp.Format(time.RFC3339Nano))
+	}
+	return b.String()
+}
+
+func newStreamClient(service *mockTelemetryService, chunkSize int) *GeneratorClient {
+	return newGeneratorClient(GeneratorClientConfig{
+		Name:         "test",
+		GeneratorURL: "http://localhost:9001",
+		ChunkSize:    chunkSize,
+	}, service, log.DefaultLogger)
+}
+
+func TestIngestCSV_IngestsInChunks(t *testing.T) {
+	mockService := &mockTelemetryService{}
+	mockService.On("RegisterSwitch", mock.Anything).Return(nil)
+	var chunks []int
+	mockService.On("IngestBatch", mock.Anything).Run(func(args mock.Arguments) {
+		chunks = append(chunks, len(args.Get(0).([]models.TelemetryData)))
+	}).Return(nil)
+
+	client := newStreamClient(mockService, 2)
+	now := time.Now().UTC()
+	payload := countersCSV(now, "switch-001", "switch-002", "switch-003", "switch-001", "switch-004") +
+		"switch-005,not-a-time,950,1.2,0,40,44\n"
+
+	result, err := client.ingestCSV(strings.NewReader(payload), "gen-1", time.Time{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{2, 2, 1}, chunks)
+	assert.Equal(t, 5, result.rows)
+	assert.Equal(t, 1, result.skipped)
+	assert.Equal(t, int64(len(payload)), result.bytes)
+	assert.True(t, result.dataTimestamp.Equal(now))
+	// Switches are registered once per payload, not once per chunk
+	mockService.AssertNumberOfCalls(t, "RegisterSwitch", 4)
+}
+
+func TestIngestCSV_DuplicateFirstRowStopsBeforeIngesting(t *testing.T) {
+	mockService := &mockTelemetryService{}
+	client := newStreamClient(mockService, 2)
+	now := time.Now().UTC()
+	client.lastDataTimestamp = now
+
+	_, err := client.ingestCSV(strings.NewReader(countersCSV(now, "switch-001", "switch-002")), "", time.Time{})
+	assert.ErrorIs(t, err, errDuplicateData)
+	mockService.AssertNotCalled(t, "IngestBatch", mock.Anything)
+}
+
+func TestIngestCSV_Errors(t *testing.T) {
+	now := time.Now().UTC()
+
+	t.Run("no data rows", func(t *testing.T) {
+		client := newStreamClient(&mockTelemetryService{}, 2)
+		_, err := client.ingestCSV(strings.NewReader(countersCSV(now)), "gen-1", time.Time{})
+		assert.ErrorContains(t, err, "no data rows")
+	})
+
+	t.Run("malformed CSV", func(t *testing.T) {
+		client := newStreamClient(&mockTelemetryService{}, 2)
+		_, err := client.ingestCSV(strings.NewReader("switch_id,\"timestamp\n"), "gen-1", time.Time{})
+		assert.ErrorContains(t, err, "failed to parse CSV")
+	})
+
+	t.Run("ingest failure keeps earlier chunks", func(t *testing.T) {
+		mockService := &mockTelemetryService{}
+		mockService.On("RegisterSwitch", mock.Anything).Return(nil)
+		mockService.On("IngestBatch", mock.Anything).Return(nil).Once()
+		mockService.On("IngestBatch", mock.Anything).Return(errors.New("store unavailable"))
+
+		client := newStreamClient(mockService, 2)
+		payload := countersCSV(now, "switch-001", "switch-002", "switch-003", "switch-004")
+		result, err := client.ingestCSV(strings.NewReader(payload), "gen-1", time.Time{})
+		assert.ErrorContains(t, err, "store unavailable")
+		assert.Equal(t, 2, result.rows)
+	})
+}
+
+func TestIngestCSV_MapsColumnsByHeader(t *testing.T) {
+	mockService := &mockTelemetryService{}
+	mockService.On("RegisterSwitch", mock.Anything).Return(nil)
+	var ingested []models.TelemetryData
+	mockService.On("IngestBatch", mock.Anything).Run(func(args mock.Arguments) {
+		ingested = append(ingested, args.Get(0).([]models.TelemetryData)...)
+	}).Return(nil)
+
+	client := newStreamClient(mockService, 10)
+	client.columnAliases = map[string]string{"node_guid": "switch_id", "temp": "temperature_c"}
+	client.optional = []string{"latency_ms", "packet_errors", "utilization_pct"}
+	payload := "temp,fw_version,timestamp,node_guid,bandwidth_mbps\n" +
+		"51.5,3.1,2025-08-02T12:00:00Z,switch-001,900\n"
+
+	result, err := client.ingestCSV(strings.NewReader(payload), "gen-1", time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 1, result.rows)
+	assert.Equal(t, "switch-001", ingested[0].SwitchID)
+	assert.Equal(t, 51.5, ingested[0].TemperatureC)
+	assert.Equal(t, 900.0, ingested[0].BandwidthMbps)
+	assert.Zero(t, ingested[0].LatencyMs)
+}
+
+func TestIngestCSV_RejectsMissingRequiredColumns(t *testing.T) {
+	mockService := &mockTelemetryService{}
+	client := newStreamClient(mockService, 10)
+	payload := "switch_id,bandwidth_mbps\nswitch-001,900\n"
+	_, err := client.ingestCSV(strings.NewReader(payload), "gen-1", time.Time{})
+	assert.ErrorIs(t, err, models.ErrMissingCSVColumns)
+	assert.ErrorContains(t, err, "timestamp")
+
+	// A metric column that is not optional is required rather than stored as zero
+	payload = "switch_id,timestamp,bandwidth_mbps,latency_ms,utilization_pct,temperature_c\n" +
+		"switch-001,2025-08-02T12:00:00Z,900,1.2,40,44\n"
+	_, err = client.ingestCSV(strings.NewReader(payload), "gen-1", time.Time{})
+	assert.ErrorIs(t, err, models.ErrMissingCSVColumns)
+	assert.ErrorContains(t, err, "packet_errors")
+	mockService.AssertNotCalled(t, "IngestBatch", mock.Anything)
+}
+
+func TestFetchAndProcessData_NegotiatesCompression(t *testing.T) {
+	switchIDs := make([]string, 200)
+	for i := range switchIDs {
+		switchIDs[i] = fmt.Sprintf("switch-%03d", i+1)
+	}
+	payload := []byte(countersCSV(time.Now().UTC(), switchIDs...))
+
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	_, err := writer.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	encoder, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	zstded := encoder.EncodeAll(payload, nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept := r.Header.Get("Accept-Encoding")
+		switch {
+		case strings.Contains(accept, "zstd"):
+			w.Header().Set("Content-Encoding", "zstd")
+			w.Write(zstded)
+		case strings.Contains(accept, "gzip"):
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipped.Bytes())
+		default:
+			w.Write(payload)
+		}
+	}))
+	defer server.Close()
+
+	for _, encoding := range []string{"zstd", "gzip", "identity"} {
+		t.Run(encoding, func(t *testing.T) {
+			mockService := &mockTelemetryService{}
+			mockService.On("RegisterSwitch", mock.Anything).Return(nil)
+			mockService.On("IngestBatch", mock.Anything).Return(nil)
+
+			client := newGeneratorClient(GeneratorClientConfig{
+				GeneratorURL:   server.URL,
+				Timeout:        time.Second,
+				AcceptEncoding: encoding,
+			}, mockService, log.DefaultLogger)
+			client.ctx = context.Background()
+			defer client.closeDecoders()
+
+			client.fetchAndProcessData()
+
+			stats := client.GetStats()
+			require.Equal(t, int64(1), stats["successful_polls"], stats["last_error"])
+			assert.Equal(t, 200, stats["last_parse_rows"])
+			assert.Equal(t, int64(len(payload)), stats["last_parse_bytes"])
+			if encoding == "identity" {
+				assert.Equal(t, "", stats["last_encoding"])
+				assert.Equal(t, int64(len(payload)), stats["last_wire_bytes"])
+			} else {
+				assert.Equal(t, encoding, stats["last_encoding"])
+				assert.Less(t, stats["last_wire_bytes"], int64(len(payload))/2)
+			}
+		})
+	}
+}
+
+func TestDecodeBody_UnsupportedEncoding(t *testing.T) {
+	client := newStreamClient(&mockTelemetryService{}, 10)
+	_, err := client.decodeBody(strings.NewReader("data"), "br")
+	assert.ErrorContains(t, err, "unsupported content encoding")
+}
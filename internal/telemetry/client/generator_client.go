@@ -4,16 +4,15 @@ package client
 
 import (
 	"context"
-	"encoding/csv"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/monitoring/metrics"
 	"github.com/ufm/internal/telemetry"
 	"github.com/ufm/internal/telemetry/models"
 )
@@ -27,7 +26,10 @@ type GeneratorClientInterface interface {
 
 // GeneratorClient handles HTTP polling of the telemetry generator service
 type GeneratorClient struct {
+	name           string
 	generatorURL   string
+	auth           SourceAuth
+	tags           map[string]string
 	httpClient     *http.Client
 	logger         log.Logger
 	service        telemetry.TelemetryService
@@ -36,6 +38,10 @@ type GeneratorClient struct {
 	maxRetries     int
 	startupDelay   time.Duration
 	readinessCheck bool
+	chunkSize      int
+	columnAliases  map[string]string
+	optional       []string
+	acceptEncoding string
 
 	// State management
 	ctx     context.Context
@@ -44,26 +50,65 @@ type GeneratorClient struct {
 	running bool
 	mu      sync.RWMutex
 
+	// Only touched by the polling worker
+	lastHeader  string        // header of the last payload
+	zstdDecoder *zstd.Decoder // reused across polls
+
 	// Deduplication tracking
 	lastGenerationID  string
 	lastDataTimestamp time.Time
+	lastETag          string // sent as If-None-Match so unchanged generations come back as 304
 
 	// Performance metrics
-	totalPolls      int64
-	successfulPolls int64
-	duplicateSkips  int64
-	errorCount      int64
-	lastPollTime    time.Time
+	totalPolls        int64
+	successfulPolls   int64
+	duplicateSkips    int64
+	notModified       int64
+	errorCount        int64
+	consecutiveErrors int64
+	lastError         string
+	lastPollTime      time.Time
+	lastSuccessTime   time.Time
+	rowsParsed        int64
+	lastParse         parseResult
 }
 
 // GeneratorClientConfig holds the configuration for the generator client
 type GeneratorClientConfig struct {
+	Name           string // Identifies the source in logs and stats, defaults to the URL
 	GeneratorURL   string
+	Auth           SourceAuth
+	Tags           map[string]string // A "site" tag becomes the location of the source's switches
 	PollInterval   time.Duration
 	Timeout        time.Duration
 	MaxRetries     int
 	StartupDelay   time.Duration
 	ReadinessCheck bool
+	ChunkSize      int               // Rows handed to IngestBatch at a time, defaults to DefaultChunkSize
+	ColumnAliases  map[string]string // Maps the source's CSV column names to models.CSVColumns
+	Optional       []string          // Metric columns read as zero when the source does not report them
+	AcceptEncoding string            // Accept-Encoding sent to the source, defaults to DefaultAcceptEncoding
+}
+
+// SourceAuth holds the credentials sent with every request to a source
+type SourceAuth struct {
+	Token    string // Sent as a bearer token
+	Username string // HTTP basic auth, used when no token is set
+	Password string
+	Headers  map[string]string
+}
+
+// apply sets the credentials on req
+func (a SourceAuth) apply(req *http.Request) {
+	for name, value := range a.Headers {
+		req.Header.Set(name, value)
+	}
+	switch {
+	case a.Token != "":
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	case a.Username != "":
+		req.SetBasicAuth(a.Username, a.Password)
+	}
 }
 
 // NewGeneratorClient creates a new generator HTTP client
@@ -72,9 +117,26 @@ func NewGeneratorClient(
 	service telemetry.TelemetryService,
 	logger log.Logger,
 ) GeneratorClientInterface {
+	return newGeneratorClient(config, service, logger)
+}
+
+func newGeneratorClient(
+	config GeneratorClientConfig,
+	service telemetry.TelemetryService,
+	logger log.Logger,
+) *GeneratorClient {
+	if config.Name == "" {
+		config.Name = config.GeneratorURL
+	}
 	if logger == nil {
 		logger = log.DefaultLogger
 	}
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = DefaultChunkSize
+	}
+	if config.AcceptEncoding == "" {
+		config.AcceptEncoding = DefaultAcceptEncoding
+	}
 
 	// Create simple HTTP client optimized for 1-second polling
 	httpClient := &http.Client{
@@ -83,13 +145,16 @@ func NewGeneratorClient(
 			MaxIdleConns:        10,              // Connection pool
 			MaxIdleConnsPerHost: 2,               // Keep few connections to generator
 			IdleConnTimeout:     5 * time.Second, // Reasonable timeout
-			DisableCompression:  true,            // CSV data doesn't benefit from compression
+			DisableCompression:  true,            // Negotiated by the client itself to measure wire bytes
 			DisableKeepAlives:   false,
 		},
 	}
 
 	return &GeneratorClient{
+		name:           config.Name,
 		generatorURL:   config.GeneratorURL,
+		auth:           config.Auth,
+		tags:           config.Tags,
 		httpClient:     httpClient,
 		logger:         logger,
 		service:        service,
@@ -98,6 +163,10 @@ func NewGeneratorClient(
 		maxRetries:     config.MaxRetries,
 		startupDelay:   config.StartupDelay,
 		readinessCheck: config.ReadinessCheck,
+		chunkSize:      config.ChunkSize,
+		columnAliases:  config.ColumnAliases,
+		optional:       config.Optional,
+		acceptEncoding: config.AcceptEncoding,
 	}
 }
 
@@ -117,8 +186,8 @@ func (gc *GeneratorClient) Start(ctx context.Context) error {
 	gc.wg.Add(1)
 	go gc.pollingWorker()
 
-	gc.logger.Infof("Generator client started, polling %s every %v",
-		gc.generatorURL, gc.pollInterval)
+	gc.logger.Infof("Generator client %s started, polling %s every %v",
+		gc.name, gc.generatorURL, gc.pollInterval)
 
 	return nil
 }
@@ -126,15 +195,16 @@ func (gc *GeneratorClient) Start(ctx context.Context) error {
 // Stop gracefully shuts down the generator client
 func (gc *GeneratorClient) Stop() error {
 	gc.mu.Lock()
-	defer gc.mu.Unlock()
-
 	if !gc.running {
+		gc.mu.Unlock()
 		return fmt.Errorf("generator client not running")
 	}
 
-	gc.logger.Infof("Stopping generator client immediately...")
+	gc.logger.Infof("Stopping generator client %s immediately...", gc.name)
 	gc.cancel()
 	gc.running = false
+	// The worker takes the lock to record polls, so it must be released before waiting
+	gc.mu.Unlock()
 
 	// Wait for worker to finish with a short timeout..
 	done := make(chan struct{})
@@ -162,6 +232,7 @@ func (gc *GeneratorClient) Stop() error {
 // pollingWorker runs the background polling loop
 func (gc *GeneratorClient) pollingWorker() {
 	defer gc.wg.Done()
+	defer gc.closeDecoders()
 
 	// Wait for startup delay to ensure telemetry service is ready
 	if gc.startupDelay > 0 {
@@ -211,112 +282,152 @@ func (gc *GeneratorClient) pollAndIngest() {
 	gc.fetchAndProcessData()
 }
 
-// fetchAndProcessData fetches and processes data synchronously
+// fetchAndProcessData streams the generator's CSV export into the telemetry service
 func (gc *GeneratorClient) fetchAndProcessData() {
-
-	// Fetch data from generator (ufm)
-	csvData, headers, err := gc.fetchCSVData()
+	resp, err := gc.fetchCounters()
 	if err != nil {
-		gc.mu.Lock()
-		gc.errorCount++
-		gc.mu.Unlock()
-		gc.logger.Errorf("Failed to fetch CSV data: %v", err)
+		gc.recordError(err)
+		gc.logger.Errorf("Failed to fetch CSV data from %s: %v", gc.name, err)
 		return
 	}
+	defer resp.Body.Close()
 
-	generationID := headers.Get("X-Generation-ID")
-	dataTimestamp := gc.parseTimestamp(headers.Get("X-Data-Timestamp"))
+	generationID := resp.Header.Get("X-Generation-ID")
+	dataTimestamp := gc.parseTimestamp(resp.Header.Get("X-Data-Timestamp"))
+	etag := resp.Header.Get("ETag")
 
-	// If no timestamp in headers, use first row timestamp from CSV
-	if dataTimestamp.IsZero() && len(csvData) > 0 {
-		dataTimestamp = gc.extractFirstTimestampFromCSV(csvData)
+	if resp.StatusCode == http.StatusNotModified {
+		metrics.IngestionNotModifiedTotal.WithLabelValues(gc.name).Inc()
+		gc.mu.Lock()
+		gc.notModified++
+		gc.mu.Unlock()
+		gc.recordDuplicate(generationID, dataTimestamp, etag)
+		return
 	}
 
+	// Skip the body entirely when the headers already identify the generation
 	if gc.isDuplicateData(generationID, dataTimestamp) {
-		gc.mu.Lock()
-		gc.duplicateSkips++
-		gc.mu.Unlock()
-		gc.logger.Debugf("Skipping duplicate data (generation_id: %s, timestamp: %v)", generationID, dataTimestamp.Format(time.RFC3339))
+		gc.recordDuplicate(generationID, dataTimestamp, etag)
 		return
 	}
 
-	// Parse and ingest the CSV data
-	telemetryData, err := gc.parseCSVData(csvData)
+	wire := &countingReader{reader: resp.Body}
+	body, err := gc.decodeBody(wire, resp.Header.Get("Content-Encoding"))
 	if err != nil {
-		gc.mu.Lock()
-		gc.errorCount++
-		gc.mu.Unlock()
-		gc.logger.Errorf("Failed to parse CSV data: %v", err)
+		gc.recordError(err)
+		gc.logger.Errorf("Failed to decode CSV data from %s: %v", gc.name, err)
 		return
 	}
 
-	// Register switches from telemetry data
-	gc.registerSwitchesFromData(telemetryData)
-
-	// Ingest the data
-	if err := gc.service.IngestBatch(telemetryData); err != nil {
-		gc.mu.Lock()
-		gc.errorCount++
-		gc.mu.Unlock()
-		gc.logger.Errorf("Failed to ingest telemetry data: %v", err)
+	result, err := gc.ingestCSV(body, generationID, dataTimestamp)
+	result.wireBytes = wire.count
+	result.encoding = resp.Header.Get("Content-Encoding")
+	gc.recordParse(result)
+	if errors.Is(err, errDuplicateData) {
+		gc.recordDuplicate(generationID, result.dataTimestamp, etag)
+		return
+	}
+	if err != nil {
+		gc.recordError(err)
+		gc.logger.Errorf("Failed to ingest telemetry data from %s after %d rows: %v", gc.name, result.rows, err)
 		return
 	}
 
 	// Update deduplication tracking
 	gc.mu.Lock()
 	gc.lastGenerationID = generationID
-	gc.lastDataTimestamp = dataTimestamp
+	gc.lastDataTimestamp = result.dataTimestamp
+	gc.lastETag = etag
 	gc.successfulPolls++
+	gc.consecutiveErrors = 0
+	gc.lastSuccessTime = time.Now()
 	gc.mu.Unlock()
 
-	gc.logger.Infof("Successfully ingested %d telemetry records, generation_id=%s", len(telemetryData), generationID)
+	gc.logger.Infof("Successfully ingested %d telemetry records from %s in %s (%.0f rows/s), generation_id=%s",
+		result.rows, gc.name, result.duration.Round(time.Millisecond), result.rowsPerSecond(), generationID)
+}
+
+// recordDuplicate counts a poll that returned an already ingested generation
+func (gc *GeneratorClient) recordDuplicate(generationID string, dataTimestamp time.Time, etag string) {
+	gc.mu.Lock()
+	if etag != "" {
+		gc.lastETag = etag
+	}
+	gc.duplicateSkips++
+	gc.consecutiveErrors = 0
+	gc.lastSuccessTime = time.Now()
+	gc.mu.Unlock()
+	gc.logger.Debugf("Skipping duplicate data (generation_id: %s, timestamp: %v)", generationID, dataTimestamp.Format(time.RFC3339))
 }
 
-// fetchCSVData fetches CSV data from the generator with simple error handling
-func (gc *GeneratorClient) fetchCSVData() (string, http.Header, error) {
+// recordError counts a failed poll
+func (gc *GeneratorClient) recordError(err error) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.errorCount++
+	gc.consecutiveErrors++
+	gc.lastError = err.Error()
+}
+
+// recordParse publishes the throughput of a streamed payload
+func (gc *GeneratorClient) recordParse(result parseResult) {
+	metrics.IngestionRowsParsedTotal.WithLabelValues(gc.name).Add(float64(result.rows))
+	metrics.IngestionRowsSkippedTotal.WithLabelValues(gc.name).Add(float64(result.skipped))
+	metrics.IngestionWireBytesTotal.WithLabelValues(gc.name).Add(float64(result.wireBytes))
+	metrics.IngestionDecodedBytesTotal.WithLabelValues(gc.name).Add(float64(result.bytes))
+	metrics.IngestionParseDuration.WithLabelValues(gc.name).Observe(result.duration.Seconds())
+	metrics.IngestionParseRowsPerSecond.WithLabelValues(gc.name).Set(result.rowsPerSecond())
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.rowsParsed += int64(result.rows)
+	gc.lastParse = result
+}
+
+// fetchCounters requests the generator's CSV export, conditional on the last
+// ETag. The response is either 200 or 304 Not Modified; the caller closes the body.
+func (gc *GeneratorClient) fetchCounters() (*http.Response, error) {
 	url := gc.generatorURL + "/counters"
 
 	// Check if context is cancelled before making request
 	select {
 	case <-gc.ctx.Done():
-		return "", nil, gc.ctx.Err()
+		return nil, gc.ctx.Err()
 	default:
 	}
 
-	ctx, cancel := context.WithTimeout(gc.ctx, gc.timeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := http.NewRequestWithContext(gc.ctx, "GET", url, nil)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers for better HTTP performance
 	req.Header.Set("Accept", "text/csv")
+	req.Header.Set("Accept-Encoding", gc.acceptEncoding)
 	req.Header.Set("User-Agent", "UFM-Telemetry-Client/1.0")
+	gc.auth.apply(req)
+	gc.mu.RLock()
+	if gc.lastETag != "" {
+		req.Header.Set("If-None-Match", gc.lastETag)
+	}
+	gc.mu.RUnlock()
 
+	// The client timeout also bounds reading the body while it is streamed
 	resp, err := gc.httpClient.Do(req)
 	if err != nil {
 		// Simple error logging - don't retry, just continue to next poll
 		gc.logger.Warnf("HTTP request failed: %v", err)
-		return "", nil, err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotModified {
+		resp.Body.Close()
 		err := fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 		gc.logger.Warnf("HTTP status error: %v", err)
-		return "", nil, err
-	}
-
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		gc.logger.Warnf("Failed to read response body: %v", err)
-		return "", nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
-	return string(body), resp.Header, nil
+	return resp, nil
 }
 
 // checkGeneratorReadiness verifies that the generator is accessible and healthy
@@ -330,6 +441,7 @@ func (gc *GeneratorClient) checkGeneratorReadiness() error {
 	if err != nil {
 		return fmt.Errorf("failed to create readiness check request: %w", err)
 	}
+	gc.auth.apply(req)
 
 	resp, err := gc.httpClient.Do(req)
 	if err != nil {
@@ -355,6 +467,7 @@ func (gc *GeneratorClient) checkGeneratorConnectivity() error {
 	if err != nil {
 		return fmt.Errorf("failed to create connectivity check request: %w", err)
 	}
+	gc.auth.apply(req)
 
 	resp, err := gc.httpClient.Do(req)
 	if err != nil {
@@ -369,59 +482,25 @@ func (gc *GeneratorClient) checkGeneratorConnectivity() error {
 	return nil
 }
 
-// parseCSVData parses CSV data into telemetry data structures
-func (gc *GeneratorClient) parseCSVData(csvData string) ([]models.TelemetryData, error) {
-	reader := csv.NewReader(strings.NewReader(csvData))
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse CSV: %w", err)
-	}
-
-	if len(records) < 2 { // Header + at least one data row
-		return nil, fmt.Errorf("CSV contains no data rows")
-	}
-
-	// Skip header row
-	var telemetryData []models.TelemetryData
-	for i, record := range records[1:] {
-		if len(record) < 7 {
-			gc.logger.Warnf("Skipping CSV row %d: insufficient columns (%d)", i+2, len(record))
-			continue
-		}
-
-		data, err := gc.parseCSVRecord(record)
-		if err != nil {
-			gc.logger.Warnf("Skipping CSV row %d: %v", i+2, err)
-			continue
-		}
-
-		telemetryData = append(telemetryData, data)
-	}
-
-	return telemetryData, nil
-}
-
-// registerSwitchesFromData registers switches found in telemetry data
-func (gc *GeneratorClient) registerSwitchesFromData(telemetryData []models.TelemetryData) {
-	// Track unique switches to avoid duplicate registrations
-	seenSwitches := make(map[string]bool)
-
+// registerSwitchesFromData registers the switches found in telemetry data
+// that were not already seen earlier in the same payload
+func (gc *GeneratorClient) registerSwitchesFromData(telemetryData []models.TelemetryData, seenSwitches map[string]bool) {
 	for _, data := range telemetryData {
 		if data.SwitchID == "" {
 			continue
 		}
 
-		// Skip if we've already processed this switch in this batch
+		// Skip if we've already processed this switch in this payload
 		if seenSwitches[data.SwitchID] {
 			continue
 		}
 		seenSwitches[data.SwitchID] = true
 
-		// Create switch record with "data center" as location
+		// Create switch record located at the source's site
 		switchRecord := models.Switch{
 			ID:       data.SwitchID,
 			Name:     data.SwitchID,
-			Location: "data center",
+			Location: gc.location(),
 			Created:  time.Now(),
 		}
 
@@ -434,48 +513,12 @@ func (gc *GeneratorClient) registerSwitchesFromData(telemetryData []models.Telem
 	}
 }
 
-// parseCSVRecord parses a single CSV record into TelemetryData
-func (gc *GeneratorClient) parseCSVRecord(record []string) (models.TelemetryData, error) {
-	// CSV format: switch_id,timestamp,bandwidth_mbps,latency_ms,packet_errors,utilization_pct,temperature_c
-	timestamp, err := time.Parse(time.RFC3339Nano, record[1])
-	if err != nil {
-		return models.TelemetryData{}, fmt.Errorf("invalid timestamp: %w", err)
-	}
-
-	bandwidth, err := strconv.ParseFloat(record[2], 64)
-	if err != nil {
-		return models.TelemetryData{}, fmt.Errorf("invalid bandwidth: %w", err)
+// location returns the location of the switches reported by this source
+func (gc *GeneratorClient) location() string {
+	if site := gc.tags["site"]; site != "" {
+		return site
 	}
-
-	latency, err := strconv.ParseFloat(record[3], 64)
-	if err != nil {
-		return models.TelemetryData{}, fmt.Errorf("invalid latency: %w", err)
-	}
-
-	packetErrors, err := strconv.ParseInt(record[4], 10, 64)
-	if err != nil {
-		return models.TelemetryData{}, fmt.Errorf("invalid packet errors: %w", err)
-	}
-
-	utilization, err := strconv.ParseFloat(record[5], 64)
-	if err != nil {
-		return models.TelemetryData{}, fmt.Errorf("invalid utilization: %w", err)
-	}
-
-	temperature, err := strconv.ParseFloat(record[6], 64)
-	if err != nil {
-		return models.TelemetryData{}, fmt.Errorf("invalid temperature: %w", err)
-	}
-
-	return models.TelemetryData{
-		SwitchID:       record[0],
-		Timestamp:      timestamp,
-		BandwidthMbps:  bandwidth,
-		LatencyMs:      latency,
-		PacketErrors:   packetErrors,
-		UtilizationPct: utilization,
-		TemperatureC:   temperature,
-	}, nil
+	return "data center"
 }
 
 // isDuplicateData checks if the data has already been processed
@@ -511,28 +554,6 @@ func (gc *GeneratorClient) parseTimestamp(timestampStr string) time.Time {
 	return timestamp
 }
 
-// extractFirstTimestampFromCSV extracts timestamp from the first data row of CSV
-func (gc *GeneratorClient) extractFirstTimestampFromCSV(csvData string) time.Time {
-	lines := strings.Split(csvData, "\n")
-	if len(lines) < 2 { // Need header + at least one data row
-		return time.Time{}
-	}
-
-	// Skip header
-	fields := strings.Split(lines[1], ",")
-	if len(fields) < 2 {
-		return time.Time{}
-	}
-
-	timestamp, err := time.Parse(time.RFC3339Nano, fields[1])
-	if err != nil {
-		gc.logger.Debugf("Failed to parse CSV timestamp '%s': %v", fields[1], err)
-		return time.Time{}
-	}
-
-	return timestamp
-}
-
 // GetStats returns performance statistics
 func (gc *GeneratorClient) GetStats() map[string]interface{} {
 	gc.mu.RLock()
@@ -548,16 +569,41 @@ func (gc *GeneratorClient) GetStats() map[string]interface{} {
 		duplicateRate = float64(gc.duplicateSkips) / float64(gc.totalPolls) * 100
 	}
 
+	status := "ok"
+	switch {
+	case gc.consecutiveErrors > 0:
+		status = "failing"
+	case gc.lastSuccessTime.IsZero():
+		status = "pending"
+	}
+
 	return map[string]interface{}{
+		"name":                gc.name,
+		"url":                 gc.generatorURL,
+		"tags":                gc.tags,
+		"status":              status,
 		"total_polls":         gc.totalPolls,
 		"successful_polls":    gc.successfulPolls,
 		"duplicate_skips":     gc.duplicateSkips,
+		"not_modified":        gc.notModified,
 		"error_count":         gc.errorCount,
+		"consecutive_errors":  gc.consecutiveErrors,
+		"last_error":          gc.lastError,
 		"success_rate":        fmt.Sprintf("%.2f%%", successRate),
 		"duplicate_rate":      fmt.Sprintf("%.2f%%", duplicateRate),
 		"last_poll_time":      gc.lastPollTime.Format(time.RFC3339),
+		"last_success_time":   gc.lastSuccessTime.Format(time.RFC3339),
 		"last_generation_id":  gc.lastGenerationID,
+		"last_etag":           gc.lastETag,
 		"last_data_timestamp": gc.lastDataTimestamp.Format(time.RFC3339Nano),
 		"poll_interval":       gc.pollInterval.String(),
+		"rows_parsed":         gc.rowsParsed,
+		"last_parse_rows":     gc.lastParse.rows,
+		"last_parse_skipped":  gc.lastParse.skipped,
+		"last_parse_bytes":    gc.lastParse.bytes,
+		"last_wire_bytes":     gc.lastParse.wireBytes,
+		"last_encoding":       gc.lastParse.encoding,
+		"last_parse_duration": gc.lastParse.duration.String(),
+		"parse_rows_per_sec":  gc.lastParse.rowsPerSecond(),
 	}
 }
@@ -2,13 +2,19 @@ package client
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/ufm/internal/log"
 	"github.com/ufm/internal/telemetry/models"
+	"github.com/ufm/internal/telemetry/stream"
 )
 
 // Mock telemetry service for testing
@@ -26,6 +32,11 @@ func (m *mockTelemetryService) IngestBatch(data []models.TelemetryData) error {
 	return args.Error(0)
 }
 
+func (m *mockTelemetryService) ValidateMetricData(data models.TelemetryData) error {
+	args := m.Called(data)
+	return args.Error(0)
+}
+
 func (m *mockTelemetryService) GetMetric(switchID string, metricType models.MetricType) (*models.MetricResponse, error) {
 	args := m.Called(switchID, metricType)
 	return args.Get(0).(*models.MetricResponse), args.Error(1)
@@ -41,6 +52,46 @@ func (m *mockTelemetryService) GetAllMetrics() (*models.AllMetricsResponse, erro
 	return args.Get(0).(*models.AllMetricsResponse), args.Error(1)
 }
 
+func (m *mockTelemetryService) GetMetricHistory(query models.HistoryQuery) (*models.MetricHistoryResponse, error) {
+	args := m.Called(query)
+	return args.Get(0).(*models.MetricHistoryResponse), args.Error(1)
+}
+
+func (m *mockTelemetryService) GetSwitchSummary(switchID string, window time.Duration) (*models.SwitchSummary, error) {
+	args := m.Called(switchID, window)
+	return args.Get(0).(*models.SwitchSummary), args.Error(1)
+}
+
+func (m *mockTelemetryService) GetFabricStatistics(groupBy string) (*models.FabricStatisticsResponse, error) {
+	args := m.Called(groupBy)
+	return args.Get(0).(*models.FabricStatisticsResponse), args.Error(1)
+}
+
+func (m *mockTelemetryService) GetTopSwitches(query models.TopSwitchesQuery) (*models.TopSwitchesResponse, error) {
+	args := m.Called(query)
+	return args.Get(0).(*models.TopSwitchesResponse), args.Error(1)
+}
+
+func (m *mockTelemetryService) GetActiveAlerts(switchID string, state models.AlertState) (*models.ActiveAlertsResponse, error) {
+	args := m.Called(switchID, state)
+	return args.Get(0).(*models.ActiveAlertsResponse), args.Error(1)
+}
+
+func (m *mockTelemetryService) GetAlertHistory(query models.AlertHistoryQuery) (*models.AlertHistoryResponse, error) {
+	args := m.Called(query)
+	return args.Get(0).(*models.AlertHistoryResponse), args.Error(1)
+}
+
+func (m *mockTelemetryService) GetAnomalies(query models.AnomalyQuery) (*models.AnomaliesResponse, error) {
+	args := m.Called(query)
+	return args.Get(0).(*models.AnomaliesResponse), args.Error(1)
+}
+
+func (m *mockTelemetryService) Subscribe(filter stream.Filter) (*stream.Subscription, error) {
+	args := m.Called(filter)
+	return args.Get(0).(*stream.Subscription), args.Error(1)
+}
+
 func (m *mockTelemetryService) RegisterSwitch(sw models.Switch) error {
 	args := m.Called(sw)
 	return args.Error(0)
@@ -179,3 +230,42 @@ func TestGeneratorClientConfig_Validation(t *testing.T) {
 		})
 	}
 }
+
+func TestGeneratorClient_ConditionalFetch(t *testing.T) {
+	var conditional, full atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"gen_1"`)
+		w.Header().Set("X-Generation-ID", "gen_1")
+		if r.Header.Get("If-None-Match") == `"gen_1"` {
+			conditional.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		fmt.Fprint(w, countersCSV(time.Now().UTC(), "switch-001"))
+	}))
+	defer server.Close()
+
+	mockService := &mockTelemetryService{}
+	mockService.On("RegisterSwitch", mock.Anything).Return(nil)
+	mockService.On("IngestBatch", mock.Anything).Return(nil)
+
+	client := newGeneratorClient(GeneratorClientConfig{
+		GeneratorURL: server.URL,
+		PollInterval: 10 * time.Millisecond,
+		Timeout:      time.Second,
+	}, mockService, log.DefaultLogger)
+	require.NoError(t, client.Start(context.Background()))
+	defer client.Stop()
+
+	require.Eventually(t, func() bool { return conditional.Load() >= 3 }, time.Second, 10*time.Millisecond)
+
+	// Only the first poll downloads the body, the rest are 304s without errors
+	stats := client.GetStats()
+	assert.Equal(t, int64(1), full.Load())
+	assert.Equal(t, int64(1), stats["successful_polls"])
+	assert.GreaterOrEqual(t, stats["not_modified"], int64(3))
+	assert.Equal(t, int64(0), stats["error_count"])
+	assert.Equal(t, `"gen_1"`, stats["last_etag"])
+	mockService.AssertNumberOfCalls(t, "IngestBatch", 1)
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/telemetry"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// SourceManager polls several generator or UFM endpoints concurrently. Each
+// source gets its own GeneratorClient, so poll intervals, credentials and
+// deduplication state are tracked per source and a slow or failing source
+// does not hold up the others.
+type SourceManager struct {
+	sources []*GeneratorClient
+	logger  log.Logger
+}
+
+// NewSourceManager creates a poller for every source. Source names must be unique.
+func NewSourceManager(
+	configs []GeneratorClientConfig,
+	service telemetry.TelemetryService,
+	logger log.Logger,
+) (*SourceManager, error) {
+	if logger == nil {
+		logger = log.DefaultLogger
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no ingestion sources configured")
+	}
+
+	names := make(map[string]bool, len(configs))
+	sources := make([]*GeneratorClient, 0, len(configs))
+	for i, config := range configs {
+		if config.GeneratorURL == "" {
+			return nil, fmt.Errorf("ingestion source %d has no URL", i+1)
+		}
+		if err := models.ValidateCSVAliases(config.ColumnAliases); err != nil {
+			return nil, fmt.Errorf("ingestion source %d: %w", i+1, err)
+		}
+		if err := models.ValidateCSVOptionalColumns(config.Optional); err != nil {
+			return nil, fmt.Errorf("ingestion source %d: %w", i+1, err)
+		}
+		source := newGeneratorClient(config, service, logger)
+		if names[source.name] {
+			return nil, fmt.Errorf("duplicate ingestion source name %q", source.name)
+		}
+		names[source.name] = true
+		sources = append(sources, source)
+	}
+
+	return &SourceManager{sources: sources, logger: logger}, nil
+}
+
+// Start begins polling every source
+func (m *SourceManager) Start(ctx context.Context) error {
+	for i, source := range m.sources {
+		if err := source.Start(ctx); err != nil {
+			for _, started := range m.sources[:i] {
+				started.Stop()
+			}
+			return fmt.Errorf("failed to start ingestion source %s: %w", source.name, err)
+		}
+	}
+
+	m.logger.Infof("Ingesting telemetry from %d sources", len(m.sources))
+	return nil
+}
+
+// Stop stops every source
+func (m *SourceManager) Stop() error {
+	var errs []error
+	for _, source := range m.sources {
+		if err := source.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GetStats returns totals across all sources along with the stats of each source
+func (m *SourceManager) GetStats() map[string]interface{} {
+	var totalPolls, successfulPolls, duplicateSkips, notModified, errorCount, rowsParsed int64
+	failing := 0
+	for _, source := range m.sources {
+		source.mu.RLock()
+		totalPolls += source.totalPolls
+		successfulPolls += source.successfulPolls
+		duplicateSkips += source.duplicateSkips
+		notModified += source.notModified
+		errorCount += source.errorCount
+		rowsParsed += source.rowsParsed
+		if source.consecutiveErrors > 0 {
+			failing++
+		}
+		source.mu.RUnlock()
+	}
+
+	return map[string]interface{}{
+		"source_count":     len(m.sources),
+		"failing_sources":  failing,
+		"total_polls":      totalPolls,
+		"successful_polls": successfulPolls,
+		"duplicate_skips":  duplicateSkips,
+		"not_modified":     notModified,
+		"error_count":      errorCount,
+		"rows_parsed":      rowsParsed,
+		"sources":          m.SourceStats(),
+	}
+}
+
+// SourceStats returns the GetStats of every source, ordered by name
+func (m *SourceManager) SourceStats() []map[string]interface{} {
+	stats := make([]map[string]interface{}, 0, len(m.sources))
+	for _, source := range m.sources {
+		stats = append(stats, source.GetStats())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i]["name"].(string) < stats[j]["name"].(string)
+	})
+	return stats
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// newCountersServer serves a single-row /counters export that only changes generation
+// when the returned counter is bumped, and rejects requests without the bearer token
+func newCountersServer(t *testing.T, switchID, token string) (*httptest.Server, *atomic.Int64) {
+	var generation atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Generation-ID", fmt.Sprintf("gen-%d", generation.Load()))
+		fmt.Fprintf(w, "%s\n%s,%s,950,1.2,0,40,44\n",
+			strings.Join(models.CSVColumns, ","), switchID, time.Now().UTC().Format(time.RFC3339Nano))
+	}))
+	t.Cleanup(server.Close)
+	return server, &generation
+}
+
+func TestSourceManager_PollsSourcesIndependently(t *testing.T) {
+	east, _ := newCountersServer(t, "switch-east", "east-token")
+	west, _ := newCountersServer(t, "switch-west", "west-token")
+
+	mockService := &mockTelemetryService{}
+	mockService.On("IngestBatch", mock.Anything).Return(nil)
+	mockService.On("RegisterSwitch", mock.MatchedBy(func(sw models.Switch) bool {
+		return sw.ID == "switch-east" && sw.Location == "east"
+	})).Return(nil)
+
+	manager, err := NewSourceManager([]GeneratorClientConfig{
+		{
+			Name:         "dc-east",
+			GeneratorURL: east.URL,
+			PollInterval: 10 * time.Millisecond,
+			Timeout:      time.Second,
+			Auth:         SourceAuth{Token: "east-token"},
+			Tags:         map[string]string{"site": "east"},
+		},
+		{
+			Name:         "dc-west",
+			GeneratorURL: west.URL,
+			PollInterval: 10 * time.Millisecond,
+			Timeout:      time.Second,
+			Auth:         SourceAuth{Token: "wrong-token"},
+		},
+	}, mockService, log.DefaultLogger)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Start(context.Background()))
+	defer manager.Stop()
+
+	// The unchanged generation is ingested once and skipped afterwards
+	require.Eventually(t, func() bool {
+		stats := manager.SourceStats()
+		return stats[0]["duplicate_skips"].(int64) > 0 && stats[1]["consecutive_errors"].(int64) > 1
+	}, time.Second, 10*time.Millisecond)
+
+	stats := manager.SourceStats()
+	assert.Equal(t, "dc-east", stats[0]["name"])
+	assert.Equal(t, "ok", stats[0]["status"])
+	assert.Equal(t, int64(1), stats[0]["successful_polls"])
+	assert.Equal(t, "dc-west", stats[1]["name"])
+	assert.Equal(t, "failing", stats[1]["status"])
+	assert.Contains(t, stats[1]["last_error"], "401")
+
+	totals := manager.GetStats()
+	assert.Equal(t, 2, totals["source_count"])
+	assert.Equal(t, 1, totals["failing_sources"])
+	mockService.AssertNumberOfCalls(t, "IngestBatch", 1)
+}
+
+func TestNewSourceManager_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []GeneratorClientConfig
+	}{
+		{"no sources", nil},
+		{"missing URL", []GeneratorClientConfig{{Name: "dc-east"}}},
+		{"duplicate name", []GeneratorClientConfig{
+			{Name: "dc-east", GeneratorURL: "http://east:9001"},
+			{Name: "dc-east", GeneratorURL: "http://east-2:9001"},
+		}},
+		{"unknown alias column", []GeneratorClientConfig{
+			{GeneratorURL: "http://east:9001", ColumnAliases: map[string]string{"guid": "node_guid"}},
+		}},
+		{"optional identity column", []GeneratorClientConfig{
+			{GeneratorURL: "http://east:9001", Optional: []string{"switch_id"}},
+		}},
+		{"duplicate default name", []GeneratorClientConfig{
+			{GeneratorURL: "http://east:9001"},
+			{GeneratorURL: "http://east:9001"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSourceManager(tt.configs, &mockTelemetryService{}, log.DefaultLogger)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// AlertState is the lifecycle state of an alert for one rule and switch
+type AlertState string
+
+const (
+	// AlertStatePending means the condition holds but has not yet held for the rule's duration
+	AlertStatePending AlertState = "pending"
+	// AlertStateFiring means the condition has held for at least the rule's duration
+	AlertStateFiring AlertState = "firing"
+	// AlertStateResolved means a firing alert's condition no longer holds
+	AlertStateResolved AlertState = "resolved"
+)
+
+// Alert is the current state of a rule evaluated against a single switch
+type Alert struct {
+	RuleName   string     `json:"rule"`
+	SwitchID   string     `json:"switch_id"`
+	Metric     string     `json:"metric"`
+	Comparator string     `json:"comparator"`
+	Threshold  float64    `json:"threshold"`
+	Severity   string     `json:"severity"`
+	State      AlertState `json:"state"`
+	Value      float64    `json:"value"`
+	ActiveAt   time.Time  `json:"active_at"`
+	FiredAt    *time.Time `json:"fired_at,omitempty"`
+	LastSeen   time.Time  `json:"last_seen"`
+}
+
+// AlertEvent records a firing or resolved transition of an alert
+type AlertEvent struct {
+	ID         int64      `json:"id,omitempty"`
+	RuleName   string     `json:"rule"`
+	SwitchID   string     `json:"switch_id"`
+	Metric     string     `json:"metric"`
+	Comparator string     `json:"comparator"`
+	Threshold  float64    `json:"threshold"`
+	Severity   string     `json:"severity"`
+	State      AlertState `json:"state"`
+	Value      float64    `json:"value"`
+	ActiveAt   time.Time  `json:"active_at"`
+	Timestamp  time.Time  `json:"timestamp"`
+}
+
+// AlertHistoryQuery selects persisted alert events, newest first
+type AlertHistoryQuery struct {
+	SwitchID string // Empty means all switches
+	RuleName string // Empty means all rules
+	From     time.Time
+	To       time.Time
+	Limit    int
+}
+
+// ActiveAlertsResponse represents the alerts currently pending or firing
+type ActiveAlertsResponse struct {
+	Alerts    []Alert   `json:"alerts"`
+	Count     int       `json:"count"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AlertHistoryResponse represents persisted alert transitions over a time range
+type AlertHistoryResponse struct {
+	Events []AlertEvent `json:"events"`
+	From   time.Time    `json:"from"`
+	To     time.Time    `json:"to"`
+	Count  int          `json:"count"`
+}
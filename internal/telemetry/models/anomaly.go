@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Anomaly is a sample whose value deviates from the switch's own baseline for a metric
+type Anomaly struct {
+	SwitchID  string    `json:"switch_id"`
+	Metric    string    `json:"metric"`
+	Value     float64   `json:"value"`
+	Mean      float64   `json:"mean"`
+	StdDev    float64   `json:"stddev"`
+	ZScore    float64   `json:"z_score"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AnomalyQuery selects recently detected anomalies, newest first
+type AnomalyQuery struct {
+	SwitchID string     // Empty means all switches
+	Metric   MetricType // Empty means all metric types
+	Since    time.Time  // Zero means no lower bound
+	Limit    int
+}
+
+// AnomaliesResponse represents recently detected anomalies
+type AnomaliesResponse struct {
+	Anomalies []Anomaly `json:"anomalies"`
+	Count     int       `json:"count"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -0,0 +1,208 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVColumns is the column layout of the generator's /counters export and of CSV ingest bodies
+var CSVColumns = []string{
+	"switch_id",
+	"timestamp",
+	"bandwidth_mbps",
+	"latency_ms",
+	"packet_errors",
+	"utilization_pct",
+	"temperature_c",
+}
+
+// IsCSVHeader reports whether record is the CSV header row rather than data
+func IsCSVHeader(record []string) bool {
+	return len(record) > 0 && strings.TrimSpace(record[0]) == CSVColumns[0]
+}
+
+// RequiredCSVColumns must appear in every CSV header. The metric columns are
+// required as well unless they are listed as optional, then they read as zero
+// when a source does not report them.
+var RequiredCSVColumns = []string{"switch_id", "timestamp"}
+
+// ErrMissingCSVColumns reports a CSV header without every required column
+var ErrMissingCSVColumns = errors.New("CSV header is missing required columns")
+
+// CSVSchema maps the columns of a CSV payload to TelemetryData fields by header name
+type CSVSchema struct {
+	positions map[string]int // CSVColumns name -> index in the record
+	width     int            // shortest record that holds every mapped column
+	unknown   []string
+	missing   []string
+}
+
+// positionalSchema reads records in CSVColumns order
+var positionalSchema = func() *CSVSchema {
+	schema, _ := NewCSVSchema(CSVColumns, nil, nil)
+	return schema
+}()
+
+// ValidateCSVAliases checks that every alias maps to one of CSVColumns and that
+// no two aliases name the same column
+func ValidateCSVAliases(aliases map[string]string) error {
+	names := make(map[string]string, len(aliases))
+	for alias, column := range aliases {
+		if !slices.Contains(CSVColumns, normalizeCSVColumn(column)) {
+			return fmt.Errorf("column alias %q maps to unknown column %q", alias, column)
+		}
+		name := normalizeCSVColumn(alias)
+		if name == "" {
+			return fmt.Errorf("column alias for %q has no name", column)
+		}
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("column aliases %q and %q name the same column", other, alias)
+		}
+		names[name] = alias
+	}
+	return nil
+}
+
+// ValidateCSVOptionalColumns checks that every optional column is a metric column
+func ValidateCSVOptionalColumns(optional []string) error {
+	for _, column := range optional {
+		name := normalizeCSVColumn(column)
+		if !slices.Contains(CSVColumns, name) || slices.Contains(RequiredCSVColumns, name) {
+			return fmt.Errorf("optional column %q is not a metric column", column)
+		}
+	}
+	return nil
+}
+
+// NewCSVSchema builds a schema from a header row. aliases maps the column names
+// a source uses to CSVColumns names, and optional lists the metric columns that
+// may be absent. Names are compared case-insensitively and columns that are
+// neither known nor aliased are ignored.
+func NewCSVSchema(header []string, aliases map[string]string, optional []string) (*CSVSchema, error) {
+	if err := ValidateCSVAliases(aliases); err != nil {
+		return nil, err
+	}
+	if err := ValidateCSVOptionalColumns(optional); err != nil {
+		return nil, err
+	}
+	normalized := make(map[string]string, len(aliases))
+	for alias, column := range aliases {
+		normalized[normalizeCSVColumn(alias)] = normalizeCSVColumn(column)
+	}
+
+	schema := &CSVSchema{positions: make(map[string]int, len(CSVColumns))}
+	for i, name := range header {
+		column := normalizeCSVColumn(name)
+		if alias, ok := normalized[column]; ok {
+			column = alias
+		}
+		if !slices.Contains(CSVColumns, column) {
+			schema.unknown = append(schema.unknown, name)
+			continue
+		}
+		if previous, ok := schema.positions[column]; ok {
+			return nil, fmt.Errorf("CSV header maps both %q and %q to %s", header[previous], name, column)
+		}
+		schema.positions[column] = i
+		schema.width = max(schema.width, i+1)
+	}
+
+	var required []string
+	for _, column := range CSVColumns {
+		if _, ok := schema.positions[column]; ok {
+			continue
+		}
+		if slices.ContainsFunc(optional, func(name string) bool { return normalizeCSVColumn(name) == column }) {
+			schema.missing = append(schema.missing, column)
+		} else {
+			required = append(required, column)
+		}
+	}
+	if len(required) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingCSVColumns, strings.Join(required, ", "))
+	}
+
+	return schema, nil
+}
+
+// Unknown returns the header columns the schema ignores
+func (s *CSVSchema) Unknown() []string {
+	return s.unknown
+}
+
+// Missing returns the optional columns absent from the header
+func (s *CSVSchema) Missing() []string {
+	return s.missing
+}
+
+// Parse parses a single CSV record laid out as described by the header
+func (s *CSVSchema) Parse(record []string) (TelemetryData, error) {
+	if len(record) < s.width {
+		return TelemetryData{}, fmt.Errorf("insufficient columns: got %d, expected %d", len(record), s.width)
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, record[s.positions["timestamp"]])
+	if err != nil {
+		return TelemetryData{}, fmt.Errorf("invalid timestamp: %w", err)
+	}
+
+	bandwidth, err := s.parseFloat(record, "bandwidth_mbps")
+	if err != nil {
+		return TelemetryData{}, fmt.Errorf("invalid bandwidth: %w", err)
+	}
+
+	latency, err := s.parseFloat(record, "latency_ms")
+	if err != nil {
+		return TelemetryData{}, fmt.Errorf("invalid latency: %w", err)
+	}
+
+	var packetErrors int64
+	if i, ok := s.positions["packet_errors"]; ok {
+		if packetErrors, err = strconv.ParseInt(record[i], 10, 64); err != nil {
+			return TelemetryData{}, fmt.Errorf("invalid packet errors: %w", err)
+		}
+	}
+
+	utilization, err := s.parseFloat(record, "utilization_pct")
+	if err != nil {
+		return TelemetryData{}, fmt.Errorf("invalid utilization: %w", err)
+	}
+
+	temperature, err := s.parseFloat(record, "temperature_c")
+	if err != nil {
+		return TelemetryData{}, fmt.Errorf("invalid temperature: %w", err)
+	}
+
+	return TelemetryData{
+		SwitchID:       record[s.positions["switch_id"]],
+		Timestamp:      timestamp,
+		BandwidthMbps:  bandwidth,
+		LatencyMs:      latency,
+		PacketErrors:   packetErrors,
+		UtilizationPct: utilization,
+		TemperatureC:   temperature,
+	}, nil
+}
+
+// parseFloat parses a metric column, an optional column missing from the header reads as zero
+func (s *CSVSchema) parseFloat(record []string, column string) (float64, error) {
+	i, ok := s.positions[column]
+	if !ok {
+		return 0, nil
+	}
+	return strconv.ParseFloat(record[i], 64)
+}
+
+// normalizeCSVColumn makes header names comparable
+func normalizeCSVColumn(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+}
+
+// ParseCSVRecord parses a single CSV record in CSVColumns order into TelemetryData
+func ParseCSVRecord(record []string) (TelemetryData, error) {
+	return positionalSchema.Parse(record)
+}
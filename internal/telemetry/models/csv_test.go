@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVSchema_MapsColumnsByHeader(t *testing.T) {
+	header := []string{"Temperature_C", "node", "site", "timestamp", "latency_ms", "switch_id", "bandwidth_mbps", "packet_errors", "utilization_pct"}
+	_, err := NewCSVSchema(header, map[string]string{"node": "switch_id"}, nil)
+	assert.ErrorContains(t, err, "maps both")
+
+	schema, err := NewCSVSchema(header[:5], map[string]string{"NODE": "switch_id"}, []string{"bandwidth_mbps", "Packet_Errors", "utilization_pct"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"site"}, schema.Unknown())
+	assert.Equal(t, []string{"bandwidth_mbps", "packet_errors", "utilization_pct"}, schema.Missing())
+
+	data, err := schema.Parse([]string{"47.5", "switch-001", "east", "2025-08-02T12:00:00Z", "1.2"})
+	require.NoError(t, err)
+	assert.Equal(t, "switch-001", data.SwitchID)
+	assert.Equal(t, time.Date(2025, 8, 2, 12, 0, 0, 0, time.UTC), data.Timestamp)
+	assert.Equal(t, 47.5, data.TemperatureC)
+	assert.Equal(t, 1.2, data.LatencyMs)
+	assert.Zero(t, data.BandwidthMbps)
+
+	_, err = schema.Parse([]string{"47.5", "switch-001"})
+	assert.ErrorContains(t, err, "insufficient columns")
+}
+
+func TestCSVSchema_RejectsMissingRequiredColumns(t *testing.T) {
+	header := []string{"switch", "bandwidth_mbps", "latency_ms"}
+	_, err := NewCSVSchema(header, nil, []string{"packet_errors", "utilization_pct", "temperature_c"})
+	assert.ErrorIs(t, err, ErrMissingCSVColumns)
+	assert.ErrorContains(t, err, "switch_id, timestamp")
+
+	// Metric columns are required unless listed as optional
+	_, err = NewCSVSchema(header, map[string]string{"switch": "switch_id"}, []string{"timestamp", "packet_errors"})
+	assert.ErrorContains(t, err, `optional column "timestamp" is not a metric column`)
+	_, err = NewCSVSchema(append(header, "timestamp"), map[string]string{"switch": "switch_id"}, []string{"packet_errors"})
+	assert.ErrorIs(t, err, ErrMissingCSVColumns)
+	assert.ErrorContains(t, err, "utilization_pct, temperature_c")
+}
+
+func TestValidateCSVAliases(t *testing.T) {
+	assert.NoError(t, ValidateCSVAliases(map[string]string{"guid": "Switch_ID", "temp": "temperature_c"}))
+	assert.Error(t, ValidateCSVAliases(map[string]string{"guid": "node_guid"}))
+	assert.ErrorContains(t, ValidateCSVAliases(map[string]string{" ": "switch_id"}), "has no name")
+	assert.ErrorContains(t, ValidateCSVAliases(map[string]string{"Temp": "temperature_c", "temp": "latency_ms"}), "name the same column")
+}
+
+func TestValidateCSVOptionalColumns(t *testing.T) {
+	assert.NoError(t, ValidateCSVOptionalColumns([]string{"Latency_MS", "packet_errors"}))
+	assert.Error(t, ValidateCSVOptionalColumns([]string{"switch_id"}))
+	assert.Error(t, ValidateCSVOptionalColumns([]string{"fan_rpm"}))
+}
+
+func TestParseCSVRecord_Positional(t *testing.T) {
+	data, err := ParseCSVRecord([]string{"switch-001", "2025-08-02T12:00:00Z", "950", "1.2", "3", "40", "44"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), data.PacketErrors)
+	assert.Equal(t, 44.0, data.TemperatureC)
+
+	_, err = ParseCSVRecord([]string{"switch-001", "2025-08-02T12:00:00Z"})
+	assert.ErrorContains(t, err, "insufficient columns")
+}
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AllMetricTypes lists every supported metric type in display order
+var AllMetricTypes = []MetricType{
+	MetricBandwidth,
+	MetricLatency,
+	MetricPacketErrors,
+	MetricUtilization,
+	MetricTemperature,
+}
+
+// IsValid reports whether the metric type is supported
+func (mt MetricType) IsValid() bool {
+	for _, validType := range AllMetricTypes {
+		if mt == validType {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMetricFloat returns the value of a specific metric type as float64
+func (td *TelemetryData) GetMetricFloat(metricType MetricType) (float64, error) {
+	switch metricType {
+	case MetricBandwidth:
+		return td.BandwidthMbps, nil
+	case MetricLatency:
+		return td.LatencyMs, nil
+	case MetricPacketErrors:
+		return float64(td.PacketErrors), nil
+	case MetricUtilization:
+		return td.UtilizationPct, nil
+	case MetricTemperature:
+		return td.TemperatureC, nil
+	default:
+		return 0, fmt.Errorf("unknown metric type: %s", metricType)
+	}
+}
+
+// HistoryCursor marks the position of the last row returned by a history page.
+// Rows are ordered by (timestamp, id) so the pair is unique and stable.
+type HistoryCursor struct {
+	Timestamp time.Time
+	ID        int64
+}
+
+// Encode returns the opaque string form of the cursor
+func (hc HistoryCursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", hc.Timestamp.UnixNano(), hc.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseHistoryCursor decodes a cursor previously produced by HistoryCursor.Encode
+func ParseHistoryCursor(s string) (*HistoryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor encoding: %w", err)
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid cursor format")
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor timestamp: %w", err)
+	}
+
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor id: %w", err)
+	}
+
+	return &HistoryCursor{
+		Timestamp: time.Unix(0, nanos).UTC(),
+		ID:        id,
+	}, nil
+}
+
+// Aggregation is the function used to reduce the samples of a downsampling window
+type Aggregation string
+
+const (
+	AggregationAvg  Aggregation = "avg"
+	AggregationMin  Aggregation = "min"
+	AggregationMax  Aggregation = "max"
+	AggregationP95  Aggregation = "p95"
+	AggregationSum  Aggregation = "sum"
+	AggregationLast Aggregation = "last"
+)
+
+// ParseAggregation validates an aggregation name
+func ParseAggregation(s string) (Aggregation, error) {
+	switch agg := Aggregation(s); agg {
+	case AggregationAvg, AggregationMin, AggregationMax, AggregationP95, AggregationSum, AggregationLast:
+		return agg, nil
+	default:
+		return "", fmt.Errorf("unknown aggregation: %s (expected avg, min, max, p95, sum or last)", s)
+	}
+}
+
+// HistoryQuery describes a time-range query against persisted telemetry.
+// When Step is set the samples are downsampled into windows of that size
+// using Aggregation, and Limit/After are ignored.
+type HistoryQuery struct {
+	SwitchID    string
+	From        time.Time
+	To          time.Time
+	Metrics     []MetricType   // Empty means all metric types
+	Limit       int            // Maximum number of rows per page
+	After       *HistoryCursor // Resume after this row, nil for the first page
+	Step        time.Duration  // Downsampling window, zero for raw rows
+	Aggregation Aggregation    // Reducer applied within each window
+}
+
+// MetricBucket holds the aggregated values of one downsampling window
+type MetricBucket struct {
+	Start  time.Time
+	Values map[MetricType]float64
+}
+
+// HistoryPage is a single page of raw telemetry rows in ascending time order
+type HistoryPage struct {
+	Data       []TelemetryData
+	NextCursor *HistoryCursor // nil when there are no more rows
+}
+
+// DataPoint is a single timestamped value of a metric series
+type DataPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// BucketPoint is one downsampling window of a metric series, identified by its start.
+// Value is nil for windows that contain no samples.
+type BucketPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     *float64  `json:"value"`
+}
+
+// MetricHistoryResponse represents historical series for a switch, keyed by metric type.
+// Raw queries fill Series and downsampled queries (Step set) fill Buckets.
+type MetricHistoryResponse struct {
+	SwitchID    string                   `json:"switch_id"`
+	From        time.Time                `json:"from"`
+	To          time.Time                `json:"to"`
+	Step        string                   `json:"step,omitempty"`
+	Aggregation string                   `json:"aggregation,omitempty"`
+	Series      map[string][]DataPoint   `json:"series,omitempty"`
+	Buckets     map[string][]BucketPoint `json:"buckets,omitempty"`
+	Count       int                      `json:"count"`
+	NextCursor  string                   `json:"next_cursor,omitempty"`
+}
+
+// MetricSummary holds distribution statistics of one metric over a window.
+// Fields are nil when the window contains no samples.
+type MetricSummary struct {
+	Avg *float64 `json:"avg"`
+	Max *float64 `json:"max"`
+	P50 *float64 `json:"p50"`
+	P99 *float64 `json:"p99"`
+}
+
+// SwitchSummary represents aggregated statistics for a switch over a trailing window
+type SwitchSummary struct {
+	SwitchID    string                   `json:"switch_id"`
+	Window      string                   `json:"window"`
+	SampleCount int64                    `json:"sample_count"`
+	Metrics     map[string]MetricSummary `json:"metrics"`
+	Timestamp   time.Time                `json:"timestamp"`
+}
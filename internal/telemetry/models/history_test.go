@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryCursor_RoundTrip(t *testing.T) {
+	cursor := HistoryCursor{
+		Timestamp: time.Date(2025, 8, 2, 12, 0, 0, 123456789, time.UTC),
+		ID:        42,
+	}
+
+	parsed, err := ParseHistoryCursor(cursor.Encode())
+	assert.NoError(t, err)
+	assert.True(t, cursor.Timestamp.Equal(parsed.Timestamp))
+	assert.Equal(t, cursor.ID, parsed.ID)
+}
+
+func TestParseHistoryCursor_Invalid(t *testing.T) {
+	for _, value := range []string{"not-base64!", "bm9jb2xvbg", "YWJjOjEyMw"} {
+		_, err := ParseHistoryCursor(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestTelemetryData_GetMetricFloat(t *testing.T) {
+	data := TelemetryData{PacketErrors: 7, TemperatureC: 45.5}
+
+	value, err := data.GetMetricFloat(MetricPacketErrors)
+	assert.NoError(t, err)
+	assert.Equal(t, 7.0, value)
+
+	value, err = data.GetMetricFloat(MetricTemperature)
+	assert.NoError(t, err)
+	assert.Equal(t, 45.5, value)
+
+	_, err = data.GetMetricFloat("unknown")
+	assert.Error(t, err)
+}
+
+func TestMetricType_IsValid(t *testing.T) {
+	for _, metricType := range AllMetricTypes {
+		assert.True(t, metricType.IsValid())
+	}
+	assert.False(t, MetricType("unknown").IsValid())
+}
+
+func TestParseAggregation(t *testing.T) {
+	for _, name := range []string{"avg", "min", "max", "p95", "sum", "last"} {
+		agg, err := ParseAggregation(name)
+		assert.NoError(t, err)
+		assert.Equal(t, Aggregation(name), agg)
+	}
+
+	_, err := ParseAggregation("median")
+	assert.Error(t, err)
+}
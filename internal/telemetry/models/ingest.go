@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Ingest row statuses
+const (
+	IngestStatusAccepted = "accepted"
+	IngestStatusRejected = "rejected"
+)
+
+// IngestRowResult reports whether a single pushed sample was accepted
+type IngestRowResult struct {
+	Row      int    `json:"row"` // 1-based position of the sample in the request body, excluding any CSV header
+	SwitchID string `json:"switch_id,omitempty"`
+	Status   string `json:"status"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// IngestResponse represents the outcome of a push ingestion request
+type IngestResponse struct {
+	Accepted  int               `json:"accepted"`
+	Rejected  int               `json:"rejected"`
+	Results   []IngestRowResult `json:"results"`
+	Timestamp time.Time         `json:"timestamp"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// GroupByLocation groups fabric statistics by Switch.Location
+const GroupByLocation = "location"
+
+// MetricStatistics describes the distribution of one metric across switches
+type MetricStatistics struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	StdDev float64 `json:"stddev"`
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+}
+
+// FleetStatistics holds per-metric statistics for a set of switches
+type FleetStatistics struct {
+	SwitchCount int                         `json:"switch_count"`
+	Metrics     map[string]MetricStatistics `json:"metrics"`
+}
+
+// FabricStatisticsResponse represents fabric-wide statistics, optionally broken down by group
+type FabricStatisticsResponse struct {
+	FleetStatistics
+	GroupBy   string                     `json:"group_by,omitempty"`
+	Groups    map[string]FleetStatistics `json:"groups,omitempty"`
+	Timestamp time.Time                  `json:"timestamp"`
+}
+
+// RankedSwitch is a switch together with the metric value it was ranked by
+type RankedSwitch struct {
+	SwitchID  string    `json:"switch_id"`
+	Value     float64   `json:"value"`
+	Timestamp time.Time `json:"timestamp,omitempty"`
+}
+
+// TopSwitchesQuery selects the K highest or lowest switches for a metric.
+// A zero Window ranks the latest cached samples; otherwise samples in the
+// trailing window are reduced per switch with Aggregation.
+type TopSwitchesQuery struct {
+	Metric      MetricType
+	K           int
+	Descending  bool
+	Window      time.Duration
+	Aggregation Aggregation
+}
+
+// TopSwitchesResponse represents a ranked list of switches for a metric
+type TopSwitchesResponse struct {
+	Metric      string         `json:"metric"`
+	Order       string         `json:"order"`
+	K           int            `json:"k"`
+	Window      string         `json:"window,omitempty"`
+	Aggregation string         `json:"aggregation,omitempty"`
+	Switches    []RankedSwitch `json:"switches"`
+	Count       int            `json:"count"`
+	Timestamp   time.Time      `json:"timestamp"`
+}
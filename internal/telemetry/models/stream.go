@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// StreamEvent is a single switch update delivered to stream subscribers
+type StreamEvent struct {
+	Seq       uint64             `json:"seq"`
+	SwitchID  string             `json:"switch_id"`
+	Timestamp time.Time          `json:"timestamp"`
+	Metrics   map[string]float64 `json:"metrics"`
+}
+
+// StreamDropped tells a subscriber that events were dropped because it fell behind
+type StreamDropped struct {
+	Dropped uint64 `json:"dropped"`
+}
+
+// StreamMessage frames WebSocket messages; Type is "metrics" with a StreamEvent or "dropped" with a StreamDropped
+type StreamMessage struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
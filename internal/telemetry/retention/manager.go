@@ -0,0 +1,301 @@
+// Package retention bounds the size of stored telemetry. It periodically rolls
+// raw samples into minute and hour rollups and deletes data past each tier's
+// retention in bounded batches.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/monitoring/metrics"
+	"github.com/ufm/internal/telemetry/storage"
+)
+
+const (
+	// DefaultInterval is how often retention runs when no interval is configured
+	DefaultInterval = 5 * time.Minute
+	// DefaultBatchSize is the number of rows deleted per statement when none is configured
+	DefaultBatchSize = 10000
+	// DefaultRollupDelay leaves recent buckets open for late samples
+	DefaultRollupDelay = 2 * time.Minute
+
+	// rollupChunkBuckets bounds how many buckets per switch a single rollup statement covers
+	rollupChunkBuckets = 60
+)
+
+// Config controls how long each tier is kept. A zero retention keeps the tier forever.
+type Config struct {
+	RawRetention    time.Duration
+	MinuteRetention time.Duration
+	HourRetention   time.Duration
+	Interval        time.Duration
+	BatchSize       int
+	RollupDelay     time.Duration
+}
+
+// Manager applies retention to a telemetry repository. Repositories that implement
+// storage.RollupStore get rollups and batched pruning of every tier; any other
+// repository only has raw rows past RawRetention deleted.
+type Manager struct {
+	repo    storage.TelemetryRepository
+	rollups storage.RollupStore // nil when the repository keeps no rollups
+	config  Config
+	logger  log.Logger
+	now     func() time.Time
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a retention manager for repo
+func NewManager(repo storage.TelemetryRepository, config Config, logger log.Logger) (*Manager, error) {
+	if logger == nil {
+		logger = log.DefaultLogger
+	}
+	if config.RawRetention < 0 || config.MinuteRetention < 0 || config.HourRetention < 0 {
+		return nil, fmt.Errorf("retention periods must not be negative")
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.RollupDelay <= 0 {
+		config.RollupDelay = DefaultRollupDelay
+	}
+
+	m := &Manager{
+		repo:   repo,
+		config: config,
+		logger: logger,
+		now:    time.Now,
+	}
+	if rollups, ok := repo.(storage.RollupStore); ok {
+		m.rollups = rollups
+	}
+	return m, nil
+}
+
+// Start runs retention immediately and then every configured interval
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel != nil {
+		return fmt.Errorf("retention manager already started")
+	}
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.wg.Add(1)
+	go m.worker(ctx)
+
+	m.logger.Infof("Retention manager started (raw %s, 1m %s, 1h %s, every %s)",
+		describe(m.config.RawRetention), describe(m.config.MinuteRetention),
+		describe(m.config.HourRetention), m.config.Interval)
+	return nil
+}
+
+// Stop cancels a run in progress and waits for the worker to exit
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	if m.cancel == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("retention manager not started")
+	}
+	m.cancel()
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		m.logger.Infof("Retention manager stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunOnce performs one retention pass: rollups first, then pruning, so raw rows
+// are never deleted before they have been rolled up
+func (m *Manager) RunOnce(ctx context.Context) error {
+	if m.rollups == nil {
+		return m.pruneWithoutRollups(ctx)
+	}
+
+	now := m.now()
+	minuteDone, err := m.rollup(ctx, storage.MinuteTier, now.Add(-m.config.RollupDelay))
+	if err != nil {
+		return err
+	}
+	hourDone, err := m.rollup(ctx, storage.HourTier, minuteDone)
+	if err != nil {
+		return err
+	}
+
+	// A tier may only be pruned up to where the next tier has caught up
+	if err := m.prune(ctx, storage.RawTier, m.cutoff(now, m.config.RawRetention, minuteDone)); err != nil {
+		return err
+	}
+	if err := m.prune(ctx, storage.MinuteTier, m.cutoff(now, m.config.MinuteRetention, hourDone)); err != nil {
+		return err
+	}
+	return m.prune(ctx, storage.HourTier, m.cutoff(now, m.config.HourRetention, time.Time{}))
+}
+
+func (m *Manager) worker(ctx context.Context) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			m.logger.Errorf("Retention run failed: %v", err)
+			metrics.ErrorsTotal.WithLabelValues("retention", "run").Inc()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// rollup fills the tier with every complete bucket that ends before until and
+// returns the time up to which the tier is complete
+func (m *Manager) rollup(ctx context.Context, tier storage.RollupTier, until time.Time) (time.Time, error) {
+	step := tier.Step()
+	end := until.Truncate(step)
+
+	latest, err := m.rollups.LatestRollup(ctx, tier)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	start := latest.Add(step)
+	if latest.IsZero() {
+		earliest, err := m.rollups.EarliestMetric(ctx, tier.Source())
+		if err != nil {
+			return time.Time{}, err
+		}
+		if earliest.IsZero() {
+			// Nothing to roll up yet, and nothing in the source to protect
+			return end, nil
+		}
+		start = earliest.Truncate(step)
+	}
+
+	var written int64
+	for from := start; from.Before(end); {
+		to := from.Add(rollupChunkBuckets * step)
+		if to.After(end) {
+			to = end
+		}
+
+		count, err := m.rollups.RollupMetrics(ctx, tier, from, to)
+		if err != nil {
+			return from, err
+		}
+		written += count
+		from = to
+	}
+
+	if written > 0 {
+		metrics.RetentionRollupBucketsTotal.WithLabelValues(string(tier)).Add(float64(written))
+		m.logger.Debugf("Rolled up %d %s buckets", written, tier)
+	}
+
+	// Buckets before start were rolled up by earlier runs
+	if start.After(end) {
+		return start, nil
+	}
+	return end, nil
+}
+
+// cutoff returns the prune horizon for a retention period, capped at rolledUntil
+// when it is set. Zero means nothing may be pruned.
+func (m *Manager) cutoff(now time.Time, retention time.Duration, rolledUntil time.Time) time.Time {
+	if retention == 0 {
+		return time.Time{}
+	}
+	cutoff := now.Add(-retention)
+	if !rolledUntil.IsZero() && rolledUntil.Before(cutoff) {
+		return rolledUntil
+	}
+	return cutoff
+}
+
+// prune deletes rows older than olderThan from the tier one batch at a time
+func (m *Manager) prune(ctx context.Context, tier storage.RollupTier, olderThan time.Time) error {
+	if olderThan.IsZero() {
+		return nil
+	}
+
+	var total int64
+	defer func() {
+		if total > 0 {
+			m.logger.Infof("Pruned %d %s telemetry rows older than %s", total, tier, olderThan.Format(time.RFC3339))
+		}
+	}()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		deleted, err := m.rollups.PruneMetrics(ctx, tier, olderThan, m.config.BatchSize)
+		if err != nil {
+			return err
+		}
+		total += deleted
+		metrics.RetentionRowsPrunedTotal.WithLabelValues(string(tier)).Add(float64(deleted))
+
+		if deleted < int64(m.config.BatchSize) {
+			return nil
+		}
+	}
+}
+
+// pruneWithoutRollups applies raw retention through DeleteOldMetrics for
+// repositories that keep no rollups
+func (m *Manager) pruneWithoutRollups(ctx context.Context) error {
+	if m.config.RawRetention == 0 {
+		return nil
+	}
+
+	before, err := m.repo.GetMetricsCount(ctx)
+	if err != nil {
+		return err
+	}
+	if err := m.repo.DeleteOldMetrics(ctx, m.now().Add(-m.config.RawRetention)); err != nil {
+		return err
+	}
+	after, err := m.repo.GetMetricsCount(ctx)
+	if err != nil {
+		return err
+	}
+
+	if pruned := before - after; pruned > 0 {
+		metrics.RetentionRowsPrunedTotal.WithLabelValues(string(storage.RawTier)).Add(float64(pruned))
+		m.logger.Infof("Pruned %d raw telemetry rows", pruned)
+	}
+	return nil
+}
+
+// describe formats a retention period for logs
+func describe(retention time.Duration) string {
+	if retention == 0 {
+		return "forever"
+	}
+	return retention.String()
+}
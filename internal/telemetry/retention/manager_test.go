@@ -0,0 +1,199 @@
+package retention
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ufm/internal/telemetry/models"
+	"github.com/ufm/internal/telemetry/storage"
+)
+
+// fakeRollupStore keeps the timestamps of each tier and records rollup ranges
+type fakeRollupStore struct {
+	*storage.MemoryRepository
+
+	mu      sync.Mutex
+	tiers   map[storage.RollupTier][]time.Time
+	rollups map[storage.RollupTier][][2]time.Time
+	prunes  map[storage.RollupTier]int
+}
+
+func newFakeRollupStore(raw ...time.Time) *fakeRollupStore {
+	return &fakeRollupStore{
+		MemoryRepository: storage.NewMemoryRepository(0),
+		tiers:            map[storage.RollupTier][]time.Time{storage.RawTier: raw},
+		rollups:          make(map[storage.RollupTier][][2]time.Time),
+		prunes:           make(map[storage.RollupTier]int),
+	}
+}
+
+func (s *fakeRollupStore) RollupMetrics(ctx context.Context, tier storage.RollupTier, from, to time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rollups[tier] = append(s.rollups[tier], [2]time.Time{from, to})
+	buckets := make(map[time.Time]bool)
+	for _, t := range s.tiers[tier.Source()] {
+		if !t.Before(from) && t.Before(to) {
+			buckets[t.Truncate(tier.Step())] = true
+		}
+	}
+	for bucket := range buckets {
+		s.tiers[tier] = append(s.tiers[tier], bucket)
+	}
+	return int64(len(buckets)), nil
+}
+
+func (s *fakeRollupStore) LatestRollup(ctx context.Context, tier storage.RollupTier) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var latest time.Time
+	for _, t := range s.tiers[tier] {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest, nil
+}
+
+func (s *fakeRollupStore) EarliestMetric(ctx context.Context, tier storage.RollupTier) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var earliest time.Time
+	for _, t := range s.tiers[tier] {
+		if earliest.IsZero() || t.Before(earliest) {
+			earliest = t
+		}
+	}
+	return earliest, nil
+}
+
+func (s *fakeRollupStore) PruneMetrics(ctx context.Context, tier storage.RollupTier, olderThan time.Time, limit int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prunes[tier]++
+	sort.Slice(s.tiers[tier], func(i, j int) bool { return s.tiers[tier][i].Before(s.tiers[tier][j]) })
+
+	var deleted int64
+	kept := s.tiers[tier][:0]
+	for _, t := range s.tiers[tier] {
+		if t.Before(olderThan) && deleted < int64(limit) {
+			deleted++
+			continue
+		}
+		kept = append(kept, t)
+	}
+	s.tiers[tier] = kept
+	return deleted, nil
+}
+
+func (s *fakeRollupStore) count(tier storage.RollupTier) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tiers[tier])
+}
+
+func TestManager_RollsUpBeforePruning(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 30, 0, time.UTC)
+
+	// One raw sample per minute over the last three hours
+	var raw []time.Time
+	for ts := now.Add(-3 * time.Hour); ts.Before(now); ts = ts.Add(time.Minute) {
+		raw = append(raw, ts)
+	}
+	store := newFakeRollupStore(raw...)
+
+	manager, err := NewManager(store, Config{
+		RawRetention:    time.Hour,
+		MinuteRetention: 2 * time.Hour,
+		BatchSize:       7,
+		RollupDelay:     2 * time.Minute,
+	}, nil)
+	require.NoError(t, err)
+	manager.now = func() time.Time { return now }
+
+	require.NoError(t, manager.RunOnce(context.Background()))
+
+	// Raw rows older than an hour are gone, pruned in several batches
+	assert.Equal(t, 60, store.count(storage.RawTier))
+	assert.Greater(t, store.prunes[storage.RawTier], 1)
+
+	// Hour buckets cover the two complete hours
+	assert.Equal(t, 2, store.count(storage.HourTier))
+
+	// Minute buckets were written up to now - delay, then pruned past their retention
+	assert.Equal(t, 3*60-2-61, store.count(storage.MinuteTier))
+	for _, bucket := range store.tiers[storage.MinuteTier] {
+		assert.False(t, bucket.Before(now.Add(-2*time.Hour)), "minute bucket %s should have been pruned", bucket)
+	}
+
+	// A second run only rolls up what arrived since
+	rollupsBefore := len(store.rollups[storage.MinuteTier])
+	now = now.Add(time.Minute)
+	require.NoError(t, manager.RunOnce(context.Background()))
+	require.Len(t, store.rollups[storage.MinuteTier], rollupsBefore+1)
+	last := store.rollups[storage.MinuteTier][rollupsBefore]
+	assert.Equal(t, time.Minute, last[1].Sub(last[0]))
+}
+
+func TestManager_NeverPrunesAheadOfRollups(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	store := newFakeRollupStore(now.Add(-10*time.Minute), now.Add(-time.Minute))
+
+	// A rollup delay longer than raw retention must not lose unrolled samples
+	manager, err := NewManager(store, Config{
+		RawRetention: time.Minute,
+		RollupDelay:  15 * time.Minute,
+	}, nil)
+	require.NoError(t, err)
+	manager.now = func() time.Time { return now }
+
+	require.NoError(t, manager.RunOnce(context.Background()))
+	assert.Equal(t, 2, store.count(storage.RawTier))
+	assert.Equal(t, 0, store.count(storage.MinuteTier))
+}
+
+func TestManager_ZeroRetentionKeepsData(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	store := newFakeRollupStore(now.Add(-48*time.Hour), now.Add(-time.Hour))
+
+	manager, err := NewManager(store, Config{}, nil)
+	require.NoError(t, err)
+	manager.now = func() time.Time { return now }
+
+	require.NoError(t, manager.RunOnce(context.Background()))
+	assert.Equal(t, 2, store.count(storage.RawTier))
+	assert.Zero(t, store.prunes[storage.RawTier])
+}
+
+func TestManager_WithoutRollupsDeletesOldMetrics(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewMemoryRepository(0)
+	require.NoError(t, repo.CreateSwitch(ctx, models.Switch{ID: "sw-1", Name: "Switch 1"}))
+	require.NoError(t, repo.StoreMetrics(ctx, []models.TelemetryData{
+		{SwitchID: "sw-1", Timestamp: time.Now().Add(-2 * time.Hour), CreatedAt: time.Now().Add(-2 * time.Hour)},
+		{SwitchID: "sw-1", Timestamp: time.Now(), CreatedAt: time.Now()},
+	}))
+
+	manager, err := NewManager(repo, Config{RawRetention: time.Hour}, nil)
+	require.NoError(t, err)
+	require.NoError(t, manager.RunOnce(ctx))
+
+	count, err := repo.GetMetricsCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestNewManager_RejectsNegativeRetention(t *testing.T) {
+	_, err := NewManager(storage.NewMemoryRepository(0), Config{RawRetention: -time.Hour}, nil)
+	assert.Error(t, err)
+}
@@ -2,13 +2,18 @@ package telemetry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/ufm/internal/log"
 	"github.com/ufm/internal/monitoring/metrics"
+	"github.com/ufm/internal/telemetry/alerting"
+	"github.com/ufm/internal/telemetry/anomaly"
 	"github.com/ufm/internal/telemetry/models"
+	"github.com/ufm/internal/telemetry/retention"
 	"github.com/ufm/internal/telemetry/storage"
+	"github.com/ufm/internal/telemetry/stream"
 )
 
 // TelemetryService defines the main business logic interface
@@ -16,11 +21,26 @@ type TelemetryService interface {
 	// Core operations
 	IngestMetrics(data models.TelemetryData) error
 	IngestBatch(data []models.TelemetryData) error
+	ValidateMetricData(data models.TelemetryData) error
 
 	// Query operations
 	GetMetric(switchID string, metricType models.MetricType) (*models.MetricResponse, error)
 	GetSwitchMetrics(switchID string) (*models.MetricsListResponse, error)
 	GetAllMetrics() (*models.AllMetricsResponse, error)
+	GetMetricHistory(query models.HistoryQuery) (*models.MetricHistoryResponse, error)
+	GetSwitchSummary(switchID string, window time.Duration) (*models.SwitchSummary, error)
+	GetFabricStatistics(groupBy string) (*models.FabricStatisticsResponse, error)
+	GetTopSwitches(query models.TopSwitchesQuery) (*models.TopSwitchesResponse, error)
+
+	// Alerting
+	GetActiveAlerts(switchID string, state models.AlertState) (*models.ActiveAlertsResponse, error)
+	GetAlertHistory(query models.AlertHistoryQuery) (*models.AlertHistoryResponse, error)
+
+	// Anomaly detection
+	GetAnomalies(query models.AnomalyQuery) (*models.AnomaliesResponse, error)
+
+	// Streaming
+	Subscribe(filter stream.Filter) (*stream.Subscription, error)
 
 	// Management operations
 	RegisterSwitch(sw models.Switch) error
@@ -35,24 +55,92 @@ type TelemetryService interface {
 	Stop(ctx context.Context) error
 }
 
+// ErrAlertingDisabled is returned by alert queries when no alerting engine is configured
+var ErrAlertingDisabled = errors.New("alerting is not enabled")
+
+// ErrAnomalyDetectionDisabled is returned by anomaly queries when no detector is configured
+var ErrAnomalyDetectionDisabled = errors.New("anomaly detection is not enabled")
+
+// ErrStreamingDisabled is returned by Subscribe when no stream hub is configured
+var ErrStreamingDisabled = errors.New("streaming is not enabled")
+
+// ErrInvalidQuery is returned by history queries that cannot be answered as asked,
+// such as an inverted time range or an unknown metric
+var ErrInvalidQuery = errors.New("invalid query")
+
 // telemetryService implements the TelemetryService interface
 type telemetryService struct {
 	store     storage.TelemetryStore
+	alerts    *alerting.Engine
+	anomalies *anomaly.Detector
+	hub       *stream.Hub
+	retention *retention.Manager
+	database  *storage.DatabaseMonitor
+	breaker   *storage.CircuitBreakerRepository
 	logger    log.Logger
 	startTime time.Time
 }
 
+// ServiceOption configures optional telemetry service components
+type ServiceOption func(*telemetryService)
+
+// WithAlertEngine evaluates alert rules against every ingested sample
+func WithAlertEngine(engine *alerting.Engine) ServiceOption {
+	return func(s *telemetryService) {
+		s.alerts = engine
+	}
+}
+
+// WithAnomalyDetector scores every ingested sample against its switch's rolling baseline
+func WithAnomalyDetector(detector *anomaly.Detector) ServiceOption {
+	return func(s *telemetryService) {
+		s.anomalies = detector
+	}
+}
+
+// WithStreamHub lets clients subscribe to updates published by the store to hub
+func WithStreamHub(hub *stream.Hub) ServiceOption {
+	return func(s *telemetryService) {
+		s.hub = hub
+	}
+}
+
+// WithRetentionManager rolls up and prunes stored telemetry while the service runs
+func WithRetentionManager(manager *retention.Manager) ServiceOption {
+	return func(s *telemetryService) {
+		s.retention = manager
+	}
+}
+
+// WithDatabaseMonitor checks the database while the service runs and reports its state in the health status
+func WithDatabaseMonitor(monitor *storage.DatabaseMonitor) ServiceOption {
+	return func(s *telemetryService) {
+		s.database = monitor
+	}
+}
+
+// WithCircuitBreaker reports the state of the database circuit breaker in the health status
+func WithCircuitBreaker(breaker *storage.CircuitBreakerRepository) ServiceOption {
+	return func(s *telemetryService) {
+		s.breaker = breaker
+	}
+}
+
 // NewTelemetryService creates a new telemetry service instance
-func NewTelemetryService(store storage.TelemetryStore, logger log.Logger) TelemetryService {
+func NewTelemetryService(store storage.TelemetryStore, logger log.Logger, options ...ServiceOption) TelemetryService {
 	if logger == nil {
 		logger = log.DefaultLogger
 	}
 
-	return &telemetryService{
+	s := &telemetryService{
 		store:     store,
 		logger:    logger,
 		startTime: time.Now(),
 	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
 }
 
 // IngestMetrics ingests a single telemetry data point
@@ -82,6 +170,13 @@ func (s *telemetryService) IngestMetrics(data models.TelemetryData) error {
 
 	metrics.TelemetryIngestDuration.WithLabelValues(data.SwitchID, "all_metrics").Observe(duration)
 
+	if s.alerts != nil {
+		s.alerts.Evaluate([]models.TelemetryData{data})
+	}
+	if s.anomalies != nil {
+		s.anomalies.Observe([]models.TelemetryData{data})
+	}
+
 	s.logger.Debugf("Ingested metrics for switch %s", data.SwitchID)
 	return nil
 }
@@ -113,6 +208,13 @@ func (s *telemetryService) IngestBatch(data []models.TelemetryData) error {
 		return fmt.Errorf("failed to ingest batch metrics: %w", err)
 	}
 
+	if s.alerts != nil {
+		s.alerts.Evaluate(validData)
+	}
+	if s.anomalies != nil {
+		s.anomalies.Observe(validData)
+	}
+
 	s.logger.Debugf("Ingested batch of %d metrics", len(validData))
 	return nil
 }
@@ -201,6 +303,331 @@ func (s *telemetryService) GetAllMetrics() (*models.AllMetricsResponse, error) {
 	return response, nil
 }
 
+// GetMetricHistory retrieves persisted metrics for a switch over a time range
+func (s *telemetryService) GetMetricHistory(query models.HistoryQuery) (*models.MetricHistoryResponse, error) {
+	start := time.Now()
+
+	if query.SwitchID == "" {
+		metrics.ErrorsTotal.WithLabelValues("telemetry_service", "empty_switch_id").Inc()
+		return nil, fmt.Errorf("%w: switchID cannot be empty", ErrInvalidQuery)
+	}
+
+	if query.To.Before(query.From) {
+		return nil, fmt.Errorf("%w: from %s is after to %s", ErrInvalidQuery,
+			query.From.Format(time.RFC3339), query.To.Format(time.RFC3339))
+	}
+
+	metricTypes := query.Metrics
+	if len(metricTypes) == 0 {
+		metricTypes = models.AllMetricTypes
+	}
+	for _, metricType := range metricTypes {
+		if !metricType.IsValid() {
+			return nil, fmt.Errorf("%w: unknown metric type %s", ErrInvalidQuery, metricType)
+		}
+	}
+	if query.Step < 0 {
+		return nil, fmt.Errorf("%w: step must be positive", ErrInvalidQuery)
+	}
+	if query.Aggregation != "" {
+		if _, err := models.ParseAggregation(string(query.Aggregation)); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+		}
+	}
+
+	response := &models.MetricHistoryResponse{
+		SwitchID: query.SwitchID,
+		From:     query.From,
+		To:       query.To,
+	}
+
+	var err error
+	if query.Step > 0 {
+		err = s.fillAggregatedHistory(response, query, metricTypes)
+	} else {
+		err = s.fillRawHistory(response, query, metricTypes)
+	}
+	if err != nil {
+		metrics.ErrorsTotal.WithLabelValues("telemetry_service", "history_query_error").Inc()
+		s.logger.Errorf("Failed to get metric history for switch %s: %v", query.SwitchID, err)
+		return nil, fmt.Errorf("failed to get metric history for switch %s: %w", query.SwitchID, err)
+	}
+
+	duration := time.Since(start).Seconds()
+	metrics.TelemetryQueryTotal.WithLabelValues(query.SwitchID, "history", "success").Inc()
+	metrics.TelemetryQueryDuration.WithLabelValues(query.SwitchID, "history").Observe(duration)
+
+	return response, nil
+}
+
+// fillRawHistory pivots one page of raw rows into a series per metric type
+func (s *telemetryService) fillRawHistory(response *models.MetricHistoryResponse, query models.HistoryQuery, metricTypes []models.MetricType) error {
+	page, err := s.store.GetHistoricalMetrics(context.Background(), query)
+	if err != nil {
+		return err
+	}
+
+	response.Series = make(map[string][]models.DataPoint, len(metricTypes))
+	for _, metricType := range metricTypes {
+		points := make([]models.DataPoint, 0, len(page.Data))
+		for i := range page.Data {
+			value, err := page.Data[i].GetMetricFloat(metricType)
+			if err != nil {
+				return err
+			}
+			points = append(points, models.DataPoint{Timestamp: page.Data[i].Timestamp, Value: value})
+		}
+		response.Series[string(metricType)] = points
+	}
+
+	response.Count = len(page.Data)
+	if page.NextCursor != nil {
+		response.NextCursor = page.NextCursor.Encode()
+	}
+	return nil
+}
+
+// fillAggregatedHistory downsamples into evenly spaced windows starting at query.From.
+// Windows without samples are emitted with a nil value so every metric has the same number of buckets.
+func (s *telemetryService) fillAggregatedHistory(response *models.MetricHistoryResponse, query models.HistoryQuery, metricTypes []models.MetricType) error {
+	// PostgreSQL keeps microseconds, so windows aligned to a nanosecond From would never line up
+	query.From = query.From.Truncate(time.Microsecond)
+	query.To = query.To.Truncate(time.Microsecond)
+
+	bucketCount := int(query.To.Sub(query.From)/query.Step) + 1
+	if bucketCount > storage.MaxHistoryBuckets {
+		return fmt.Errorf("%w: step %s yields %d windows, maximum is %d", ErrInvalidQuery, query.Step, bucketCount, storage.MaxHistoryBuckets)
+	}
+
+	if query.Aggregation == "" {
+		query.Aggregation = models.AggregationAvg
+	}
+
+	buckets, err := s.store.GetAggregatedMetrics(context.Background(), query)
+	if err != nil {
+		return err
+	}
+
+	// Place each bucket by its offset from From rather than by its exact start time
+	values := make([]map[models.MetricType]float64, bucketCount)
+	for _, bucket := range buckets {
+		offset := bucket.Start.Sub(query.From)
+		if offset < 0 {
+			continue
+		}
+		if i := int(offset / query.Step); i < bucketCount {
+			values[i] = bucket.Values
+		}
+	}
+
+	response.Buckets = make(map[string][]models.BucketPoint, len(metricTypes))
+	for _, metricType := range metricTypes {
+		points := make([]models.BucketPoint, 0, bucketCount)
+		for i := 0; i < bucketCount; i++ {
+			bucketStart := query.From.Add(time.Duration(i) * query.Step)
+			point := models.BucketPoint{Timestamp: bucketStart}
+			if value, ok := values[i][metricType]; ok {
+				point.Value = &value
+			}
+			points = append(points, point)
+		}
+		response.Buckets[string(metricType)] = points
+	}
+
+	response.Step = query.Step.String()
+	response.Aggregation = string(query.Aggregation)
+	response.Count = bucketCount
+	return nil
+}
+
+// GetSwitchSummary retrieves avg/max/p50/p99 per metric for a switch over a trailing window
+func (s *telemetryService) GetSwitchSummary(switchID string, window time.Duration) (*models.SwitchSummary, error) {
+	start := time.Now()
+
+	if switchID == "" {
+		metrics.ErrorsTotal.WithLabelValues("telemetry_service", "empty_switch_id").Inc()
+		return nil, fmt.Errorf("switchID cannot be empty")
+	}
+
+	if window <= 0 {
+		return nil, fmt.Errorf("summary window must be positive")
+	}
+
+	summary, err := s.store.GetSwitchMetricsSummary(context.Background(), switchID, window)
+	if err != nil {
+		metrics.ErrorsTotal.WithLabelValues("telemetry_service", "summary_query_error").Inc()
+		s.logger.Errorf("Failed to get summary for switch %s: %v", switchID, err)
+		return nil, fmt.Errorf("failed to get summary for switch %s: %w", switchID, err)
+	}
+
+	duration := time.Since(start).Seconds()
+	metrics.TelemetryQueryTotal.WithLabelValues(switchID, "summary", "success").Inc()
+	metrics.TelemetryQueryDuration.WithLabelValues(switchID, "summary").Observe(duration)
+
+	return summary, nil
+}
+
+// GetFabricStatistics computes per-metric statistics across all cached switches,
+// optionally grouped by switch location
+func (s *telemetryService) GetFabricStatistics(groupBy string) (*models.FabricStatisticsResponse, error) {
+	if groupBy != "" && groupBy != models.GroupByLocation {
+		return nil, fmt.Errorf("unsupported group_by: %s", groupBy)
+	}
+
+	allData := s.store.ListAllSwitches()
+	samples := make([]*models.TelemetryData, 0, len(allData))
+	for _, data := range allData {
+		if data != nil {
+			samples = append(samples, data)
+		}
+	}
+
+	response := &models.FabricStatisticsResponse{
+		FleetStatistics: computeFleetStatistics(samples),
+		GroupBy:         groupBy,
+		Timestamp:       time.Now(),
+	}
+
+	if groupBy == models.GroupByLocation {
+		switches, err := s.store.ListSwitches(context.Background())
+		if err != nil {
+			s.logger.Errorf("Failed to list switches for location grouping: %v", err)
+			return nil, fmt.Errorf("failed to resolve switch locations: %w", err)
+		}
+
+		locations := make(map[string]string, len(switches))
+		for _, sw := range switches {
+			locations[sw.ID] = sw.Location
+		}
+
+		grouped := make(map[string][]*models.TelemetryData)
+		for _, sample := range samples {
+			location := locations[sample.SwitchID]
+			if location == "" {
+				location = "unknown"
+			}
+			grouped[location] = append(grouped[location], sample)
+		}
+
+		response.Groups = make(map[string]models.FleetStatistics, len(grouped))
+		for location, groupSamples := range grouped {
+			response.Groups[location] = computeFleetStatistics(groupSamples)
+		}
+	}
+
+	return response, nil
+}
+
+// GetTopSwitches returns the K highest or lowest switches for a metric, either from the
+// latest cached samples or, when query.Window is set, aggregated from persisted history
+func (s *telemetryService) GetTopSwitches(query models.TopSwitchesQuery) (*models.TopSwitchesResponse, error) {
+	if !query.Metric.IsValid() {
+		return nil, fmt.Errorf("invalid metric type: %s", query.Metric)
+	}
+
+	if query.K <= 0 {
+		return nil, fmt.Errorf("k must be positive")
+	}
+
+	response := &models.TopSwitchesResponse{
+		Metric:    string(query.Metric),
+		Order:     "asc",
+		K:         query.K,
+		Timestamp: time.Now(),
+	}
+	if query.Descending {
+		response.Order = "desc"
+	}
+
+	var ranked []models.RankedSwitch
+	var err error
+	if query.Window > 0 {
+		if query.Aggregation == "" {
+			query.Aggregation = models.AggregationAvg
+		}
+		response.Window = query.Window.String()
+		response.Aggregation = string(query.Aggregation)
+		ranked, err = s.store.GetTopSwitches(context.Background(), query)
+	} else {
+		ranked, err = s.store.TopSwitches(query.Metric, query.K, query.Descending)
+	}
+	if err != nil {
+		metrics.ErrorsTotal.WithLabelValues("telemetry_service", "top_query_error").Inc()
+		s.logger.Errorf("Failed to rank switches by %s: %v", query.Metric, err)
+		return nil, fmt.Errorf("failed to rank switches by %s: %w", query.Metric, err)
+	}
+
+	response.Switches = ranked
+	response.Count = len(ranked)
+	return response, nil
+}
+
+// GetActiveAlerts returns pending and firing alerts, optionally filtered by switch and state
+func (s *telemetryService) GetActiveAlerts(switchID string, state models.AlertState) (*models.ActiveAlertsResponse, error) {
+	if s.alerts == nil {
+		return nil, ErrAlertingDisabled
+	}
+
+	alerts := s.alerts.ActiveAlerts(switchID, state)
+	return &models.ActiveAlertsResponse{
+		Alerts:    alerts,
+		Count:     len(alerts),
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// GetAlertHistory returns persisted alert transitions over a time range
+func (s *telemetryService) GetAlertHistory(query models.AlertHistoryQuery) (*models.AlertHistoryResponse, error) {
+	if s.alerts == nil {
+		return nil, ErrAlertingDisabled
+	}
+
+	if query.To.Before(query.From) {
+		return nil, fmt.Errorf("invalid time range: from %s is after to %s",
+			query.From.Format(time.RFC3339), query.To.Format(time.RFC3339))
+	}
+
+	events, err := s.alerts.History(context.Background(), query)
+	if err != nil {
+		metrics.ErrorsTotal.WithLabelValues("telemetry_service", "alert_history_error").Inc()
+		s.logger.Errorf("Failed to get alert history: %v", err)
+		return nil, fmt.Errorf("failed to get alert history: %w", err)
+	}
+
+	return &models.AlertHistoryResponse{
+		Events: events,
+		From:   query.From,
+		To:     query.To,
+		Count:  len(events),
+	}, nil
+}
+
+// GetAnomalies returns recently detected anomalies, newest first
+func (s *telemetryService) GetAnomalies(query models.AnomalyQuery) (*models.AnomaliesResponse, error) {
+	if s.anomalies == nil {
+		return nil, ErrAnomalyDetectionDisabled
+	}
+
+	if query.Metric != "" && !query.Metric.IsValid() {
+		return nil, fmt.Errorf("invalid metric type: %s", query.Metric)
+	}
+
+	anomalies := s.anomalies.Anomalies(query)
+	return &models.AnomaliesResponse{
+		Anomalies: anomalies,
+		Count:     len(anomalies),
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// Subscribe registers a stream subscriber for live updates matching filter
+func (s *telemetryService) Subscribe(filter stream.Filter) (*stream.Subscription, error) {
+	if s.hub == nil {
+		return nil, ErrStreamingDisabled
+	}
+	return s.hub.Subscribe(filter)
+}
+
 // RegisterSwitch registers a new switch in the system
 func (s *telemetryService) RegisterSwitch(sw models.Switch) error {
 	if sw.ID == "" {
@@ -258,9 +685,33 @@ func (s *telemetryService) GetHealthStatus() map[string]interface{} {
 		checks["switches"] = "no_data"
 	}
 
+	// The cache keeps serving while the database is down, so an outage degrades rather than fails the service
+	var database *storage.DatabaseHealth
+	if s.database != nil {
+		health := s.database.Health()
+		database = &health
+		checks["database"] = health.Status
+		if !health.Healthy() {
+			status = "degraded"
+			checks["storage"] = "degraded"
+		}
+	}
+
+	// While the circuit is open writes go to the write-ahead log and history queries fail fast
+	var breaker *storage.CircuitBreakerStatus
+	if s.breaker != nil {
+		breakerStatus := s.breaker.Status()
+		breaker = &breakerStatus
+		checks["circuit_breaker"] = string(breakerStatus.State)
+		if breakerStatus.State != storage.CircuitClosed {
+			status = "degraded"
+			checks["storage"] = "degraded"
+		}
+	}
+
 	uptime := time.Since(s.startTime)
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"service":      "telemetry",
 		"status":       status,
 		"uptime":       uptime.String(),
@@ -270,6 +721,13 @@ func (s *telemetryService) GetHealthStatus() map[string]interface{} {
 		"timestamp":    time.Now().Format(time.RFC3339),
 		"version":      "1.0.0",
 	}
+	if database != nil {
+		result["database"] = database
+	}
+	if breaker != nil {
+		result["circuit_breaker"] = breaker
+	}
+	return result
 }
 
 // Start initializes the telemetry service
@@ -281,6 +739,24 @@ func (s *telemetryService) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start telemetry store: %w", err)
 	}
 
+	if s.alerts != nil {
+		if err := s.alerts.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start alerting engine: %w", err)
+		}
+	}
+
+	if s.retention != nil {
+		if err := s.retention.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start retention manager: %w", err)
+		}
+	}
+
+	if s.database != nil {
+		if err := s.database.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start database monitor: %w", err)
+		}
+	}
+
 	s.logger.Infof("Telemetry service started successfully")
 	return nil
 }
@@ -289,6 +765,29 @@ func (s *telemetryService) Start(ctx context.Context) error {
 func (s *telemetryService) Stop(ctx context.Context) error {
 	s.logger.Infof("Stopping telemetry service...")
 
+	// End open streams so their HTTP connections can be shut down
+	if s.hub != nil {
+		s.hub.Close()
+	}
+
+	if s.database != nil {
+		s.database.Stop()
+	}
+
+	// Stop retention before the store so no prune runs against a closed database
+	if s.retention != nil {
+		if err := s.retention.Stop(ctx); err != nil {
+			s.logger.Errorf("Error stopping retention manager: %v", err)
+		}
+	}
+
+	// Stop alerting first so pending transitions are persisted while the store is still up
+	if s.alerts != nil {
+		if err := s.alerts.Stop(ctx); err != nil {
+			s.logger.Errorf("Error stopping alerting engine: %v", err)
+		}
+	}
+
 	// Stop the underlying store
 	if err := s.store.Stop(ctx); err != nil {
 		s.logger.Errorf("Error stopping telemetry store: %v", err)
@@ -325,40 +824,3 @@ func (s *telemetryService) ValidateMetricData(data models.TelemetryData) error {
 
 	return nil
 }
-
-// GetMetricStatistics returns statistical information about metrics
-func (s *telemetryService) GetMetricStatistics() map[string]interface{} {
-	allData := s.store.ListAllSwitches()
-
-	if len(allData) == 0 {
-		return map[string]interface{}{
-			"switch_count": 0,
-			"timestamp":    time.Now().Format(time.RFC3339),
-		}
-	}
-
-	// Calculate basic statistics
-	var totalBandwidth, totalLatency, totalUtilization, totalTemperature float64
-	var totalErrors int64
-	count := len(allData)
-
-	for _, data := range allData {
-		if data != nil {
-			totalBandwidth += data.BandwidthMbps
-			totalLatency += data.LatencyMs
-			totalUtilization += data.UtilizationPct
-			totalTemperature += data.TemperatureC
-			totalErrors += data.PacketErrors
-		}
-	}
-
-	return map[string]interface{}{
-		"switch_count":       count,
-		"avg_bandwidth_mbps": totalBandwidth / float64(count),
-		"avg_latency_ms":     totalLatency / float64(count),
-		"avg_utilization":    totalUtilization / float64(count),
-		"avg_temperature":    totalTemperature / float64(count),
-		"total_errors":       totalErrors,
-		"timestamp":          time.Now().Format(time.RFC3339),
-	}
-}
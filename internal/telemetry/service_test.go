@@ -0,0 +1,115 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ufm/internal/telemetry/models"
+	"github.com/ufm/internal/telemetry/storage"
+)
+
+// microsecondRepository returns bucket starts at microsecond precision, like date_bin in PostgreSQL
+type microsecondRepository struct {
+	*storage.MemoryRepository
+}
+
+func (r *microsecondRepository) GetAggregatedMetrics(ctx context.Context, query models.HistoryQuery) ([]models.MetricBucket, error) {
+	buckets, err := r.MemoryRepository.GetAggregatedMetrics(ctx, query)
+	for i := range buckets {
+		buckets[i].Start = buckets[i].Start.Truncate(time.Microsecond)
+	}
+	return buckets, err
+}
+
+// newTestService creates a service over an in-memory repository holding switch-001
+func newTestService(t *testing.T, repository storage.TelemetryRepository) TelemetryService {
+	t.Helper()
+	require.NoError(t, repository.CreateSwitch(context.Background(), models.Switch{ID: "switch-001", Name: "switch-001"}))
+	store := storage.NewHybridStore(storage.NewInMemoryCache(), repository, storage.DefaultHybridStoreConfig(), nil)
+	return NewTelemetryService(store, nil)
+}
+
+func TestGetMetricHistory_AggregatesFromNanosecondPrecision(t *testing.T) {
+	repository := &microsecondRepository{MemoryRepository: storage.NewMemoryRepository(100)}
+	service := newTestService(t, repository)
+
+	// Relative ranges are resolved from time.Now, which carries nanoseconds
+	from := time.Now().Add(-3 * time.Minute).Truncate(time.Microsecond).Add(789 * time.Nanosecond)
+	require.NoError(t, repository.StoreMetrics(context.Background(), []models.TelemetryData{
+		{SwitchID: "switch-001", Timestamp: from.Add(10 * time.Second), TemperatureC: 40},
+		{SwitchID: "switch-001", Timestamp: from.Add(20 * time.Second), TemperatureC: 50},
+		{SwitchID: "switch-001", Timestamp: from.Add(130 * time.Second), TemperatureC: 60},
+	}))
+
+	response, err := service.GetMetricHistory(models.HistoryQuery{
+		SwitchID:    "switch-001",
+		From:        from,
+		To:          from.Add(3 * time.Minute),
+		Metrics:     []models.MetricType{models.MetricTemperature},
+		Step:        time.Minute,
+		Aggregation: models.AggregationAvg,
+	})
+	require.NoError(t, err)
+
+	points := response.Buckets[string(models.MetricTemperature)]
+	require.Len(t, points, 4)
+	require.NotNil(t, points[0].Value)
+	assert.Equal(t, 45.0, *points[0].Value)
+	assert.Nil(t, points[1].Value)
+	require.NotNil(t, points[2].Value)
+	assert.Equal(t, 60.0, *points[2].Value)
+	assert.Nil(t, points[3].Value)
+}
+
+func TestGetSwitchSummary(t *testing.T) {
+	repository := storage.NewMemoryRepository(100)
+	service := newTestService(t, repository)
+
+	now := time.Now()
+	require.NoError(t, repository.StoreMetrics(context.Background(), []models.TelemetryData{
+		{SwitchID: "switch-001", Timestamp: now.Add(-2 * time.Minute), LatencyMs: 1},
+		{SwitchID: "switch-001", Timestamp: now.Add(-time.Minute), LatencyMs: 3},
+		{SwitchID: "switch-001", Timestamp: now.Add(-2 * time.Hour), LatencyMs: 50},
+	}))
+
+	summary, err := service.GetSwitchSummary("switch-001", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "switch-001", summary.SwitchID)
+	assert.Equal(t, "1h0m0s", summary.Window)
+	assert.Equal(t, int64(2), summary.SampleCount)
+	latency := summary.Metrics[string(models.MetricLatency)]
+	require.NotNil(t, latency.Avg)
+	assert.InDelta(t, 2.0, *latency.Avg, 1e-9)
+	assert.Equal(t, 3.0, *latency.Max)
+
+	_, err = service.GetSwitchSummary("", time.Hour)
+	assert.ErrorContains(t, err, "switchID cannot be empty")
+	_, err = service.GetSwitchSummary("switch-001", 0)
+	assert.ErrorContains(t, err, "window must be positive")
+}
+
+func TestGetMetricHistory_RejectsInvalidQueries(t *testing.T) {
+	service := newTestService(t, storage.NewMemoryRepository(100))
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		query models.HistoryQuery
+	}{
+		{"empty switch", models.HistoryQuery{From: now.Add(-time.Hour), To: now}},
+		{"inverted range", models.HistoryQuery{SwitchID: "switch-001", From: now, To: now.Add(-time.Hour)}},
+		{"unknown metric", models.HistoryQuery{SwitchID: "switch-001", From: now.Add(-time.Hour), To: now, Metrics: []models.MetricType{"humidity"}}},
+		{"unknown aggregation", models.HistoryQuery{SwitchID: "switch-001", From: now.Add(-time.Hour), To: now, Step: time.Minute, Aggregation: "median"}},
+		{"too many windows", models.HistoryQuery{SwitchID: "switch-001", From: now.Add(-24 * time.Hour), To: now, Step: time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.GetMetricHistory(tt.query)
+			assert.ErrorIs(t, err, ErrInvalidQuery)
+		})
+	}
+}
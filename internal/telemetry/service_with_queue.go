@@ -3,10 +3,12 @@ package telemetry
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/ufm/internal/log"
 	"github.com/ufm/internal/telemetry/models"
 	"github.com/ufm/internal/telemetry/queue"
+	"github.com/ufm/internal/telemetry/stream"
 )
 
 // QueuedTelemetryService wraps the regular service with optional request queuing
@@ -209,10 +211,46 @@ func (s *QueuedTelemetryService) IngestBatch(data []models.TelemetryData) error
 	return s.baseService.IngestBatch(data)
 }
 
+func (s *QueuedTelemetryService) ValidateMetricData(data models.TelemetryData) error {
+	return s.baseService.ValidateMetricData(data)
+}
+
 func (s *QueuedTelemetryService) GetAllMetrics() (*models.AllMetricsResponse, error) {
 	return s.baseService.GetAllMetrics()
 }
 
+func (s *QueuedTelemetryService) GetMetricHistory(query models.HistoryQuery) (*models.MetricHistoryResponse, error) {
+	return s.baseService.GetMetricHistory(query)
+}
+
+func (s *QueuedTelemetryService) GetSwitchSummary(switchID string, window time.Duration) (*models.SwitchSummary, error) {
+	return s.baseService.GetSwitchSummary(switchID, window)
+}
+
+func (s *QueuedTelemetryService) GetFabricStatistics(groupBy string) (*models.FabricStatisticsResponse, error) {
+	return s.baseService.GetFabricStatistics(groupBy)
+}
+
+func (s *QueuedTelemetryService) GetTopSwitches(query models.TopSwitchesQuery) (*models.TopSwitchesResponse, error) {
+	return s.baseService.GetTopSwitches(query)
+}
+
+func (s *QueuedTelemetryService) GetActiveAlerts(switchID string, state models.AlertState) (*models.ActiveAlertsResponse, error) {
+	return s.baseService.GetActiveAlerts(switchID, state)
+}
+
+func (s *QueuedTelemetryService) GetAlertHistory(query models.AlertHistoryQuery) (*models.AlertHistoryResponse, error) {
+	return s.baseService.GetAlertHistory(query)
+}
+
+func (s *QueuedTelemetryService) GetAnomalies(query models.AnomalyQuery) (*models.AnomaliesResponse, error) {
+	return s.baseService.GetAnomalies(query)
+}
+
+func (s *QueuedTelemetryService) Subscribe(filter stream.Filter) (*stream.Subscription, error) {
+	return s.baseService.Subscribe(filter)
+}
+
 func (s *QueuedTelemetryService) RegisterSwitch(sw models.Switch) error {
 	return s.baseService.RegisterSwitch(sw)
 }
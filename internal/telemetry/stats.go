@@ -0,0 +1,81 @@
+package telemetry
+
+import (
+	"math"
+	"sort"
+
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// computeFleetStatistics computes per-metric statistics over the latest sample of each switch
+func computeFleetStatistics(samples []*models.TelemetryData) models.FleetStatistics {
+	stats := models.FleetStatistics{
+		SwitchCount: len(samples),
+		Metrics:     make(map[string]models.MetricStatistics, len(models.AllMetricTypes)),
+	}
+
+	values := make([]float64, 0, len(samples))
+	for _, metricType := range models.AllMetricTypes {
+		values = values[:0]
+		for _, sample := range samples {
+			value, err := sample.GetMetricFloat(metricType)
+			if err != nil {
+				continue
+			}
+			values = append(values, value)
+		}
+		stats.Metrics[string(metricType)] = computeMetricStatistics(values)
+	}
+
+	return stats
+}
+
+// computeMetricStatistics computes mean, min, max, population stddev and percentiles.
+// The input slice is sorted in place.
+func computeMetricStatistics(values []float64) models.MetricStatistics {
+	if len(values) == 0 {
+		return models.MetricStatistics{}
+	}
+
+	sort.Float64s(values)
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var squaredDiffs float64
+	for _, v := range values {
+		squaredDiffs += (v - mean) * (v - mean)
+	}
+
+	return models.MetricStatistics{
+		Count:  len(values),
+		Mean:   mean,
+		Min:    values[0],
+		Max:    values[len(values)-1],
+		StdDev: math.Sqrt(squaredDiffs / float64(len(values))),
+		P50:    percentile(values, 0.50),
+		P90:    percentile(values, 0.90),
+		P95:    percentile(values, 0.95),
+		P99:    percentile(values, 0.99),
+	}
+}
+
+// percentile returns the q-th quantile of sorted values using linear interpolation
+func percentile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := q * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+
+	weight := rank - float64(lower)
+	return sorted[lower]*(1-weight) + sorted[upper]*weight
+}
@@ -0,0 +1,38 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+func TestComputeMetricStatistics(t *testing.T) {
+	stats := computeMetricStatistics([]float64{4, 2, 8, 6})
+
+	assert.Equal(t, 4, stats.Count)
+	assert.Equal(t, 5.0, stats.Mean)
+	assert.Equal(t, 2.0, stats.Min)
+	assert.Equal(t, 8.0, stats.Max)
+	assert.InDelta(t, 2.2360, stats.StdDev, 0.0001)
+	assert.Equal(t, 5.0, stats.P50)
+	assert.InDelta(t, 7.4, stats.P90, 0.0001)
+}
+
+func TestComputeMetricStatistics_Empty(t *testing.T) {
+	assert.Equal(t, models.MetricStatistics{}, computeMetricStatistics(nil))
+}
+
+func TestComputeFleetStatistics(t *testing.T) {
+	samples := []*models.TelemetryData{
+		{SwitchID: "switch-001", TemperatureC: 40, PacketErrors: 1},
+		{SwitchID: "switch-002", TemperatureC: 60, PacketErrors: 3},
+	}
+
+	stats := computeFleetStatistics(samples)
+
+	assert.Equal(t, 2, stats.SwitchCount)
+	assert.Len(t, stats.Metrics, len(models.AllMetricTypes))
+	assert.Equal(t, 50.0, stats.Metrics[string(models.MetricTemperature)].Mean)
+	assert.Equal(t, 3.0, stats.Metrics[string(models.MetricPacketErrors)].Max)
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ufm/internal/telemetry/models"
+)
+
+const (
+	// DefaultAlertHistoryLimit is used when an alert history query does not set a limit
+	DefaultAlertHistoryLimit = 100
+	// MaxAlertHistoryLimit caps the number of events a single alert history query may return
+	MaxAlertHistoryLimit = 1000
+)
+
+// PostgreSQLAlertRepository persists alert transitions in the alert_events table
+type PostgreSQLAlertRepository struct {
+	db *sql.DB
+}
+
+// NewPostgreSQLAlertRepository creates a new alert history repository instance
+func NewPostgreSQLAlertRepository(db *sql.DB) *PostgreSQLAlertRepository {
+	return &PostgreSQLAlertRepository{
+		db: db,
+	}
+}
+
+// RecordAlertEvents stores alert transitions in a single transaction
+func (r *PostgreSQLAlertRepository) RecordAlertEvents(ctx context.Context, events []models.AlertEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO alert_events
+		(rule_name, switch_id, metric, comparator, threshold, severity, state, value, active_at, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, event := range events {
+		_, err = stmt.ExecContext(ctx,
+			event.RuleName,
+			event.SwitchID,
+			event.Metric,
+			event.Comparator,
+			event.Threshold,
+			event.Severity,
+			string(event.State),
+			event.Value,
+			event.ActiveAt,
+			event.Timestamp,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert alert event for switch %s: %w", event.SwitchID, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetAlertHistory retrieves alert transitions within a time range, newest first
+func (r *PostgreSQLAlertRepository) GetAlertHistory(ctx context.Context, query models.AlertHistoryQuery) ([]models.AlertEvent, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultAlertHistoryLimit
+	}
+
+	sqlQuery := `
+		SELECT id, rule_name, switch_id, metric, comparator, threshold,
+		       severity, state, value, active_at, timestamp
+		FROM alert_events
+		WHERE timestamp >= $1 AND timestamp <= $2
+	`
+	args := []interface{}{query.From, query.To}
+
+	if query.SwitchID != "" {
+		args = append(args, query.SwitchID)
+		sqlQuery += fmt.Sprintf(` AND switch_id = $%d`, len(args))
+	}
+	if query.RuleName != "" {
+		args = append(args, query.RuleName)
+		sqlQuery += fmt.Sprintf(` AND rule_name = $%d`, len(args))
+	}
+
+	args = append(args, limit)
+	sqlQuery += fmt.Sprintf(` ORDER BY timestamp DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert history: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AlertEvent{}
+	for rows.Next() {
+		var event models.AlertEvent
+		var state string
+		err := rows.Scan(
+			&event.ID,
+			&event.RuleName,
+			&event.SwitchID,
+			&event.Metric,
+			&event.Comparator,
+			&event.Threshold,
+			&event.Severity,
+			&state,
+			&event.Value,
+			&event.ActiveAt,
+			&event.Timestamp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert event row: %w", err)
+		}
+		event.State = models.AlertState(state)
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating alert event rows: %w", err)
+	}
+
+	return events, nil
+}
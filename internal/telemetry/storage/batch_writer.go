@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/ufm/internal/monitoring/metrics"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// shutdownFlushTimeout bounds how long queued rows may take to reach the
+// database once the store is stopping; later writes are spilled
+const shutdownFlushTimeout = 3 * time.Second
+
+// flushBatch is a group of rows handed to a write worker
+type flushBatch struct {
+	rows     []models.TelemetryData
+	queuedAt time.Time // When the oldest row was buffered
+}
+
+// coalesceWorker gathers queued rows into batches of config.BatchSize for the
+// write workers. Rows that do not fill a batch are written on the next
+// FlushInterval tick, so no row waits longer than that for a worker.
+func (hs *HybridStore) coalesceWorker() {
+	defer hs.wg.Done()
+	defer close(hs.writeQueue)
+
+	config, updated := hs.currentConfig()
+	ticker := time.NewTicker(config.FlushInterval)
+	defer ticker.Stop()
+
+	var pending []models.TelemetryData
+	var queuedAt time.Time
+
+	for {
+		select {
+		case <-hs.ctx.Done():
+			hs.flushOnShutdown(pending, queuedAt)
+			return
+
+		case rows := <-hs.flushQueue:
+			if len(pending) == 0 {
+				queuedAt = time.Now()
+			}
+			pending = append(pending, rows...)
+
+			for len(pending) >= config.BatchSize {
+				// Cap the batch so appends to pending cannot overwrite it
+				batch := pending[:config.BatchSize:config.BatchSize]
+				if !hs.dispatch(flushBatch{rows: batch, queuedAt: queuedAt}) {
+					break
+				}
+				pending = pending[config.BatchSize:]
+			}
+
+		case <-updated:
+			config, updated = hs.currentConfig()
+			ticker.Reset(config.FlushInterval)
+
+		case <-ticker.C:
+			if len(pending) > 0 && hs.dispatch(flushBatch{rows: pending, queuedAt: queuedAt}) {
+				pending = nil
+			}
+
+		case reply := <-hs.flushRequests:
+			// FlushToDatabase writes these itself
+			reply <- pending
+			pending = nil
+		}
+	}
+}
+
+// dispatch hands a batch to the write workers, blocking while they are all
+// busy. It returns false if the store stops first.
+func (hs *HybridStore) dispatch(batch flushBatch) bool {
+	select {
+	case hs.writeQueue <- batch:
+		return true
+	case <-hs.ctx.Done():
+		return false
+	}
+}
+
+// flushOnShutdown hands the rows still buffered or queued to the write workers.
+// Writes that have not finished within shutdownFlushTimeout are cancelled and
+// their rows spilled, as are rows the workers cannot take by then.
+func (hs *HybridStore) flushOnShutdown(pending []models.TelemetryData, queuedAt time.Time) {
+	time.AfterFunc(shutdownFlushTimeout, hs.cancelWrites)
+
+	for drained := false; !drained; {
+		select {
+		case rows := <-hs.flushQueue:
+			if len(pending) == 0 {
+				queuedAt = time.Now()
+			}
+			pending = append(pending, rows...)
+		default:
+			drained = true
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	hs.logger.Infof("Flushing %d queued metrics before shutdown", len(pending))
+
+	config, _ := hs.currentConfig()
+	for len(pending) > 0 {
+		size := min(len(pending), config.BatchSize)
+		select {
+		case hs.writeQueue <- flushBatch{rows: pending[:size], queuedAt: queuedAt}:
+			pending = pending[size:]
+		case <-hs.writeCtx.Done():
+			hs.mu.Lock()
+			hs.pendingFlushItems -= len(pending)
+			hs.mu.Unlock()
+			hs.spill(pending, "Shutdown flush timed out")
+			return
+		}
+	}
+}
+
+// writeWorker writes batches until the coalescer closes the write queue
+func (hs *HybridStore) writeWorker() {
+	defer hs.wg.Done()
+
+	for batch := range hs.writeQueue {
+		start := time.Now()
+		err := hs.writeOrSpill(hs.writeCtx, batch.rows)
+
+		status := "success"
+		if err != nil {
+			status = "error"
+		} else {
+			metrics.TelemetryFlushLatency.Observe(time.Since(batch.queuedAt).Seconds())
+		}
+		metrics.TelemetryFlushDuration.WithLabelValues(status).Observe(time.Since(start).Seconds())
+		metrics.TelemetryFlushBatchRows.Observe(float64(len(batch.rows)))
+	}
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// recordingRepository records the size of every write
+type recordingRepository struct {
+	*MemoryRepository
+	mu      sync.Mutex
+	batches []int
+}
+
+func (r *recordingRepository) StoreMetrics(ctx context.Context, metrics []models.TelemetryData) error {
+	r.mu.Lock()
+	r.batches = append(r.batches, len(metrics))
+	r.mu.Unlock()
+	return r.MemoryRepository.StoreMetrics(ctx, metrics)
+}
+
+func (r *recordingRepository) batchSizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.batches...)
+}
+
+func newBatchWriterStore(t *testing.T, batchSize int, flushInterval time.Duration) (*HybridStore, *recordingRepository) {
+	repo := &recordingRepository{MemoryRepository: NewMemoryRepository(1000)}
+	createSwitches(t, repo, "switch-001")
+
+	config := DefaultHybridStoreConfig()
+	config.BatchSize = batchSize
+	config.FlushInterval = flushInterval
+	config.FlushWorkers = 2
+
+	store := NewHybridStore(NewInMemoryCache(), repo, config, nil)
+	require.NoError(t, store.Start(context.Background()))
+	return store, repo
+}
+
+func TestHybridStore_CoalescesRowsIntoBatches(t *testing.T) {
+	ctx := context.Background()
+	store, repo := newBatchWriterStore(t, 3, time.Hour)
+	defer store.Stop(ctx)
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, store.UpdateMetrics("switch-001", models.TelemetryData{SwitchID: "switch-001", TemperatureC: float64(i)}))
+	}
+	require.Eventually(t, func() bool { return len(repo.batchSizes()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []int{3, 3}, repo.batchSizes())
+
+	// The leftover row is held back for a fuller batch until flushed explicitly
+	require.NoError(t, store.FlushToDatabase(ctx))
+	assert.Equal(t, []int{3, 3, 1}, repo.batchSizes())
+}
+
+func TestHybridStore_WritesPartialBatchOnFlushInterval(t *testing.T) {
+	ctx := context.Background()
+	store, repo := newBatchWriterStore(t, 100, 20*time.Millisecond)
+	defer store.Stop(ctx)
+
+	require.NoError(t, store.StoreMetricsBulk(ctx, []models.TelemetryData{
+		{SwitchID: "switch-001", TemperatureC: 40},
+		{SwitchID: "switch-001", TemperatureC: 41},
+	}))
+	require.Eventually(t, func() bool { return len(repo.batchSizes()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []int{2}, repo.batchSizes())
+}
+
+func TestHybridStore_StopFlushesBufferedRows(t *testing.T) {
+	ctx := context.Background()
+	store, repo := newBatchWriterStore(t, 2, time.Hour)
+
+	require.NoError(t, store.StoreMetricsBulk(ctx, []models.TelemetryData{
+		{SwitchID: "switch-001", TemperatureC: 40},
+		{SwitchID: "switch-001", TemperatureC: 41},
+		{SwitchID: "switch-001", TemperatureC: 42},
+	}))
+	require.NoError(t, store.Stop(ctx))
+
+	count, err := repo.GetMetricsCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	assert.ElementsMatch(t, []int{2, 1}, repo.batchSizes())
+}
+
+func TestHybridStore_UpdateConfigAppliesFlushInterval(t *testing.T) {
+	ctx := context.Background()
+	store, repo := newBatchWriterStore(t, 100, time.Hour)
+	defer store.Stop(ctx)
+
+	require.NoError(t, store.UpdateMetrics("switch-001", models.TelemetryData{SwitchID: "switch-001", TemperatureC: 40}))
+
+	// The running coalescer picks up the shorter interval without a restart
+	config, _ := store.currentConfig()
+	config.FlushInterval = 20 * time.Millisecond
+	store.UpdateConfig(config)
+
+	require.Eventually(t, func() bool { return len(repo.batchSizes()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []int{1}, repo.batchSizes())
+}
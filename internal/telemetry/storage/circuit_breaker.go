@@ -0,0 +1,356 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/monitoring/metrics"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+const (
+	// DefaultCircuitFailureThreshold is the number of consecutive failures that opens the circuit
+	DefaultCircuitFailureThreshold = 5
+	// DefaultCircuitOpenTimeout is how long an open circuit fails fast before letting a trial call through
+	DefaultCircuitOpenTimeout = 30 * time.Second
+	// DefaultCircuitHalfOpenRequests is the number of trial calls allowed at once while half-open
+	DefaultCircuitHalfOpenRequests = 1
+)
+
+// ErrCircuitOpen is returned without calling the database while the circuit is open
+var ErrCircuitOpen = errors.New("database circuit breaker is open")
+
+// CircuitState is the state of a circuit breaker
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // Calls pass through
+	CircuitOpen     CircuitState = "open"      // Calls fail fast with ErrCircuitOpen
+	CircuitHalfOpen CircuitState = "half_open" // A limited number of trial calls pass through
+)
+
+// circuitStateValues maps states to the database_circuit_breaker_state gauge
+var circuitStateValues = map[CircuitState]float64{
+	CircuitClosed:   0,
+	CircuitHalfOpen: 1,
+	CircuitOpen:     2,
+}
+
+// CircuitBreakerConfig controls when the circuit opens and how it recovers
+type CircuitBreakerConfig struct {
+	FailureThreshold int           // Consecutive failures that open the circuit
+	OpenTimeout      time.Duration // Time spent open before trial calls are let through
+	HalfOpenRequests int           // Trial calls allowed at once while half-open
+
+	// IsFailure decides which errors count against the database, IsUnavailable by default
+	IsFailure func(error) bool
+}
+
+// CircuitBreakerStatus is a snapshot of the breaker for health reporting
+type CircuitBreakerStatus struct {
+	State               CircuitState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+	RejectedCalls       int64        `json:"rejected_calls"`
+	LastError           string       `json:"last_error,omitempty"`
+}
+
+// CircuitBreakerRepository wraps a TelemetryRepository so that a database that
+// keeps timing out or refusing connections is not called at all for a while.
+// After FailureThreshold consecutive failures the circuit opens and every call
+// fails fast with ErrCircuitOpen, which lets the hybrid store spill writes to
+// the write-ahead log and serve reads from the cache instead of blocking. After
+// OpenTimeout a few trial calls are let through; one success closes the circuit
+// again and a failure reopens it.
+type CircuitBreakerRepository struct {
+	repository TelemetryRepository
+	config     CircuitBreakerConfig
+	logger     log.Logger
+
+	mu                  sync.Mutex
+	state               CircuitState
+	consecutiveFailures int
+	openedAt            time.Time
+	trials              int // Trial calls in flight while half-open
+	rejected            int64
+	lastError           string
+}
+
+// NewCircuitBreakerRepository wraps repository with a circuit breaker
+func NewCircuitBreakerRepository(repository TelemetryRepository, config CircuitBreakerConfig, logger log.Logger) *CircuitBreakerRepository {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = DefaultCircuitFailureThreshold
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = DefaultCircuitOpenTimeout
+	}
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = DefaultCircuitHalfOpenRequests
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = IsUnavailable
+	}
+	if logger == nil {
+		logger = log.DefaultLogger
+	}
+
+	metrics.CircuitBreakerState.Set(circuitStateValues[CircuitClosed])
+	return &CircuitBreakerRepository{
+		repository: repository,
+		config:     config,
+		logger:     logger,
+		state:      CircuitClosed,
+	}
+}
+
+// IsUnavailable reports whether err means the database is down or too slow to
+// answer, as opposed to rejecting a particular query or batch
+func IsUnavailable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08", // connection exception
+			"53", // insufficient resources
+			"57", // operator intervention, such as a shutdown or query cancelled by statement_timeout
+			"58": // system error
+			return true
+		}
+		return false
+	}
+
+	// Errors lib/pq returns for a dropped connection without wrapping a net.Error
+	message := err.Error()
+	return strings.Contains(message, "connection refused") || strings.Contains(message, "bad connection")
+}
+
+// Status returns the current breaker state
+func (cb *CircuitBreakerRepository) Status() CircuitBreakerStatus {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	status := CircuitBreakerStatus{
+		State:               cb.state,
+		ConsecutiveFailures: cb.consecutiveFailures,
+		RejectedCalls:       cb.rejected,
+		LastError:           cb.lastError,
+	}
+	if cb.state != CircuitClosed {
+		openedAt := cb.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// Unwrap returns the wrapped repository
+func (cb *CircuitBreakerRepository) Unwrap() TelemetryRepository {
+	return cb.repository
+}
+
+// allow decides whether a call may reach the database and whether it is a half-open trial
+func (cb *CircuitBreakerRepository) allow() (bool, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if time.Since(cb.openedAt) < cb.config.OpenTimeout {
+			cb.rejected++
+			metrics.CircuitBreakerRejectedTotal.Inc()
+			return false, ErrCircuitOpen
+		}
+		cb.transition(CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if cb.trials >= cb.config.HalfOpenRequests {
+			cb.rejected++
+			metrics.CircuitBreakerRejectedTotal.Inc()
+			return false, ErrCircuitOpen
+		}
+		cb.trials++
+		return true, nil
+	}
+	return false, nil
+}
+
+// record updates the breaker with the outcome of an allowed call
+func (cb *CircuitBreakerRepository) record(trial bool, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	// Calls started before the circuit last changed state no longer count as trials
+	trial = trial && cb.state == CircuitHalfOpen
+	if trial && cb.trials > 0 {
+		cb.trials--
+	}
+
+	if errors.Is(err, context.Canceled) {
+		// The caller gave up, which says nothing about the database
+		return
+	}
+	if err == nil || !cb.config.IsFailure(err) {
+		cb.consecutiveFailures = 0
+		if trial {
+			cb.transition(CircuitClosed)
+		}
+		return
+	}
+
+	cb.consecutiveFailures++
+	cb.lastError = err.Error()
+	switch {
+	case trial:
+		cb.transition(CircuitOpen)
+	case cb.state == CircuitClosed && cb.consecutiveFailures >= cb.config.FailureThreshold:
+		cb.transition(CircuitOpen)
+	}
+}
+
+// transition moves the breaker to state. Callers hold cb.mu.
+func (cb *CircuitBreakerRepository) transition(state CircuitState) {
+	if cb.state == state {
+		return
+	}
+
+	switch state {
+	case CircuitOpen:
+		cb.openedAt = time.Now()
+		cb.logger.Errorf("Database circuit breaker opened after %d consecutive failures, failing fast for %v: %s",
+			cb.consecutiveFailures, cb.config.OpenTimeout, cb.lastError)
+	case CircuitHalfOpen:
+		cb.trials = 0
+		cb.logger.Infof("Database circuit breaker half-open, letting %d trial calls through", cb.config.HalfOpenRequests)
+	case CircuitClosed:
+		cb.logger.Infof("Database circuit breaker closed, %d calls were rejected while open", cb.rejected)
+		cb.lastError = ""
+	}
+
+	cb.state = state
+	metrics.CircuitBreakerState.Set(circuitStateValues[state])
+	metrics.CircuitBreakerTransitionsTotal.WithLabelValues(string(state)).Inc()
+}
+
+// call runs fn through the breaker
+func (cb *CircuitBreakerRepository) call(fn func() error) error {
+	trial, err := cb.allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	cb.record(trial, err)
+	return err
+}
+
+// guard runs a call returning a value through the breaker
+func guard[T any](cb *CircuitBreakerRepository, fn func() (T, error)) (T, error) {
+	var result T
+	err := cb.call(func() error {
+		var err error
+		result, err = fn()
+		return err
+	})
+	return result, err
+}
+
+func (cb *CircuitBreakerRepository) CreateSwitch(ctx context.Context, sw models.Switch) error {
+	return cb.call(func() error { return cb.repository.CreateSwitch(ctx, sw) })
+}
+
+func (cb *CircuitBreakerRepository) GetSwitch(ctx context.Context, switchID string) (*models.Switch, error) {
+	return guard(cb, func() (*models.Switch, error) { return cb.repository.GetSwitch(ctx, switchID) })
+}
+
+func (cb *CircuitBreakerRepository) ListSwitches(ctx context.Context) ([]models.Switch, error) {
+	return guard(cb, func() ([]models.Switch, error) { return cb.repository.ListSwitches(ctx) })
+}
+
+func (cb *CircuitBreakerRepository) StoreMetrics(ctx context.Context, metrics []models.TelemetryData) error {
+	return cb.call(func() error { return cb.repository.StoreMetrics(ctx, metrics) })
+}
+
+// BulkStoreMetrics uses the wrapped repository's bulk path when it has one
+func (cb *CircuitBreakerRepository) BulkStoreMetrics(ctx context.Context, metrics []models.TelemetryData) error {
+	return cb.call(func() error {
+		if repo, ok := cb.repository.(BulkMetricsStorer); ok {
+			return repo.BulkStoreMetrics(ctx, metrics)
+		}
+		return cb.repository.StoreMetrics(ctx, metrics)
+	})
+}
+
+func (cb *CircuitBreakerRepository) GetLatestMetrics(ctx context.Context, switchID string) (*models.TelemetryData, error) {
+	return guard(cb, func() (*models.TelemetryData, error) { return cb.repository.GetLatestMetrics(ctx, switchID) })
+}
+
+func (cb *CircuitBreakerRepository) GetHistoricalMetrics(ctx context.Context, query models.HistoryQuery) (*models.HistoryPage, error) {
+	return guard(cb, func() (*models.HistoryPage, error) { return cb.repository.GetHistoricalMetrics(ctx, query) })
+}
+
+func (cb *CircuitBreakerRepository) GetAggregatedMetrics(ctx context.Context, query models.HistoryQuery) ([]models.MetricBucket, error) {
+	return guard(cb, func() ([]models.MetricBucket, error) { return cb.repository.GetAggregatedMetrics(ctx, query) })
+}
+
+func (cb *CircuitBreakerRepository) GetSwitchMetricsSummary(ctx context.Context, switchID string, window time.Duration) (*models.SwitchSummary, error) {
+	return guard(cb, func() (*models.SwitchSummary, error) {
+		return cb.repository.GetSwitchMetricsSummary(ctx, switchID, window)
+	})
+}
+
+func (cb *CircuitBreakerRepository) GetTopSwitches(ctx context.Context, query models.TopSwitchesQuery) ([]models.RankedSwitch, error) {
+	return guard(cb, func() ([]models.RankedSwitch, error) { return cb.repository.GetTopSwitches(ctx, query) })
+}
+
+func (cb *CircuitBreakerRepository) DeleteOldMetrics(ctx context.Context, olderThan time.Time) error {
+	return cb.call(func() error { return cb.repository.DeleteOldMetrics(ctx, olderThan) })
+}
+
+func (cb *CircuitBreakerRepository) GetMetricsCount(ctx context.Context) (int64, error) {
+	return guard(cb, func() (int64, error) { return cb.repository.GetMetricsCount(ctx) })
+}
+
+// Ping fails fast while the circuit is open, so callers treat the database as unreachable
+func (cb *CircuitBreakerRepository) Ping(ctx context.Context) error {
+	return cb.call(func() error {
+		if pinger, ok := cb.repository.(Pinger); ok {
+			return pinger.Ping(ctx)
+		}
+		return nil
+	})
+}
+
+// Start starts the wrapped repository's background maintenance, if it has any
+func (cb *CircuitBreakerRepository) Start(ctx context.Context) error {
+	if starter, ok := cb.repository.(Starter); ok {
+		return starter.Start(ctx)
+	}
+	return nil
+}
+
+// Close closes the wrapped repository whatever the circuit state, so stopping
+// the store still releases the database connection
+func (cb *CircuitBreakerRepository) Close() error {
+	if closer, ok := cb.repository.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// timeoutRepository times out every call while down is set and counts the calls that reach it
+type timeoutRepository struct {
+	*MemoryRepository
+	down    atomic.Bool
+	calls   atomic.Int64
+	started atomic.Bool
+	closed  atomic.Bool
+}
+
+func (r *timeoutRepository) StoreMetrics(ctx context.Context, metrics []models.TelemetryData) error {
+	r.calls.Add(1)
+	if r.down.Load() {
+		return fmt.Errorf("failed to insert metrics: %w", context.DeadlineExceeded)
+	}
+	return r.MemoryRepository.StoreMetrics(ctx, metrics)
+}
+
+func (r *timeoutRepository) Ping(ctx context.Context) error {
+	r.calls.Add(1)
+	if r.down.Load() {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+func (r *timeoutRepository) Start(ctx context.Context) error {
+	r.started.Store(true)
+	return nil
+}
+
+func (r *timeoutRepository) Close() error {
+	r.closed.Store(true)
+	return nil
+}
+
+func TestCircuitBreaker_OpensFailsFastAndRecovers(t *testing.T) {
+	ctx := context.Background()
+	repo := &timeoutRepository{MemoryRepository: NewMemoryRepository(100)}
+	createSwitches(t, repo, "switch-001")
+	breaker := NewCircuitBreakerRepository(repo, CircuitBreakerConfig{FailureThreshold: 3, OpenTimeout: 30 * time.Millisecond}, nil)
+	batch := walBatch("switch-001", 40)
+
+	repo.down.Store(true)
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, breaker.StoreMetrics(ctx, batch), context.DeadlineExceeded)
+	}
+	assert.Equal(t, CircuitOpen, breaker.Status().State)
+
+	// Open: calls fail fast without reaching the database
+	assert.ErrorIs(t, breaker.StoreMetrics(ctx, batch), ErrCircuitOpen)
+	assert.ErrorIs(t, breaker.Ping(ctx), ErrCircuitOpen)
+	assert.Equal(t, int64(3), repo.calls.Load())
+	assert.Equal(t, int64(2), breaker.Status().RejectedCalls)
+
+	// A failed trial reopens the circuit
+	time.Sleep(40 * time.Millisecond)
+	assert.ErrorIs(t, breaker.StoreMetrics(ctx, batch), context.DeadlineExceeded)
+	assert.Equal(t, CircuitOpen, breaker.Status().State)
+
+	// A successful trial closes it
+	repo.down.Store(false)
+	time.Sleep(40 * time.Millisecond)
+	require.NoError(t, breaker.StoreMetrics(ctx, batch))
+	status := breaker.Status()
+	assert.Equal(t, CircuitClosed, status.State)
+	assert.Zero(t, status.ConsecutiveFailures)
+	assert.Nil(t, status.OpenedAt)
+}
+
+func TestCircuitBreaker_IgnoresRejectedRequests(t *testing.T) {
+	ctx := context.Background()
+	breaker := NewCircuitBreakerRepository(NewMemoryRepository(100), CircuitBreakerConfig{FailureThreshold: 1}, nil)
+
+	// Unknown switches are a problem with the request, not the database
+	for i := 0; i < 3; i++ {
+		assert.Error(t, breaker.StoreMetrics(ctx, walBatch("switch-unknown", 40)))
+	}
+	assert.Equal(t, CircuitClosed, breaker.Status().State)
+}
+
+func TestIsUnavailable(t *testing.T) {
+	tests := []struct {
+		err         error
+		unavailable bool
+	}{
+		{nil, false},
+		{errors.New("switch switch-001 not found"), false},
+		{fmt.Errorf("query: %w", context.Canceled), false},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), true},
+		{&pq.Error{Code: "08006"}, true},                          // connection failure
+		{&pq.Error{Code: "57014"}, true},                          // query canceled by statement_timeout
+		{fmt.Errorf("copy: %w", &pq.Error{Code: "23503"}), false}, // foreign key violation
+		{errors.New("dial tcp 127.0.0.1:5432: connect: connection refused"), true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.unavailable, IsUnavailable(tt.err), "%v", tt.err)
+	}
+}
+
+func TestHybridStore_SpillsWithoutRetryingWhileCircuitOpen(t *testing.T) {
+	ctx := context.Background()
+	repo := &timeoutRepository{MemoryRepository: NewMemoryRepository(100)}
+	createSwitches(t, repo, "switch-001")
+	breaker := NewCircuitBreakerRepository(repo, CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour}, nil)
+
+	wal, err := OpenWAL(t.TempDir(), WALConfig{}, nil)
+	require.NoError(t, err)
+	config := DefaultHybridStoreConfig()
+	config.MaxRetries = 3
+	config.BatchSize = 1
+	store := NewHybridStore(NewInMemoryCache(), breaker, config, nil, WithWAL(wal, 10*time.Millisecond))
+	require.NoError(t, store.Start(ctx))
+	defer store.Stop(ctx)
+
+	// Open the circuit
+	repo.down.Store(true)
+	require.Error(t, breaker.StoreMetrics(ctx, walBatch("switch-001", 40)))
+	calls := repo.calls.Load()
+
+	// Without failing fast the first retry alone would wait a second
+	require.NoError(t, store.UpdateMetrics("switch-001", models.TelemetryData{SwitchID: "switch-001", TemperatureC: 41}))
+	require.Eventually(t, func() bool { return wal.Batches() == 1 }, 500*time.Millisecond, 5*time.Millisecond)
+
+	// The batch waits in the WAL instead of being rejected, and the database is left alone
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int64(1), wal.Batches())
+	assert.Equal(t, calls, repo.calls.Load())
+}
+
+func TestHybridStore_StartsAndClosesRepositoryBehindCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	repo := &timeoutRepository{MemoryRepository: NewMemoryRepository(100)}
+	breaker := NewCircuitBreakerRepository(repo, CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour}, nil)
+	store := NewHybridStore(NewInMemoryCache(), breaker, DefaultHybridStoreConfig(), nil)
+	require.NoError(t, store.Start(ctx))
+	assert.True(t, repo.started.Load())
+
+	// An open circuit does not keep the connection from being closed
+	repo.down.Store(true)
+	require.Error(t, breaker.Ping(ctx))
+	require.Equal(t, CircuitOpen, breaker.Status().State)
+
+	require.NoError(t, store.Stop(ctx))
+	assert.True(t, repo.closed.Load())
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/monitoring/metrics"
+)
+
+// DefaultHealthCheckInterval is how often the database is checked when no interval is configured
+const DefaultHealthCheckInterval = 15 * time.Second
+
+// Database health states reported by DatabaseMonitor
+const (
+	DatabaseStatusOK          = "ok"
+	DatabaseStatusUnreachable = "unreachable"
+)
+
+// HealthChecker is implemented by repositories that can verify their database answers queries
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// PoolStatser is implemented by repositories backed by a database/sql connection pool
+type PoolStatser interface {
+	Stats() sql.DBStats
+}
+
+// PoolStats is a snapshot of the database connection pool
+type PoolStats struct {
+	MaxOpenConnections int     `json:"max_open_connections"`
+	OpenConnections    int     `json:"open_connections"`
+	InUse              int     `json:"in_use"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"wait_count"`
+	WaitDurationSecs   float64 `json:"wait_duration_seconds"`
+}
+
+// DatabaseHealth is the outcome of the latest database health check
+type DatabaseHealth struct {
+	Status              string     `json:"status"`
+	CheckedAt           time.Time  `json:"checked_at"`
+	LatencyMs           float64    `json:"latency_ms"`
+	Error               string     `json:"error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Pool                *PoolStats `json:"pool,omitempty"`
+}
+
+// Healthy reports whether the last check reached the database
+func (h DatabaseHealth) Healthy() bool {
+	return h.Status == DatabaseStatusOK
+}
+
+// DatabaseMonitor periodically checks that the telemetry database is reachable
+// and publishes its connection pool statistics. Repositories without a health
+// check or ping, such as the embedded backends, are always reported healthy.
+type DatabaseMonitor struct {
+	repository TelemetryRepository
+	interval   time.Duration
+	logger     log.Logger
+
+	mu     sync.RWMutex
+	health DatabaseHealth
+	waits  sql.DBStats // Last published stats, to turn cumulative wait totals into counter increments
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDatabaseMonitor creates a monitor for repository checked every interval
+func NewDatabaseMonitor(repository TelemetryRepository, interval time.Duration, logger log.Logger) *DatabaseMonitor {
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+	if logger == nil {
+		logger = log.DefaultLogger
+	}
+	return &DatabaseMonitor{
+		repository: repository,
+		interval:   interval,
+		logger:     logger,
+	}
+}
+
+// Start runs a first check so the state is known immediately, then keeps checking in the background
+func (m *DatabaseMonitor) Start(ctx context.Context) error {
+	m.Check(ctx)
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.wg.Add(1)
+	go m.run(ctx)
+	return nil
+}
+
+// Stop ends background checks
+func (m *DatabaseMonitor) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+		m.wg.Wait()
+	}
+}
+
+// Health returns the result of the latest check
+func (m *DatabaseMonitor) Health() DatabaseHealth {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.health
+}
+
+// Check runs one health check, publishes the pool metrics and returns the result
+func (m *DatabaseMonitor) Check(ctx context.Context) DatabaseHealth {
+	start := time.Now()
+	err := m.ping(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous := m.health
+	health := DatabaseHealth{
+		Status:    DatabaseStatusOK,
+		CheckedAt: start,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		health.Status = DatabaseStatusUnreachable
+		health.Error = err.Error()
+		health.ConsecutiveFailures = previous.ConsecutiveFailures + 1
+		metrics.ErrorsTotal.WithLabelValues("database", "health_check_failed").Inc()
+	}
+	if statser, ok := m.repository.(PoolStatser); ok {
+		health.Pool = m.publishPoolStats(statser.Stats())
+	}
+
+	switch {
+	case err != nil && previous.Status != DatabaseStatusUnreachable:
+		m.logger.Errorf("Database health check failed: %v", err)
+	case err == nil && previous.Status == DatabaseStatusUnreachable:
+		m.logger.Infof("Database reachable again after %d failed health checks", previous.ConsecutiveFailures)
+	}
+
+	m.health = health
+	return health
+}
+
+func (m *DatabaseMonitor) run(ctx context.Context) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// ping prefers a full health check over a connection ping
+func (m *DatabaseMonitor) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, m.interval)
+	defer cancel()
+
+	switch repository := m.repository.(type) {
+	case HealthChecker:
+		return repository.HealthCheck(ctx)
+	case Pinger:
+		return repository.Ping(ctx)
+	default:
+		return nil
+	}
+}
+
+// publishPoolStats updates the pool gauges and counters. Callers hold m.mu.
+func (m *DatabaseMonitor) publishPoolStats(stats sql.DBStats) *PoolStats {
+	metrics.DatabaseConnectionsOpen.Set(float64(stats.OpenConnections))
+	metrics.DatabaseConnectionsActive.Set(float64(stats.InUse))
+	metrics.DatabaseConnectionsIdle.Set(float64(stats.Idle))
+	metrics.DatabaseConnectionsMax.Set(float64(stats.MaxOpenConnections))
+
+	// The pool reports running totals, which reset when the pool is replaced
+	if stats.WaitCount >= m.waits.WaitCount {
+		metrics.DatabaseConnectionWaitsTotal.Add(float64(stats.WaitCount - m.waits.WaitCount))
+	}
+	if stats.WaitDuration >= m.waits.WaitDuration {
+		metrics.DatabaseConnectionWaitSeconds.Add((stats.WaitDuration - m.waits.WaitDuration).Seconds())
+	}
+	m.waits = stats
+
+	return &PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationSecs:   stats.WaitDuration.Seconds(),
+	}
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseMonitor_TracksReachability(t *testing.T) {
+	ctx := context.Background()
+	repo := &flakyRepository{MemoryRepository: NewMemoryRepository(10)}
+	monitor := NewDatabaseMonitor(repo, time.Hour, nil)
+
+	health := monitor.Check(ctx)
+	assert.True(t, health.Healthy())
+	assert.Nil(t, health.Pool, "the memory repository has no connection pool")
+
+	repo.down.Store(true)
+	monitor.Check(ctx)
+	health = monitor.Check(ctx)
+	assert.Equal(t, DatabaseStatusUnreachable, health.Status)
+	assert.Equal(t, 2, health.ConsecutiveFailures)
+	assert.NotEmpty(t, health.Error)
+	assert.Equal(t, health, monitor.Health())
+
+	repo.down.Store(false)
+	health = monitor.Check(ctx)
+	assert.True(t, health.Healthy())
+	assert.Zero(t, health.ConsecutiveFailures)
+}
+
+func TestDatabaseMonitor_EmbeddedRepositoryIsHealthy(t *testing.T) {
+	monitor := NewDatabaseMonitor(NewMemoryRepository(10), 0, nil)
+	assert.NoError(t, monitor.Start(context.Background()))
+	defer monitor.Stop()
+
+	assert.True(t, monitor.Health().Healthy())
+	assert.False(t, monitor.Health().CheckedAt.IsZero())
+}
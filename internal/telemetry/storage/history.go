@@ -0,0 +1,27 @@
+package storage
+
+import "github.com/ufm/internal/telemetry/models"
+
+const (
+	// DefaultHistoryPageSize is used when a history query does not set a limit
+	DefaultHistoryPageSize = 1000
+	// MaxHistoryPageSize caps the number of rows a single history page may return
+	MaxHistoryPageSize = 10000
+	// MaxHistoryBuckets caps the number of windows a downsampled query may produce
+	MaxHistoryBuckets = 10000
+)
+
+// newHistoryPage trims rows fetched with limit+1 to a page and sets the next cursor
+// when the extra row shows that more data is available.
+func newHistoryPage(rows []models.TelemetryData, limit int) *models.HistoryPage {
+	page := &models.HistoryPage{Data: rows}
+	if len(rows) > limit {
+		page.Data = rows[:limit]
+		last := page.Data[limit-1]
+		page.NextCursor = &models.HistoryCursor{
+			Timestamp: last.Timestamp,
+			ID:        last.ID,
+		}
+	}
+	return page
+}
@@ -2,12 +2,15 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"runtime"
 	"sync"
 	"time"
 
 	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/monitoring/metrics"
 	"github.com/ufm/internal/telemetry/models"
 )
 
@@ -21,13 +24,38 @@ type TelemetryRepository interface {
 	// Telemetry data operations
 	StoreMetrics(ctx context.Context, metrics []models.TelemetryData) error
 	GetLatestMetrics(ctx context.Context, switchID string) (*models.TelemetryData, error)
-	GetHistoricalMetrics(ctx context.Context, switchID string, from, to time.Time) ([]models.TelemetryData, error)
+	GetHistoricalMetrics(ctx context.Context, query models.HistoryQuery) (*models.HistoryPage, error)
+	GetAggregatedMetrics(ctx context.Context, query models.HistoryQuery) ([]models.MetricBucket, error)
+	GetSwitchMetricsSummary(ctx context.Context, switchID string, window time.Duration) (*models.SwitchSummary, error)
+	GetTopSwitches(ctx context.Context, query models.TopSwitchesQuery) ([]models.RankedSwitch, error)
 
 	// Utility operations
 	DeleteOldMetrics(ctx context.Context, olderThan time.Time) error
 	GetMetricsCount(ctx context.Context) (int64, error)
 }
 
+// ErrInvalidData is wrapped by repository writes rejected for the data itself, such
+// as samples of an unknown switch. Retrying such a write can never succeed.
+var ErrInvalidData = errors.New("invalid telemetry data")
+
+// BulkMetricsStorer is implemented by repositories with a faster write path for
+// large batches, such as PostgreSQL's COPY. Other repositories use StoreMetrics.
+type BulkMetricsStorer interface {
+	BulkStoreMetrics(ctx context.Context, metrics []models.TelemetryData) error
+}
+
+// Pinger is implemented by repositories backed by a database that can become
+// unreachable. Repositories without it are assumed to always be reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Starter is implemented by repositories with background maintenance, which runs
+// from Start until the repository is closed
+type Starter interface {
+	Start(ctx context.Context) error
+}
+
 // TelemetryCache defines the interface for in-memory cache operations
 type TelemetryCache interface {
 	// Write operations
@@ -38,6 +66,7 @@ type TelemetryCache interface {
 	GetMetric(switchID string, metricType models.MetricType) (interface{}, error)
 	GetAllMetrics(switchID string) (*models.TelemetryData, error)
 	ListAllSwitches() map[string]*models.TelemetryData
+	TopSwitches(metricType models.MetricType, k int, descending bool) ([]models.RankedSwitch, error)
 
 	// Utility operations
 	GetSwitchCount() int
@@ -61,6 +90,12 @@ type TelemetryStore interface {
 	LoadFromDatabase(ctx context.Context) error
 	StoreMetricsBulk(ctx context.Context, metrics []models.TelemetryData) error
 
+	// Historical queries
+	GetHistoricalMetrics(ctx context.Context, query models.HistoryQuery) (*models.HistoryPage, error)
+	GetAggregatedMetrics(ctx context.Context, query models.HistoryQuery) ([]models.MetricBucket, error)
+	GetSwitchMetricsSummary(ctx context.Context, switchID string, window time.Duration) (*models.SwitchSummary, error)
+	GetTopSwitches(ctx context.Context, query models.TopSwitchesQuery) ([]models.RankedSwitch, error)
+
 	// Lifecycle operations
 	Start(ctx context.Context) error
 	Stop(ctx context.Context) error
@@ -75,6 +110,7 @@ type HybridStoreConfig struct {
 	BatchSize     int           // Maximum batch size for database writes
 	CacheTTL      time.Duration // How long to keep data in cache
 	MaxRetries    int           // Maximum retry attempts for database operations
+	FlushWorkers  int           // Parallel database writers
 }
 
 // DefaultHybridStoreConfig returns sensible defaults
@@ -84,6 +120,58 @@ func DefaultHybridStoreConfig() HybridStoreConfig {
 		BatchSize:     100,
 		CacheTTL:      5 * time.Minute,
 		MaxRetries:    3,
+		FlushWorkers:  4,
+	}
+}
+
+// withDefaults replaces unset or invalid values with the defaults
+func (c HybridStoreConfig) withDefaults() HybridStoreConfig {
+	defaults := DefaultHybridStoreConfig()
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaults.FlushInterval
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaults.BatchSize
+	}
+	if c.CacheTTL <= 0 {
+		c.CacheTTL = defaults.CacheTTL
+	}
+	if c.MaxRetries <= 0 {
+		c.MaxRetries = defaults.MaxRetries
+	}
+	if c.FlushWorkers <= 0 {
+		c.FlushWorkers = defaults.FlushWorkers
+	}
+	return c
+}
+
+// UpdatePublisher receives every sample written to the cache, e.g. to stream it to clients
+type UpdatePublisher interface {
+	Publish(samples []models.TelemetryData)
+}
+
+// HybridStoreOption configures optional hybrid store components
+type HybridStoreOption func(*HybridStore)
+
+// WithUpdatePublisher publishes every cache update to publisher
+func WithUpdatePublisher(publisher UpdatePublisher) HybridStoreOption {
+	return func(hs *HybridStore) {
+		hs.publisher = publisher
+	}
+}
+
+// WithWAL buffers batches that cannot be written to the database in wal instead
+// of dropping them: when the flush queue is full, when writes fail after all
+// retries and when the store stops with batches still queued. Buffered batches,
+// including those left by a previous run, are written back every drainInterval
+// once the database accepts writes again.
+func WithWAL(wal *WAL, drainInterval time.Duration) HybridStoreOption {
+	return func(hs *HybridStore) {
+		if drainInterval <= 0 {
+			drainInterval = DefaultWALDrainInterval
+		}
+		hs.wal = wal
+		hs.walDrainInterval = drainInterval
 	}
 }
 
@@ -91,14 +179,26 @@ func DefaultHybridStoreConfig() HybridStoreConfig {
 type HybridStore struct {
 	cache      TelemetryCache
 	repository TelemetryRepository
-	config     HybridStoreConfig
+	config     HybridStoreConfig // Guarded by mu, see UpdateConfig
 	logger     log.Logger
+	publisher  UpdatePublisher
+
+	// Closed and replaced whenever config changes
+	configUpdated chan struct{}
+
+	// Durable overflow for batches the database has not accepted
+	wal              *WAL
+	walDrainInterval time.Duration
 
 	// Background processing
-	ctx        context.Context
-	cancel     context.CancelFunc
-	wg         sync.WaitGroup
-	flushQueue chan []models.TelemetryData
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+	flushQueue    chan []models.TelemetryData
+	writeQueue    chan flushBatch
+	flushRequests chan chan []models.TelemetryData
+	writeCtx      context.Context // Outlives ctx by the shutdown flush budget
+	cancelWrites  context.CancelFunc
 
 	// Performance metrics
 	mu                 sync.RWMutex
@@ -117,19 +217,26 @@ func NewHybridStore(
 	repository TelemetryRepository,
 	config HybridStoreConfig,
 	logger log.Logger,
+	options ...HybridStoreOption,
 ) *HybridStore {
 	if logger == nil {
 		logger = log.DefaultLogger
 	}
 
-	return &HybridStore{
-		cache:      cache,
-		repository: repository,
-		config:     config,
-		logger:     logger,
-		flushQueue: make(chan []models.TelemetryData, 100), // Buffered channel
-		startTime:  time.Now(),
+	hs := &HybridStore{
+		cache:         cache,
+		repository:    repository,
+		config:        config.withDefaults(),
+		configUpdated: make(chan struct{}),
+		logger:        logger,
+		flushQueue:    make(chan []models.TelemetryData, 100), // Buffered channel
+		flushRequests: make(chan chan []models.TelemetryData),
+		startTime:     time.Now(),
 	}
+	for _, option := range options {
+		option(hs)
+	}
+	return hs
 }
 
 // Start initializes the hybrid store and starts background processes
@@ -141,12 +248,23 @@ func (hs *HybridStore) Start(ctx context.Context) error {
 		return fmt.Errorf("hybrid store already started")
 	}
 
+	if starter, ok := hs.repository.(Starter); ok {
+		if err := starter.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start telemetry repository: %w", err)
+		}
+	}
+
 	hs.ctx, hs.cancel = context.WithCancel(ctx)
+	hs.writeCtx, hs.cancelWrites = context.WithCancel(context.WithoutCancel(ctx))
 	hs.startTime = time.Now()
 
-	// Start background flush worker
-	hs.wg.Add(1)
-	go hs.flushWorker()
+	// Start the coalescing batch writer
+	hs.writeQueue = make(chan flushBatch, hs.config.FlushWorkers)
+	hs.wg.Add(1 + hs.config.FlushWorkers)
+	go hs.coalesceWorker()
+	for i := 0; i < hs.config.FlushWorkers; i++ {
+		go hs.writeWorker()
+	}
 
 	// Start periodic cache cleanup
 	hs.wg.Add(1)
@@ -156,10 +274,48 @@ func (hs *HybridStore) Start(ctx context.Context) error {
 	hs.wg.Add(1)
 	go hs.periodicFlushWorker()
 
-	hs.logger.Infof("Hybrid telemetry store started with flush interval: %v", hs.config.FlushInterval)
+	// Replay and drain the write-ahead log
+	if hs.wal != nil {
+		hs.wg.Add(1)
+		go hs.walWorker()
+	}
+
+	hs.logger.Infof("Hybrid telemetry store started with flush interval: %v, batch size: %d, flush workers: %d",
+		hs.config.FlushInterval, hs.config.BatchSize, hs.config.FlushWorkers)
 	return nil
 }
 
+// UpdateConfig applies new flush, batching, cache and retry settings. Running
+// workers pick them up immediately; the number of flush workers is fixed once
+// the store has started.
+func (hs *HybridStore) UpdateConfig(config HybridStoreConfig) {
+	config = config.withDefaults()
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if hs.ctx != nil && config.FlushWorkers != hs.config.FlushWorkers {
+		hs.logger.Warnf("Changing flush workers from %d to %d requires a restart", hs.config.FlushWorkers, config.FlushWorkers)
+		config.FlushWorkers = hs.config.FlushWorkers
+	}
+	if config == hs.config {
+		return
+	}
+
+	hs.config = config
+	close(hs.configUpdated)
+	hs.configUpdated = make(chan struct{})
+	hs.logger.Infof("Hybrid telemetry store settings updated: flush interval: %v, batch size: %d, cache TTL: %v, max retries: %d",
+		config.FlushInterval, config.BatchSize, config.CacheTTL, config.MaxRetries)
+}
+
+// currentConfig returns the settings in effect and a channel closed when they change
+func (hs *HybridStore) currentConfig() (HybridStoreConfig, <-chan struct{}) {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+	return hs.config, hs.configUpdated
+}
+
 // Stop gracefully shuts down the hybrid store
 func (hs *HybridStore) Stop(ctx context.Context) error {
 	hs.mu.Lock()
@@ -181,9 +337,11 @@ func (hs *HybridStore) Stop(ctx context.Context) error {
 	select {
 	case <-done:
 		hs.logger.Infof("Hybrid telemetry store stopped gracefully")
+		hs.cancelWrites()
+		hs.closeWAL()
 
 		// Close database connection
-		if repo, ok := hs.repository.(*PostgreSQLRepository); ok {
+		if repo, ok := hs.repository.(io.Closer); ok {
 			if err := repo.Close(); err != nil {
 				hs.logger.Warnf("Failed to close database connection: %v", err)
 			} else {
@@ -194,9 +352,11 @@ func (hs *HybridStore) Stop(ctx context.Context) error {
 		return nil
 	case <-ctx.Done():
 		hs.logger.Warnf("Hybrid telemetry store stop timed out: %v", ctx.Err())
+		hs.cancelWrites()
+		hs.closeWAL()
 
 		// Still try to close database connection even on timeout
-		if repo, ok := hs.repository.(*PostgreSQLRepository); ok {
+		if repo, ok := hs.repository.(io.Closer); ok {
 			if err := repo.Close(); err != nil {
 				hs.logger.Warnf("Failed to close database connection: %v", err)
 			}
@@ -215,6 +375,7 @@ func (hs *HybridStore) UpdateMetrics(switchID string, data models.TelemetryData)
 	if err != nil {
 		return fmt.Errorf("failed to update cache: %w", err)
 	}
+	hs.publish([]models.TelemetryData{data})
 
 	// Queue for database write (non-blocking)
 	select {
@@ -223,8 +384,8 @@ func (hs *HybridStore) UpdateMetrics(switchID string, data models.TelemetryData)
 		hs.pendingFlushItems++
 		hs.mu.Unlock()
 	default:
-		// Queue is full, log warning but don't block
-		hs.logger.Warnf("Flush queue is full, dropping telemetry data for switch %s", switchID)
+		// Queue is full, don't block
+		hs.spill([]models.TelemetryData{data}, "Flush queue is full")
 	}
 
 	hs.incrementRequestCount()
@@ -245,6 +406,7 @@ func (hs *HybridStore) UpdateBatch(data map[string]models.TelemetryData) error {
 		telemetryData.SwitchID = switchID
 		metrics = append(metrics, telemetryData)
 	}
+	hs.publish(metrics)
 
 	// Queue for database write (non-blocking)
 	if len(metrics) > 0 {
@@ -254,7 +416,7 @@ func (hs *HybridStore) UpdateBatch(data map[string]models.TelemetryData) error {
 			hs.pendingFlushItems += len(metrics)
 			hs.mu.Unlock()
 		default:
-			hs.logger.Warnf("Flush queue is full, dropping %d telemetry records", len(metrics))
+			hs.spill(metrics, "Flush queue is full")
 		}
 	}
 
@@ -278,9 +440,25 @@ func (hs *HybridStore) StoreMetricsBulk(ctx context.Context, metrics []models.Te
 	if err != nil {
 		return fmt.Errorf("failed to update cache batch: %w", err)
 	}
+	hs.publish(metrics)
 
-	// Store all records to database (for historical data)
-	return hs.writeToDatabase(ctx, metrics)
+	// Store all records to database (for historical data) through the batch
+	// writer. A full queue holds the caller back instead of dropping rows.
+	runCtx := hs.runContext()
+	if runCtx == nil {
+		return hs.writeToDatabase(ctx, metrics)
+	}
+	select {
+	case hs.flushQueue <- metrics:
+		hs.mu.Lock()
+		hs.pendingFlushItems += len(metrics)
+		hs.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-runCtx.Done():
+		return fmt.Errorf("hybrid store stopped")
+	}
 }
 
 // Read operations delegate to cache for speed
@@ -302,6 +480,12 @@ func (hs *HybridStore) ListAllSwitches() map[string]*models.TelemetryData {
 	return hs.cache.ListAllSwitches()
 }
 
+func (hs *HybridStore) TopSwitches(metricType models.MetricType, k int, descending bool) ([]models.RankedSwitch, error) {
+	hs.incrementRequestCount()
+	hs.incrementCacheHit()
+	return hs.cache.TopSwitches(metricType, k, descending)
+}
+
 func (hs *HybridStore) GetSwitchCount() int {
 	return hs.cache.GetSwitchCount()
 }
@@ -342,8 +526,18 @@ func (hs *HybridStore) ListSwitches(ctx context.Context) ([]models.Switch, error
 func (hs *HybridStore) FlushToDatabase(ctx context.Context) error {
 	hs.logger.Debugf("Manual flush to database requested")
 
-	// Collect all pending items from queue
+	// Take over the rows the batch writer is holding back for a fuller batch
 	var allMetrics []models.TelemetryData
+	if runCtx := hs.runContext(); runCtx != nil {
+		reply := make(chan []models.TelemetryData, 1)
+		select {
+		case hs.flushRequests <- reply:
+			allMetrics = <-reply
+		case <-runCtx.Done():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 
 	// Drain the queue with timeout
 	flushCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -365,7 +559,7 @@ flushData:
 		return nil
 	}
 
-	return hs.writeToDatabase(ctx, allMetrics)
+	return hs.writeOrSpill(ctx, allMetrics)
 }
 
 // LoadFromDatabase loads recent data from database into cache
@@ -399,6 +593,30 @@ func (hs *HybridStore) LoadFromDatabase(ctx context.Context) error {
 	return nil
 }
 
+// GetHistoricalMetrics reads a page of persisted metrics from the database
+func (hs *HybridStore) GetHistoricalMetrics(ctx context.Context, query models.HistoryQuery) (*models.HistoryPage, error) {
+	hs.incrementRequestCount()
+	return hs.repository.GetHistoricalMetrics(ctx, query)
+}
+
+// GetAggregatedMetrics reads downsampled metrics from the database
+func (hs *HybridStore) GetAggregatedMetrics(ctx context.Context, query models.HistoryQuery) ([]models.MetricBucket, error) {
+	hs.incrementRequestCount()
+	return hs.repository.GetAggregatedMetrics(ctx, query)
+}
+
+// GetSwitchMetricsSummary reads summary statistics for a switch from the database
+func (hs *HybridStore) GetSwitchMetricsSummary(ctx context.Context, switchID string, window time.Duration) (*models.SwitchSummary, error) {
+	hs.incrementRequestCount()
+	return hs.repository.GetSwitchMetricsSummary(ctx, switchID, window)
+}
+
+// GetTopSwitches ranks switches by a metric aggregated over a trailing window in the database
+func (hs *HybridStore) GetTopSwitches(ctx context.Context, query models.TopSwitchesQuery) ([]models.RankedSwitch, error) {
+	hs.incrementRequestCount()
+	return hs.repository.GetTopSwitches(ctx, query)
+}
+
 // GetPerformanceMetrics returns comprehensive performance statistics
 func (hs *HybridStore) GetPerformanceMetrics() *models.PerformanceMetrics {
 	hs.mu.RLock()
@@ -434,51 +652,12 @@ func (hs *HybridStore) GetPerformanceMetrics() *models.PerformanceMetrics {
 
 // Background workers
 
-// flushWorker handles background database writes
-func (hs *HybridStore) flushWorker() {
-	defer hs.wg.Done()
-
-	for {
-		select {
-		case <-hs.ctx.Done():
-			// Flush remaining items before shutdown with shorter timeout
-			flushCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-			defer cancel()
-
-			// Process remaining items in the queue quickly
-			processed := 0
-			for {
-				select {
-				case metrics := <-hs.flushQueue:
-					if err := hs.writeToDatabase(flushCtx, metrics); err != nil {
-						hs.logger.Warnf("Failed to flush metrics during shutdown: %v", err)
-					} else {
-						processed += len(metrics)
-					}
-				case <-flushCtx.Done():
-					if processed > 0 {
-						hs.logger.Infof("Flushed %d metrics during shutdown", processed)
-					}
-					return
-				default:
-					if processed > 0 {
-						hs.logger.Infof("Flushed %d metrics during shutdown", processed)
-					}
-					return
-				}
-			}
-
-		case metrics := <-hs.flushQueue:
-			hs.writeToDatabase(hs.ctx, metrics)
-		}
-	}
-}
-
 // periodicFlushWorker triggers periodic cache cleanup and metrics reporting
 func (hs *HybridStore) periodicFlushWorker() {
 	defer hs.wg.Done()
 
-	ticker := time.NewTicker(hs.config.FlushInterval)
+	config, updated := hs.currentConfig()
+	ticker := time.NewTicker(config.FlushInterval)
 	defer ticker.Stop()
 
 	for {
@@ -486,6 +665,9 @@ func (hs *HybridStore) periodicFlushWorker() {
 		case <-hs.ctx.Done():
 			hs.logger.Debugf("Periodic flush worker shutting down")
 			return
+		case <-updated:
+			config, updated = hs.currentConfig()
+			ticker.Reset(config.FlushInterval)
 		case <-ticker.C:
 			// Log performance metrics periodically
 			metrics := hs.GetPerformanceMetrics()
@@ -498,7 +680,8 @@ func (hs *HybridStore) periodicFlushWorker() {
 func (hs *HybridStore) cleanupWorker() {
 	defer hs.wg.Done()
 
-	ticker := time.NewTicker(hs.config.CacheTTL)
+	config, updated := hs.currentConfig()
+	ticker := time.NewTicker(config.CacheTTL)
 	defer ticker.Stop()
 
 	for {
@@ -506,8 +689,11 @@ func (hs *HybridStore) cleanupWorker() {
 		case <-hs.ctx.Done():
 			hs.logger.Debugf("Cleanup worker shutting down")
 			return
+		case <-updated:
+			config, updated = hs.currentConfig()
+			ticker.Reset(config.CacheTTL)
 		case <-ticker.C:
-			cleaned := hs.cache.CleanupStale(hs.config.CacheTTL)
+			cleaned := hs.cache.CleanupStale(config.CacheTTL)
 			if cleaned > 0 {
 				hs.logger.Debugf("Cleaned up %d stale cache entries", cleaned)
 			}
@@ -521,8 +707,11 @@ func (hs *HybridStore) writeToDatabase(ctx context.Context, metrics []models.Tel
 		return nil
 	}
 
+	config, _ := hs.currentConfig()
+
 	var err error
-	for attempt := 1; attempt <= hs.config.MaxRetries; attempt++ {
+	attempt := 1
+	for ; attempt <= config.MaxRetries; attempt++ {
 		// Check if context is cancelled before attempting database write
 		select {
 		case <-ctx.Done():
@@ -530,13 +719,11 @@ func (hs *HybridStore) writeToDatabase(ctx context.Context, metrics []models.Tel
 		default:
 		}
 
-		// Use bulk insert for better performance
-		if repo, ok := hs.repository.(*PostgreSQLRepository); ok {
-			err = repo.BulkStoreMetrics(ctx, metrics)
-		} else {
-			err = hs.repository.StoreMetrics(ctx, metrics)
+		err = hs.storeBatch(ctx, metrics)
+		if errors.Is(err, ErrCircuitOpen) {
+			// Retrying would only wait for a database known to be down
+			break
 		}
-
 		if err == nil {
 			hs.mu.Lock()
 			hs.totalDBWrites += int64(len(metrics))
@@ -548,10 +735,10 @@ func (hs *HybridStore) writeToDatabase(ctx context.Context, metrics []models.Tel
 			return nil
 		}
 
-		if attempt < hs.config.MaxRetries {
+		if attempt < config.MaxRetries {
 			backoff := time.Duration(attempt) * time.Second
 			hs.logger.Warnf("Database write failed (attempt %d/%d), retrying in %v: %v",
-				attempt, hs.config.MaxRetries, backoff, err)
+				attempt, config.MaxRetries, backoff, err)
 
 			select {
 			case <-ctx.Done():
@@ -566,10 +753,157 @@ func (hs *HybridStore) writeToDatabase(ctx context.Context, metrics []models.Tel
 	hs.totalDBWriteErrors++
 	hs.mu.Unlock()
 
-	return fmt.Errorf("failed to write metrics after %d attempts: %w", hs.config.MaxRetries, err)
+	return fmt.Errorf("failed to write metrics after %d attempts: %w", min(attempt, config.MaxRetries), err)
+}
+
+// storeBatch makes a single database write attempt
+func (hs *HybridStore) storeBatch(ctx context.Context, metrics []models.TelemetryData) error {
+	// Use bulk insert for better performance
+	if repo, ok := hs.repository.(BulkMetricsStorer); ok {
+		return repo.BulkStoreMetrics(ctx, metrics)
+	}
+	return hs.repository.StoreMetrics(ctx, metrics)
+}
+
+// writeOrSpill writes a dequeued batch to the database and spills it if that fails
+func (hs *HybridStore) writeOrSpill(ctx context.Context, metrics []models.TelemetryData) error {
+	err := hs.writeToDatabase(ctx, metrics)
+	if err == nil {
+		return nil
+	}
+
+	hs.mu.Lock()
+	hs.pendingFlushItems -= len(metrics)
+	hs.mu.Unlock()
+
+	hs.spill(metrics, "Database write failed")
+	return err
+}
+
+// spill stores a batch that cannot be written now in the WAL, or drops it when
+// there is no WAL or it cannot be written either
+func (hs *HybridStore) spill(metrics []models.TelemetryData, reason string) {
+	if hs.wal == nil {
+		hs.logger.Warnf("%s, dropping %d telemetry records", reason, len(metrics))
+		return
+	}
+	if err := hs.wal.Append(metrics); err != nil {
+		hs.logger.Errorf("%s and the write-ahead log failed, dropping %d telemetry records: %v", reason, len(metrics), err)
+		return
+	}
+	hs.logger.Debugf("%s, buffered %d telemetry records in the write-ahead log", reason, len(metrics))
+}
+
+// walWorker replays batches left by a previous run, then drains the WAL
+// whenever it holds batches and the database accepts writes again
+func (hs *HybridStore) walWorker() {
+	defer hs.wg.Done()
+
+	ticker := time.NewTicker(hs.walDrainInterval)
+	defer ticker.Stop()
+
+	for {
+		if hs.wal.Batches() > 0 {
+			hs.drainWAL()
+		}
+
+		select {
+		case <-hs.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drainWAL writes buffered batches to the database until one fails
+func (hs *HybridStore) drainWAL() {
+	drained, err := hs.wal.Drain(func(batch []models.TelemetryData) error {
+		// One attempt per batch, a failure leaves the rest for the next tick
+		err := hs.storeBatch(hs.ctx, batch)
+		if isDataError(err) {
+			// Rows the database refuses as invalid will never be accepted, so they
+			// must not hold up the batches behind them
+			err = hs.storeRows(batch)
+		}
+		if err != nil {
+			return err
+		}
+
+		hs.mu.Lock()
+		hs.totalDBWrites += int64(len(batch))
+		hs.lastFlushTime = time.Now()
+		hs.mu.Unlock()
+		return nil
+	})
+
+	if drained > 0 {
+		hs.logger.Infof("Wrote %d buffered telemetry batches from the write-ahead log to the database", drained)
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		hs.logger.Debugf("Write-ahead log drain waiting for the database circuit breaker with %d batches pending", hs.wal.Batches())
+	} else if err != nil && hs.ctx.Err() == nil {
+		hs.logger.Warnf("Write-ahead log drain paused with %d batches pending: %v", hs.wal.Batches(), err)
+	}
+}
+
+// storeRows writes a buffered batch one row at a time and discards the rows the
+// database rejects as invalid. If a row fails for another reason, the WAL keeps
+// only that row and the ones after it, so rows already written are not repeated.
+func (hs *HybridStore) storeRows(batch []models.TelemetryData) error {
+	rejected := 0
+	defer func() {
+		if rejected > 0 {
+			hs.logger.Errorf("Discarded %d of %d buffered telemetry records rejected by the database", rejected, len(batch))
+			metrics.WALBatchesTotal.WithLabelValues("rejected").Inc()
+		}
+	}()
+
+	for i := range batch {
+		err := hs.storeBatch(hs.ctx, batch[i:i+1])
+		if isDataError(err) {
+			rejected++
+			hs.logger.Warnf("Discarding buffered telemetry record for switch %s rejected by the database: %v", batch[i].SwitchID, err)
+			continue
+		}
+		if err != nil {
+			return &partialWriteError{written: i, err: err}
+		}
+	}
+	return nil
+}
+
+// isDataError reports whether a repository rejected the data itself rather
+// than failing to reach the database
+func isDataError(err error) bool {
+	return errors.Is(err, ErrInvalidData)
+}
+
+// closeWAL closes the WAL, leaving pending batches for the next run
+func (hs *HybridStore) closeWAL() {
+	if hs.wal == nil {
+		return
+	}
+	if err := hs.wal.Close(); err != nil {
+		hs.logger.Warnf("Failed to close write-ahead log: %v", err)
+	}
+}
+
+// publish hands cache updates to the configured publisher, if any
+func (hs *HybridStore) publish(metrics []models.TelemetryData) {
+	if hs.publisher != nil {
+		hs.publisher.Publish(metrics)
+	}
+}
+
+// runContext returns the context of the running store, nil before Start
+func (hs *HybridStore) runContext() context.Context {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+	return hs.ctx
 }
 
 // Performance tracking helpers
+
 func (hs *HybridStore) incrementRequestCount() {
 	hs.mu.Lock()
 	hs.totalRequests++
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// DefaultMemoryRepositoryCapacity is the number of rows kept when no capacity is configured
+const DefaultMemoryRepositoryCapacity = 100000
+
+// MemoryRepository implements TelemetryRepository in process memory. Rows are
+// kept in a fixed-size ring buffer, so once it is full every write evicts the
+// oldest rows. Nothing survives a restart; it is meant for tests and demo mode.
+type MemoryRepository struct {
+	mu       sync.RWMutex
+	switches map[string]models.Switch
+	rows     []models.TelemetryData // Ring buffer, oldest row at start
+	start    int
+	size     int
+	nextID   int64
+}
+
+// NewMemoryRepository creates an in-memory repository holding at most capacity rows
+func NewMemoryRepository(capacity int) *MemoryRepository {
+	if capacity <= 0 {
+		capacity = DefaultMemoryRepositoryCapacity
+	}
+	return &MemoryRepository{
+		switches: make(map[string]models.Switch),
+		rows:     make([]models.TelemetryData, capacity),
+		nextID:   1,
+	}
+}
+
+// CreateSwitch creates a switch or updates the name and location of an existing one
+func (r *MemoryRepository) CreateSwitch(ctx context.Context, sw models.Switch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.switches[sw.ID]; ok {
+		sw.Created = existing.Created
+	} else if sw.Created.IsZero() {
+		sw.Created = time.Now()
+	}
+	r.switches[sw.ID] = sw
+	return nil
+}
+
+// GetSwitch retrieves a switch by ID
+func (r *MemoryRepository) GetSwitch(ctx context.Context, switchID string) (*models.Switch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sw, ok := r.switches[switchID]
+	if !ok {
+		return nil, fmt.Errorf("switch %s not found", switchID)
+	}
+	return &sw, nil
+}
+
+// ListSwitches retrieves all switches ordered by creation time
+func (r *MemoryRepository) ListSwitches(ctx context.Context) ([]models.Switch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	switches := make([]models.Switch, 0, len(r.switches))
+	for _, sw := range r.switches {
+		switches = append(switches, sw)
+	}
+	sort.Slice(switches, func(i, j int) bool {
+		if !switches[i].Created.Equal(switches[j].Created) {
+			return switches[i].Created.Before(switches[j].Created)
+		}
+		return switches[i].ID < switches[j].ID
+	})
+	return switches, nil
+}
+
+// StoreMetrics appends metrics to the ring, evicting the oldest rows when it is full
+func (r *MemoryRepository) StoreMetrics(ctx context.Context, metrics []models.TelemetryData) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Validate the whole batch first so a rejected batch stores nothing
+	for _, metric := range metrics {
+		if _, ok := r.switches[metric.SwitchID]; !ok {
+			return fmt.Errorf("failed to insert metric for switch %s: %w: switch not found", metric.SwitchID, ErrInvalidData)
+		}
+	}
+
+	now := time.Now()
+	for _, metric := range metrics {
+		row := withDefaultTimes(metric, now)
+		row.ID = r.nextID
+		r.nextID++
+		r.append(row)
+	}
+	return nil
+}
+
+// GetLatestMetrics retrieves the most recent metrics for a switch
+func (r *MemoryRepository) GetLatestMetrics(ctx context.Context, switchID string) (*models.TelemetryData, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *models.TelemetryData
+	r.each(func(row *models.TelemetryData) {
+		if row.SwitchID == switchID && (latest == nil || !row.Timestamp.Before(latest.Timestamp)) {
+			latest = row
+		}
+	})
+	if latest == nil {
+		return nil, fmt.Errorf("no metrics found for switch %s", switchID)
+	}
+
+	metric := *latest
+	return &metric, nil
+}
+
+// GetHistoricalMetrics retrieves one page of metrics for a switch within a time range
+func (r *MemoryRepository) GetHistoricalMetrics(ctx context.Context, query models.HistoryQuery) (*models.HistoryPage, error) {
+	rows := r.collect(func(row *models.TelemetryData) bool {
+		return row.SwitchID == query.SwitchID && inRange(row.Timestamp, query.From, query.To)
+	})
+	return selectHistoryPage(rows, query), nil
+}
+
+// GetAggregatedMetrics downsamples a switch's metrics into windows of query.Step aligned to query.From
+func (r *MemoryRepository) GetAggregatedMetrics(ctx context.Context, query models.HistoryQuery) ([]models.MetricBucket, error) {
+	rows := r.collect(func(row *models.TelemetryData) bool {
+		return row.SwitchID == query.SwitchID && inRange(row.Timestamp, query.From, query.To)
+	})
+	return aggregateRows(rows, query)
+}
+
+// GetSwitchMetricsSummary returns avg/max/p50/p99 per metric for a switch over the trailing window
+func (r *MemoryRepository) GetSwitchMetricsSummary(ctx context.Context, switchID string, window time.Duration) (*models.SwitchSummary, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("summary window must be positive")
+	}
+
+	since := time.Now().Add(-window)
+	rows := r.collect(func(row *models.TelemetryData) bool {
+		return row.SwitchID == switchID && row.Timestamp.After(since)
+	})
+	return summarizeRows(switchID, window, rows), nil
+}
+
+// GetTopSwitches ranks switches by a metric reduced over the trailing query.Window
+func (r *MemoryRepository) GetTopSwitches(ctx context.Context, query models.TopSwitchesQuery) ([]models.RankedSwitch, error) {
+	if query.Window <= 0 {
+		return nil, fmt.Errorf("ranking window must be positive")
+	}
+
+	since := time.Now().Add(-query.Window)
+	rows := r.collect(func(row *models.TelemetryData) bool {
+		return row.Timestamp.After(since)
+	})
+	return rankRows(rows, query)
+}
+
+// DeleteOldMetrics removes metrics created before olderThan
+func (r *MemoryRepository) DeleteOldMetrics(ctx context.Context, olderThan time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := make([]models.TelemetryData, 0, r.size)
+	r.each(func(row *models.TelemetryData) {
+		if !row.CreatedAt.Before(olderThan) {
+			kept = append(kept, *row)
+		}
+	})
+
+	r.start, r.size = 0, 0
+	for _, row := range kept {
+		r.append(row)
+	}
+	return nil
+}
+
+// GetMetricsCount returns the number of rows currently held
+func (r *MemoryRepository) GetMetricsCount(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return int64(r.size), nil
+}
+
+// append writes a row at the end of the ring, overwriting the oldest when full
+func (r *MemoryRepository) append(row models.TelemetryData) {
+	capacity := len(r.rows)
+	if r.size < capacity {
+		r.rows[(r.start+r.size)%capacity] = row
+		r.size++
+		return
+	}
+	r.rows[r.start] = row
+	r.start = (r.start + 1) % capacity
+}
+
+// each visits the held rows from oldest to newest
+func (r *MemoryRepository) each(fn func(*models.TelemetryData)) {
+	capacity := len(r.rows)
+	for i := 0; i < r.size; i++ {
+		fn(&r.rows[(r.start+i)%capacity])
+	}
+}
+
+// collect copies the rows that pass match
+func (r *MemoryRepository) collect(match func(*models.TelemetryData) bool) []models.TelemetryData {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var rows []models.TelemetryData
+	r.each(func(row *models.TelemetryData) {
+		if match(row) {
+			rows = append(rows, *row)
+		}
+	})
+	return rows
+}
+
+// inRange reports whether from <= t <= to
+func inRange(t, from, to time.Time) bool {
+	return !t.Before(from) && !t.After(to)
+}
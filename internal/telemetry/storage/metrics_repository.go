@@ -111,10 +111,10 @@ func (r *MetricsRepository) GetLatestMetrics(ctx context.Context, switchID strin
 }
 
 // GetHistoricalMetrics gets historical metrics with metrics
-func (r *MetricsRepository) GetHistoricalMetrics(ctx context.Context, switchID string, from, to time.Time) ([]models.TelemetryData, error) {
+func (r *MetricsRepository) GetHistoricalMetrics(ctx context.Context, query models.HistoryQuery) (*models.HistoryPage, error) {
 	start := time.Now()
 
-	data, err := r.repo.GetHistoricalMetrics(ctx, switchID, from, to)
+	page, err := r.repo.GetHistoricalMetrics(ctx, query)
 
 	duration := time.Since(start).Seconds()
 	if err != nil {
@@ -125,7 +125,61 @@ func (r *MetricsRepository) GetHistoricalMetrics(ctx context.Context, switchID s
 	}
 	metrics.DatabaseOperationDuration.WithLabelValues("get_historical", "telemetry").Observe(duration)
 
-	return data, err
+	return page, err
+}
+
+// GetAggregatedMetrics gets downsampled metrics with metrics
+func (r *MetricsRepository) GetAggregatedMetrics(ctx context.Context, query models.HistoryQuery) ([]models.MetricBucket, error) {
+	start := time.Now()
+
+	buckets, err := r.repo.GetAggregatedMetrics(ctx, query)
+
+	duration := time.Since(start).Seconds()
+	if err != nil {
+		metrics.DatabaseOperationsTotal.WithLabelValues("get_aggregated", "telemetry", "error").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "get_aggregated_metrics").Inc()
+	} else {
+		metrics.DatabaseOperationsTotal.WithLabelValues("get_aggregated", "telemetry", "success").Inc()
+	}
+	metrics.DatabaseOperationDuration.WithLabelValues("get_aggregated", "telemetry").Observe(duration)
+
+	return buckets, err
+}
+
+// GetSwitchMetricsSummary gets switch summary statistics with metrics
+func (r *MetricsRepository) GetSwitchMetricsSummary(ctx context.Context, switchID string, window time.Duration) (*models.SwitchSummary, error) {
+	start := time.Now()
+
+	summary, err := r.repo.GetSwitchMetricsSummary(ctx, switchID, window)
+
+	duration := time.Since(start).Seconds()
+	if err != nil {
+		metrics.DatabaseOperationsTotal.WithLabelValues("get_summary", "telemetry", "error").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "get_switch_metrics_summary").Inc()
+	} else {
+		metrics.DatabaseOperationsTotal.WithLabelValues("get_summary", "telemetry", "success").Inc()
+	}
+	metrics.DatabaseOperationDuration.WithLabelValues("get_summary", "telemetry").Observe(duration)
+
+	return summary, err
+}
+
+// GetTopSwitches gets ranked switches with metrics
+func (r *MetricsRepository) GetTopSwitches(ctx context.Context, query models.TopSwitchesQuery) ([]models.RankedSwitch, error) {
+	start := time.Now()
+
+	ranked, err := r.repo.GetTopSwitches(ctx, query)
+
+	duration := time.Since(start).Seconds()
+	if err != nil {
+		metrics.DatabaseOperationsTotal.WithLabelValues("get_top", "telemetry", "error").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "get_top_switches").Inc()
+	} else {
+		metrics.DatabaseOperationsTotal.WithLabelValues("get_top", "telemetry", "success").Inc()
+	}
+	metrics.DatabaseOperationDuration.WithLabelValues("get_top", "telemetry").Observe(duration)
+
+	return ranked, err
 }
 
 // DeleteOldMetrics deletes old metrics with metrics
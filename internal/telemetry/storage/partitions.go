@@ -0,0 +1,311 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ufm/internal/log"
+)
+
+// PartitionInterval is the time span covered by one telemetry_metrics partition
+type PartitionInterval string
+
+const (
+	// PartitionDaily creates one partition per UTC day
+	PartitionDaily PartitionInterval = "day"
+	// PartitionHourly creates one partition per hour
+	PartitionHourly PartitionInterval = "hour"
+)
+
+const (
+	// DefaultPartitionsAhead is how many future partitions are kept ready
+	DefaultPartitionsAhead = 2
+	// DefaultPartitionCheckInterval is how often missing partitions are created
+	DefaultPartitionCheckInterval = 15 * time.Minute
+
+	partitionPrefix  = "telemetry_metrics_p"
+	defaultPartition = "telemetry_metrics_default"
+)
+
+// ParsePartitionInterval validates a configured partition interval
+func ParsePartitionInterval(s string) (PartitionInterval, error) {
+	switch interval := PartitionInterval(strings.ToLower(s)); interval {
+	case PartitionDaily, PartitionHourly:
+		return interval, nil
+	default:
+		return "", fmt.Errorf("unknown partition interval %q (expected day or hour)", s)
+	}
+}
+
+// Step returns the width of one partition
+func (i PartitionInterval) Step() time.Duration {
+	if i == PartitionHourly {
+		return time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// nameLayout is the time layout of the partition name suffix
+func (i PartitionInterval) nameLayout() string {
+	if i == PartitionHourly {
+		return "2006010215"
+	}
+	return "20060102"
+}
+
+// PartitionConfig controls how telemetry_metrics partitions are maintained
+type PartitionConfig struct {
+	Interval      PartitionInterval
+	Ahead         int           // Partitions created beyond the current one
+	CheckInterval time.Duration // How often missing partitions are created
+}
+
+// PostgreSQLOption configures optional PostgreSQL repository behaviour
+type PostgreSQLOption func(*PostgreSQLRepository)
+
+// WithPartitioning keeps partitions of telemetry_metrics created ahead of time in
+// the background, from Start until the repository is closed. It has no effect
+// when the table is not partitioned (migration 005 not applied).
+func WithPartitioning(config PartitionConfig, logger log.Logger) PostgreSQLOption {
+	return func(r *PostgreSQLRepository) {
+		if config.Interval == "" {
+			config.Interval = PartitionDaily
+		}
+		if config.Ahead <= 0 {
+			config.Ahead = DefaultPartitionsAhead
+		}
+		if config.CheckInterval <= 0 {
+			config.CheckInterval = DefaultPartitionCheckInterval
+		}
+		if logger != nil {
+			r.logger = logger
+		}
+		r.partitions = &config
+	}
+}
+
+// partition is one range partition of telemetry_metrics, bounds are [from, to)
+type partition struct {
+	name     string
+	from, to time.Time
+}
+
+// EnsurePartitions creates the partition holding now and the configured number of
+// partitions after it. Rows already in the default partition for a new range are
+// moved into it. Ranges overlapping an existing partition, for example daily
+// partitions created before switching to hourly, are skipped.
+func (r *PostgreSQLRepository) EnsurePartitions(ctx context.Context, now time.Time) (int, error) {
+	if r.partitions == nil {
+		return 0, nil
+	}
+
+	partitioned, err := r.isPartitioned(ctx)
+	if err != nil || !partitioned {
+		return 0, err
+	}
+
+	existing, err := r.listPartitions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	interval := r.partitions.Interval
+	start := now.UTC().Truncate(interval.Step())
+	created := 0
+	for i := 0; i <= r.partitions.Ahead; i++ {
+		next := partition{from: start.Add(time.Duration(i) * interval.Step())}
+		next.to = next.from.Add(interval.Step())
+		next.name = partitionPrefix + next.from.Format(interval.nameLayout())
+
+		if overlapsAny(next, existing) {
+			continue
+		}
+		if err := r.createPartition(ctx, next); err != nil {
+			return created, err
+		}
+		existing = append(existing, next)
+		created++
+	}
+
+	return created, nil
+}
+
+// dropPartitionsBefore detaches and drops every partition that ends at or before
+// cutoff and returns the approximate number of rows removed
+func (r *PostgreSQLRepository) dropPartitionsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	existing, err := r.listPartitions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var dropped int64
+	for _, p := range existing {
+		if p.to.After(cutoff) {
+			continue
+		}
+
+		// Live tuple statistics avoid a full count of a partition about to be dropped
+		var rows int64
+		err := r.db.QueryRowContext(ctx,
+			`SELECT COALESCE((SELECT n_live_tup FROM pg_stat_user_tables WHERE relname = $1), 0)`,
+			p.name).Scan(&rows)
+		if err != nil {
+			return dropped, fmt.Errorf("failed to read size of partition %s: %w", p.name, err)
+		}
+
+		name := pq.QuoteIdentifier(p.name)
+		if _, err := r.db.ExecContext(ctx, `ALTER TABLE telemetry_metrics DETACH PARTITION `+name); err != nil {
+			return dropped, fmt.Errorf("failed to detach partition %s: %w", p.name, err)
+		}
+		if _, err := r.db.ExecContext(ctx, `DROP TABLE `+name); err != nil {
+			return dropped, fmt.Errorf("failed to drop partition %s: %w", p.name, err)
+		}
+
+		r.logger.Infof("Dropped telemetry partition %s (~%d rows)", p.name, rows)
+		dropped += rows
+	}
+
+	return dropped, nil
+}
+
+// createPartition creates p as a standalone table, moves matching rows out of
+// the default partition and attaches it, all in one transaction
+func (r *PostgreSQLRepository) createPartition(ctx context.Context, p partition) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	name := pq.QuoteIdentifier(p.name)
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{query: fmt.Sprintf(`CREATE TABLE %s (LIKE telemetry_metrics INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`, name)},
+		{
+			query: fmt.Sprintf(`
+				WITH moved AS (
+					DELETE FROM %s WHERE timestamp >= $1 AND timestamp < $2 RETURNING *
+				)
+				INSERT INTO %s SELECT * FROM moved`, defaultPartition, name),
+			args: []interface{}{p.from, p.to},
+		},
+		{query: fmt.Sprintf(`ALTER TABLE telemetry_metrics ATTACH PARTITION %s FOR VALUES FROM (%s) TO (%s)`,
+			name, pq.QuoteLiteral(p.from.Format(time.RFC3339)), pq.QuoteLiteral(p.to.Format(time.RFC3339)))},
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement.query, statement.args...); err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", p.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create partition %s: %w", p.name, err)
+	}
+
+	r.logger.Infof("Created telemetry partition %s [%s, %s)", p.name, p.from.Format(time.RFC3339), p.to.Format(time.RFC3339))
+	return nil
+}
+
+// isPartitioned reports whether telemetry_metrics is a partitioned table
+func (r *PostgreSQLRepository) isPartitioned(ctx context.Context) (bool, error) {
+	var partitioned bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'telemetry_metrics'::regclass
+		)`).Scan(&partitioned)
+	if err != nil {
+		return false, fmt.Errorf("failed to check telemetry_metrics partitioning: %w", err)
+	}
+	return partitioned, nil
+}
+
+// listPartitions returns the managed partitions of telemetry_metrics ordered by
+// range. Bounds are read from the partition names, which this package controls;
+// the default partition and foreign names are ignored.
+func (r *PostgreSQLRepository) listPartitions(ctx context.Context) ([]partition, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'telemetry_metrics'::regclass
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list telemetry partitions: %w", err)
+	}
+	defer rows.Close()
+
+	var partitions []partition
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan partition name: %w", err)
+		}
+		if p, ok := parsePartitionName(name); ok {
+			partitions = append(partitions, p)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating partitions: %w", err)
+	}
+
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].from.Before(partitions[j].from) })
+	return partitions, nil
+}
+
+// partitionWorker creates upcoming partitions until ctx is cancelled
+func (r *PostgreSQLRepository) partitionWorker(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.partitions.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.EnsurePartitions(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			r.logger.Errorf("Failed to maintain telemetry partitions: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// parsePartitionName recovers the range of a partition from its name
+func parsePartitionName(name string) (partition, bool) {
+	suffix, ok := strings.CutPrefix(name, partitionPrefix)
+	if !ok {
+		return partition{}, false
+	}
+
+	for _, interval := range []PartitionInterval{PartitionDaily, PartitionHourly} {
+		layout := interval.nameLayout()
+		if len(suffix) != len(layout) {
+			continue
+		}
+		from, err := time.Parse(layout, suffix)
+		if err != nil {
+			return partition{}, false
+		}
+		return partition{name: name, from: from, to: from.Add(interval.Step())}, true
+	}
+	return partition{}, false
+}
+
+// overlapsAny reports whether p shares any time with one of the partitions
+func overlapsAny(p partition, partitions []partition) bool {
+	for _, other := range partitions {
+		if p.from.Before(other.to) && other.from.Before(p.to) {
+			return true
+		}
+	}
+	return false
+}
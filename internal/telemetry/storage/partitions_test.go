@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ufm/internal/telemetry/models"
+)
+
+func TestParsePartitionName(t *testing.T) {
+	day, ok := parsePartitionName("telemetry_metrics_p20261016")
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), day.from)
+	assert.Equal(t, time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), day.to)
+
+	hour, ok := parsePartitionName("telemetry_metrics_p2026101613")
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC), hour.from)
+	assert.Equal(t, time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC), hour.to)
+
+	for _, name := range []string{"telemetry_metrics_default", "telemetry_metrics_p2026", "telemetry_metrics_pabcdefgh", "other_p20261016"} {
+		_, ok := parsePartitionName(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestOverlapsAny(t *testing.T) {
+	day, _ := parsePartitionName("telemetry_metrics_p20261016")
+	inside, _ := parsePartitionName("telemetry_metrics_p2026101613")
+	next, _ := parsePartitionName("telemetry_metrics_p2026101700")
+
+	assert.True(t, overlapsAny(inside, []partition{day}))
+	assert.False(t, overlapsAny(next, []partition{day}), "adjacent ranges do not overlap")
+}
+
+func TestParsePartitionInterval(t *testing.T) {
+	interval, err := ParsePartitionInterval("Hour")
+	require.NoError(t, err)
+	assert.Equal(t, PartitionHourly, interval)
+	assert.Equal(t, time.Hour, interval.Step())
+
+	_, err = ParsePartitionInterval("week")
+	assert.Error(t, err)
+}
+
+// TestPostgreSQLRepository_Partitions runs against the database named by
+// UFM_TEST_DATABASE_URL with the migrations applied. Its tables are truncated.
+func TestPostgreSQLRepository_Partitions(t *testing.T) {
+	dsn := os.Getenv("UFM_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("UFM_TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec(`TRUNCATE switches, telemetry_metrics, telemetry_rollup_1m, telemetry_rollup_1h`)
+	require.NoError(t, err)
+
+	repo := NewPostgreSQLRepository(db)
+	repo.partitions = &PartitionConfig{Interval: PartitionHourly, Ahead: 1}
+	defer repo.Close()
+
+	partitioned, err := repo.isPartitioned(ctx)
+	require.NoError(t, err)
+	if !partitioned {
+		t.Skip("telemetry_metrics is not partitioned")
+	}
+
+	// Rows written before their partition exists land in the default partition
+	now := time.Date(2001, 2, 3, 4, 30, 0, 0, time.UTC)
+	createSwitches(t, repo, "switch-001")
+	require.NoError(t, repo.StoreMetrics(ctx, []models.TelemetryData{
+		{SwitchID: "switch-001", Timestamp: now.Add(-2 * time.Hour)},
+		{SwitchID: "switch-001", Timestamp: now},
+	}))
+
+	created, err := repo.EnsurePartitions(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, created)
+	defer func() {
+		_, err := repo.dropPartitionsBefore(ctx, now.Add(48*time.Hour))
+		assert.NoError(t, err)
+	}()
+
+	var inCurrent int
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT COUNT(*) FROM telemetry_metrics_p2001020304`).Scan(&inCurrent))
+	assert.Equal(t, 1, inCurrent, "the default partition's row for the new range is moved")
+
+	created, err = repo.EnsurePartitions(ctx, now)
+	require.NoError(t, err)
+	assert.Zero(t, created, "existing partitions are left alone")
+
+	// Raw pruning drops the expired partition and deletes the old row from the default partition
+	_, err = repo.PruneMetrics(ctx, RawTier, now.Add(2*time.Hour), 100)
+	require.NoError(t, err)
+	count, err := repo.GetMetricsCount(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	partitions, err := repo.listPartitions(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, partitions)
+}
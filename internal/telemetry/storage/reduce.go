@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// The helpers in this file evaluate repository queries over rows already
+// loaded into memory. Backends without window functions or percentiles use
+// them so every TelemetryRepository implementation returns the same results
+// as the PostgreSQL queries.
+
+// withDefaultTimes fills a zero Timestamp or CreatedAt with now, matching the
+// column defaults applied by the PostgreSQL repository
+func withDefaultTimes(metric models.TelemetryData, now time.Time) models.TelemetryData {
+	if metric.Timestamp.IsZero() {
+		metric.Timestamp = now
+	}
+	if metric.CreatedAt.IsZero() {
+		metric.CreatedAt = now
+	}
+	return metric
+}
+
+// sortByTimeAndID orders rows by (timestamp, id) ascending, the order used by history pages
+func sortByTimeAndID(rows []models.TelemetryData) {
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].Timestamp.Equal(rows[j].Timestamp) {
+			return rows[i].Timestamp.Before(rows[j].Timestamp)
+		}
+		return rows[i].ID < rows[j].ID
+	})
+}
+
+// afterCursor reports whether a row sorts strictly after the cursor position
+func afterCursor(row models.TelemetryData, cursor *models.HistoryCursor) bool {
+	if cursor == nil {
+		return true
+	}
+	if !row.Timestamp.Equal(cursor.Timestamp) {
+		return row.Timestamp.After(cursor.Timestamp)
+	}
+	return row.ID > cursor.ID
+}
+
+// selectHistoryPage returns the page of rows matching query. rows must already
+// be restricted to the query's switch and time range.
+func selectHistoryPage(rows []models.TelemetryData, query models.HistoryQuery) *models.HistoryPage {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultHistoryPageSize
+	}
+
+	sortByTimeAndID(rows)
+	matched := make([]models.TelemetryData, 0, min(len(rows), limit+1))
+	for _, row := range rows {
+		if !afterCursor(row, query.After) {
+			continue
+		}
+		matched = append(matched, row)
+		if len(matched) > limit {
+			break
+		}
+	}
+
+	return newHistoryPage(matched, limit)
+}
+
+// aggregateRows downsamples rows into windows of query.Step aligned to query.From.
+// rows must already be restricted to the query's switch and time range.
+func aggregateRows(rows []models.TelemetryData, query models.HistoryQuery) ([]models.MetricBucket, error) {
+	if query.Step <= 0 {
+		return nil, fmt.Errorf("aggregation step must be positive")
+	}
+	if err := validateAggregation(query.Aggregation); err != nil {
+		return nil, err
+	}
+
+	metricTypes := query.Metrics
+	if len(metricTypes) == 0 {
+		metricTypes = models.AllMetricTypes
+	}
+
+	sortByTimeAndID(rows)
+	var buckets []models.MetricBucket
+	for start := 0; start < len(rows); {
+		offset := rows[start].Timestamp.Sub(query.From) / query.Step
+		bucketStart := query.From.Add(offset * query.Step)
+		bucketEnd := bucketStart.Add(query.Step)
+
+		end := start
+		for end < len(rows) && rows[end].Timestamp.Before(bucketEnd) {
+			end++
+		}
+
+		bucket := models.MetricBucket{
+			Start:  bucketStart,
+			Values: make(map[models.MetricType]float64, len(metricTypes)),
+		}
+		for _, metricType := range metricTypes {
+			values, err := metricValues(rows[start:end], metricType)
+			if err != nil {
+				return nil, err
+			}
+			bucket.Values[metricType] = reduceValues(query.Aggregation, values)
+		}
+		buckets = append(buckets, bucket)
+		start = end
+	}
+
+	return buckets, nil
+}
+
+// summarizeRows computes avg/max/p50/p99 per metric. rows must already be
+// restricted to the switch and trailing window.
+func summarizeRows(switchID string, window time.Duration, rows []models.TelemetryData) *models.SwitchSummary {
+	summary := &models.SwitchSummary{
+		SwitchID:    switchID,
+		Window:      window.String(),
+		SampleCount: int64(len(rows)),
+		Metrics:     make(map[string]models.MetricSummary, len(models.AllMetricTypes)),
+		Timestamp:   time.Now(),
+	}
+
+	for _, metricType := range models.AllMetricTypes {
+		values, _ := metricValues(rows, metricType)
+		if len(values) == 0 {
+			summary.Metrics[string(metricType)] = models.MetricSummary{}
+			continue
+		}
+
+		avg := reduceValues(models.AggregationAvg, values)
+		maxValue := reduceValues(models.AggregationMax, values)
+		sorted := sortedCopy(values)
+		p50 := percentileCont(sorted, 0.5)
+		p99 := percentileCont(sorted, 0.99)
+		summary.Metrics[string(metricType)] = models.MetricSummary{
+			Avg: &avg,
+			Max: &maxValue,
+			P50: &p50,
+			P99: &p99,
+		}
+	}
+
+	return summary
+}
+
+// rankRows reduces each switch's rows with query.Aggregation and returns the
+// top query.K. rows must already be restricted to the trailing window.
+func rankRows(rows []models.TelemetryData, query models.TopSwitchesQuery) ([]models.RankedSwitch, error) {
+	if !query.Metric.IsValid() {
+		return nil, fmt.Errorf("unknown metric type: %s", query.Metric)
+	}
+	if err := validateAggregation(query.Aggregation); err != nil {
+		return nil, err
+	}
+
+	sortByTimeAndID(rows)
+	bySwitch := make(map[string][]models.TelemetryData)
+	for _, row := range rows {
+		bySwitch[row.SwitchID] = append(bySwitch[row.SwitchID], row)
+	}
+
+	candidates := make([]models.RankedSwitch, 0, len(bySwitch))
+	for switchID, switchRows := range bySwitch {
+		values, err := metricValues(switchRows, query.Metric)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, models.RankedSwitch{
+			SwitchID:  switchID,
+			Value:     reduceValues(query.Aggregation, values),
+			Timestamp: switchRows[len(switchRows)-1].Timestamp,
+		})
+	}
+
+	return selectTopK(candidates, query.K, query.Descending), nil
+}
+
+// metricValues extracts one metric from rows, preserving their order
+func metricValues(rows []models.TelemetryData, metricType models.MetricType) ([]float64, error) {
+	values := make([]float64, 0, len(rows))
+	for i := range rows {
+		value, err := rows[i].GetMetricFloat(metricType)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// validateAggregation accepts the aggregations understood by reduceValues
+func validateAggregation(agg models.Aggregation) error {
+	if agg == "" {
+		return nil
+	}
+	_, err := models.ParseAggregation(string(agg))
+	return err
+}
+
+// reduceValues applies agg to a non-empty series ordered by time. An empty
+// aggregation means avg, as in the SQL repositories.
+func reduceValues(agg models.Aggregation, values []float64) float64 {
+	switch agg {
+	case models.AggregationMin:
+		result := values[0]
+		for _, value := range values[1:] {
+			result = math.Min(result, value)
+		}
+		return result
+	case models.AggregationMax:
+		result := values[0]
+		for _, value := range values[1:] {
+			result = math.Max(result, value)
+		}
+		return result
+	case models.AggregationSum:
+		var sum float64
+		for _, value := range values {
+			sum += value
+		}
+		return sum
+	case models.AggregationP95:
+		return percentileCont(sortedCopy(values), 0.95)
+	case models.AggregationLast:
+		return values[len(values)-1]
+	default:
+		var sum float64
+		for _, value := range values {
+			sum += value
+		}
+		return sum / float64(len(values))
+	}
+}
+
+// percentileCont interpolates linearly between the closest ranks of a sorted
+// series, matching PostgreSQL's percentile_cont
+func percentileCont(sorted []float64, p float64) float64 {
+	position := p * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	if lower == upper {
+		return sorted[lower]
+	}
+	fraction := position - float64(lower)
+	return sorted[lower] + (sorted[upper]-sorted[lower])*fraction
+}
+
+func sortedCopy(values []float64) []float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	return sorted
+}
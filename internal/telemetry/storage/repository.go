@@ -3,35 +3,124 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lib/pq"
+	"github.com/ufm/internal/log"
 	"github.com/ufm/internal/telemetry/models"
 )
 
 // PostgreSQLRepository implements the TelemetryRepository interface
 type PostgreSQLRepository struct {
-	db *sql.DB
+	db           *sql.DB
+	logger       log.Logger
+	queryTimeout atomic.Int64 // Per-query deadline in nanoseconds, zero for none
+
+	// Partition maintenance, only set by WithPartitioning
+	partitions *PartitionConfig
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
 }
 
 // NewPostgreSQLRepository creates a new PostgreSQL repository instance
-func NewPostgreSQLRepository(db *sql.DB) *PostgreSQLRepository {
-	return &PostgreSQLRepository{
-		db: db,
+func NewPostgreSQLRepository(db *sql.DB, options ...PostgreSQLOption) *PostgreSQLRepository {
+	r := &PostgreSQLRepository{
+		db:     db,
+		logger: log.DefaultLogger,
 	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Start runs partition maintenance in the background until Close, when
+// WithPartitioning is set
+func (r *PostgreSQLRepository) Start(ctx context.Context) error {
+	if r.partitions == nil {
+		return nil
+	}
+	if r.cancel != nil {
+		return fmt.Errorf("partition maintenance already started")
+	}
+
+	// Maintenance is stopped by Close, not by the caller's context
+	ctx, r.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	r.wg.Add(1)
+	go r.partitionWorker(ctx)
+	return nil
+}
+
+// PoolConfig sizes the database connection pool
+type PoolConfig struct {
+	MaxOpenConnections int           // Zero means unlimited
+	MaxIdleConnections int           // Zero keeps no idle connections
+	ConnMaxLifetime    time.Duration // Zero reuses connections forever
+}
+
+// WithQueryTimeout bounds every telemetry read and write by timeout. Partition
+// and retention maintenance are not affected, they are bounded by batching.
+func WithQueryTimeout(timeout time.Duration) PostgreSQLOption {
+	return func(r *PostgreSQLRepository) {
+		r.SetQueryTimeout(timeout)
+	}
+}
+
+// SetQueryTimeout changes the per-query timeout, zero disables it. Safe to call
+// while the repository is in use.
+func (r *PostgreSQLRepository) SetQueryTimeout(timeout time.Duration) {
+	r.queryTimeout.Store(int64(timeout))
 }
 
-// Close closes the database connection
+// SetPool resizes the connection pool. Safe to call while the repository is in use.
+func (r *PostgreSQLRepository) SetPool(config PoolConfig) {
+	r.db.SetMaxOpenConns(config.MaxOpenConnections)
+	r.db.SetMaxIdleConns(config.MaxIdleConnections)
+	r.db.SetConnMaxLifetime(config.ConnMaxLifetime)
+}
+
+// queryContext applies the configured query timeout to ctx
+func (r *PostgreSQLRepository) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := time.Duration(r.queryTimeout.Load()); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
+
+// Stats returns the connection pool statistics
+func (r *PostgreSQLRepository) Stats() sql.DBStats {
+	return r.db.Stats()
+}
+
+// Close stops partition maintenance and closes the database connection
 func (r *PostgreSQLRepository) Close() error {
+	if r.cancel != nil {
+		r.cancel()
+		r.wg.Wait()
+	}
 	if r.db != nil {
 		return r.db.Close()
 	}
 	return nil
 }
 
+// Ping checks that the database is reachable
+func (r *PostgreSQLRepository) Ping(ctx context.Context) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+	return r.db.PingContext(ctx)
+}
+
 // CreateSwitch creates a new switch in the database
 func (r *PostgreSQLRepository) CreateSwitch(ctx context.Context, sw models.Switch) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	query := `
 		INSERT INTO switches (id, name, location, created_at)
 		VALUES ($1, $2, $3, $4)
@@ -55,6 +144,9 @@ func (r *PostgreSQLRepository) CreateSwitch(ctx context.Context, sw models.Switc
 
 // GetSwitch retrieves a switch by ID
 func (r *PostgreSQLRepository) GetSwitch(ctx context.Context, switchID string) (*models.Switch, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	query := `
 		SELECT id, name, location, created_at
 		FROM switches
@@ -78,6 +170,9 @@ func (r *PostgreSQLRepository) GetSwitch(ctx context.Context, switchID string) (
 
 // ListSwitches retrieves all switches
 func (r *PostgreSQLRepository) ListSwitches(ctx context.Context) ([]models.Switch, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	query := `
 		SELECT id, name, location, created_at
 		FROM switches
@@ -109,6 +204,9 @@ func (r *PostgreSQLRepository) ListSwitches(ctx context.Context) ([]models.Switc
 
 // StoreMetrics stores multiple telemetry metrics in batch
 func (r *PostgreSQLRepository) StoreMetrics(ctx context.Context, metrics []models.TelemetryData) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	if len(metrics) == 0 {
 		return nil
 	}
@@ -155,19 +253,35 @@ func (r *PostgreSQLRepository) StoreMetrics(ctx context.Context, metrics []model
 			createdAt,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to insert metric for switch %s: %w", metric.SwitchID, err)
+			return fmt.Errorf("failed to insert metric for switch %s: %w", metric.SwitchID, postgresDataError(err))
 		}
 	}
 
 	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return fmt.Errorf("failed to commit transaction: %w", postgresDataError(err))
 	}
 
 	return nil
 }
 
+// postgresDataError marks integrity constraint violations (class 23) and invalid
+// values (class 22) with ErrInvalidData
+func postgresDataError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "22", "23":
+			return fmt.Errorf("%w: %w", ErrInvalidData, err)
+		}
+	}
+	return err
+}
+
 // GetLatestMetrics retrieves the most recent metrics for a switch
 func (r *PostgreSQLRepository) GetLatestMetrics(ctx context.Context, switchID string) (*models.TelemetryData, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	query := `
 		SELECT id, switch_id, timestamp, bandwidth_mbps, latency_ms, 
 		       packet_errors, utilization_pct, temperature_c, created_at
@@ -200,18 +314,35 @@ func (r *PostgreSQLRepository) GetLatestMetrics(ctx context.Context, switchID st
 	return &metric, nil
 }
 
-// GetHistoricalMetrics retrieves metrics for a switch within a time range
-func (r *PostgreSQLRepository) GetHistoricalMetrics(ctx context.Context, switchID string, from, to time.Time) ([]models.TelemetryData, error) {
-	query := `
+// GetHistoricalMetrics retrieves one page of metrics for a switch within a time range.
+// Pages are ordered by (timestamp, id) ascending and resume after query.After.
+func (r *PostgreSQLRepository) GetHistoricalMetrics(ctx context.Context, query models.HistoryQuery) (*models.HistoryPage, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultHistoryPageSize
+	}
+
+	sqlQuery := `
 		SELECT id, switch_id, timestamp, bandwidth_mbps, latency_ms,
 		       packet_errors, utilization_pct, temperature_c, created_at
 		FROM telemetry_metrics
 		WHERE switch_id = $1 AND timestamp >= $2 AND timestamp <= $3
-		ORDER BY timestamp DESC
-		LIMIT 1000
 	`
+	args := []interface{}{query.SwitchID, query.From, query.To}
+
+	if query.After != nil {
+		sqlQuery += ` AND (timestamp, id) > ($4, $5)`
+		args = append(args, query.After.Timestamp, query.After.ID)
+	}
+
+	// Fetch one extra row to find out whether another page exists
+	sqlQuery += fmt.Sprintf(` ORDER BY timestamp ASC, id ASC LIMIT $%d`, len(args)+1)
+	args = append(args, limit+1)
 
-	rows, err := r.db.QueryContext(ctx, query, switchID, from, to)
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query historical metrics: %w", err)
 	}
@@ -241,11 +372,83 @@ func (r *PostgreSQLRepository) GetHistoricalMetrics(ctx context.Context, switchI
 		return nil, fmt.Errorf("error iterating metric rows: %w", err)
 	}
 
-	return metrics, nil
+	return newHistoryPage(metrics, limit), nil
+}
+
+// GetAggregatedMetrics downsamples a switch's metrics into windows of query.Step
+// aligned to query.From, reducing each window with query.Aggregation. Windows
+// without samples are not returned.
+func (r *PostgreSQLRepository) GetAggregatedMetrics(ctx context.Context, query models.HistoryQuery) ([]models.MetricBucket, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	if query.Step <= 0 {
+		return nil, fmt.Errorf("aggregation step must be positive")
+	}
+
+	metricTypes := query.Metrics
+	if len(metricTypes) == 0 {
+		metricTypes = models.AllMetricTypes
+	}
+
+	selects := make([]string, 0, len(metricTypes))
+	for _, metricType := range metricTypes {
+		expr, err := aggregateExpression(query.Aggregation, metricType)
+		if err != nil {
+			return nil, err
+		}
+		selects = append(selects, expr)
+	}
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT date_bin(make_interval(secs => $4), timestamp, $2) AS bucket, %s
+		FROM telemetry_metrics
+		WHERE switch_id = $1 AND timestamp >= $2 AND timestamp <= $3
+		GROUP BY bucket
+		ORDER BY bucket ASC
+	`, strings.Join(selects, ", "))
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, query.SwitchID, query.From, query.To, query.Step.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aggregated metrics: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []models.MetricBucket
+	values := make([]sql.NullFloat64, len(metricTypes))
+	dest := make([]interface{}, len(metricTypes)+1)
+	for i := range values {
+		dest[i+1] = &values[i]
+	}
+
+	for rows.Next() {
+		var bucket models.MetricBucket
+		dest[0] = &bucket.Start
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan aggregated row: %w", err)
+		}
+
+		bucket.Values = make(map[models.MetricType]float64, len(metricTypes))
+		for i, metricType := range metricTypes {
+			if values[i].Valid {
+				bucket.Values[metricType] = values[i].Float64
+			}
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating aggregated rows: %w", err)
+	}
+
+	return buckets, nil
 }
 
 // DeleteOldMetrics removes metrics older than the specified time
 func (r *PostgreSQLRepository) DeleteOldMetrics(ctx context.Context, olderThan time.Time) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	query := `DELETE FROM telemetry_metrics WHERE created_at < $1`
 
 	result, err := r.db.ExecContext(ctx, query, olderThan)
@@ -268,6 +471,9 @@ func (r *PostgreSQLRepository) DeleteOldMetrics(ctx context.Context, olderThan t
 
 // GetMetricsCount returns the total number of metrics in the database
 func (r *PostgreSQLRepository) GetMetricsCount(ctx context.Context) (int64, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	query := `SELECT COUNT(*) FROM telemetry_metrics`
 
 	var count int64
@@ -281,6 +487,9 @@ func (r *PostgreSQLRepository) GetMetricsCount(ctx context.Context) (int64, erro
 
 // BulkStoreMetrics uses PostgreSQL's COPY command for high-performance bulk inserts
 func (r *PostgreSQLRepository) BulkStoreMetrics(ctx context.Context, metrics []models.TelemetryData) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	if len(metrics) == 0 {
 		return nil
 	}
@@ -331,93 +540,136 @@ func (r *PostgreSQLRepository) BulkStoreMetrics(ctx context.Context, metrics []m
 	for _, row := range values {
 		_, err = stmt.ExecContext(ctx, row...)
 		if err != nil {
-			return fmt.Errorf("failed to exec copy row: %w", err)
+			return fmt.Errorf("failed to exec copy row: %w", postgresDataError(err))
 		}
 	}
 
 	_, err = stmt.ExecContext(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to flush copy: %w", err)
+		return fmt.Errorf("failed to flush copy: %w", postgresDataError(err))
 	}
 
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return fmt.Errorf("failed to commit transaction: %w", postgresDataError(err))
 	}
 
 	return nil
 }
 
-// GetSwitchMetricsSummary returns aggregated metrics for a switch over a time period
-func (r *PostgreSQLRepository) GetSwitchMetricsSummary(ctx context.Context, switchID string, hours int) (map[string]interface{}, error) {
-	query := `
-		SELECT 
-			COUNT(*) as sample_count,
-			AVG(bandwidth_mbps) as avg_bandwidth,
-			MAX(bandwidth_mbps) as max_bandwidth,
-			AVG(latency_ms) as avg_latency,
-			MAX(latency_ms) as max_latency,
-			SUM(packet_errors) as total_errors,
-			AVG(utilization_pct) as avg_utilization,
-			MAX(utilization_pct) as max_utilization,
-			AVG(temperature_c) as avg_temperature,
-			MAX(temperature_c) as max_temperature
-		FROM telemetry_metrics
-		WHERE switch_id = $1 AND timestamp > NOW() - INTERVAL '%d hours'
-	`
+// GetSwitchMetricsSummary returns avg/max/p50/p99 per metric for a switch over the trailing window
+func (r *PostgreSQLRepository) GetSwitchMetricsSummary(ctx context.Context, switchID string, window time.Duration) (*models.SwitchSummary, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
 
-	query = fmt.Sprintf(query, hours)
+	if window <= 0 {
+		return nil, fmt.Errorf("summary window must be positive")
+	}
 
-	var summary struct {
-		SampleCount    int64
-		AvgBandwidth   sql.NullFloat64
-		MaxBandwidth   sql.NullFloat64
-		AvgLatency     sql.NullFloat64
-		MaxLatency     sql.NullFloat64
-		TotalErrors    sql.NullInt64
-		AvgUtilization sql.NullFloat64
-		MaxUtilization sql.NullFloat64
-		AvgTemperature sql.NullFloat64
-		MaxTemperature sql.NullFloat64
+	selects := make([]string, 0, len(models.AllMetricTypes)*4)
+	for _, metricType := range models.AllMetricTypes {
+		column := metricColumns[metricType]
+		selects = append(selects,
+			fmt.Sprintf("AVG(%s)::double precision", column),
+			fmt.Sprintf("MAX(%s)::double precision", column),
+			fmt.Sprintf("percentile_cont(0.5) WITHIN GROUP (ORDER BY %s)", column),
+			fmt.Sprintf("percentile_cont(0.99) WITHIN GROUP (ORDER BY %s)", column),
+		)
 	}
 
-	err := r.db.QueryRowContext(ctx, query, switchID).Scan(
-		&summary.SampleCount,
-		&summary.AvgBandwidth,
-		&summary.MaxBandwidth,
-		&summary.AvgLatency,
-		&summary.MaxLatency,
-		&summary.TotalErrors,
-		&summary.AvgUtilization,
-		&summary.MaxUtilization,
-		&summary.AvgTemperature,
-		&summary.MaxTemperature,
-	)
+	query := fmt.Sprintf(`
+		SELECT COUNT(*), %s
+		FROM telemetry_metrics
+		WHERE switch_id = $1 AND timestamp > NOW() - make_interval(secs => $2)
+	`, strings.Join(selects, ", "))
 
+	var sampleCount int64
+	values := make([]sql.NullFloat64, len(models.AllMetricTypes)*4)
+	dest := make([]interface{}, 0, len(values)+1)
+	dest = append(dest, &sampleCount)
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+
+	err := r.db.QueryRowContext(ctx, query, switchID, window.Seconds()).Scan(dest...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get switch metrics summary: %w", err)
 	}
 
-	result := map[string]interface{}{
-		"switch_id":       switchID,
-		"time_period":     fmt.Sprintf("%d hours", hours),
-		"sample_count":    summary.SampleCount,
-		"avg_bandwidth":   nullFloat64ToInterface(summary.AvgBandwidth),
-		"max_bandwidth":   nullFloat64ToInterface(summary.MaxBandwidth),
-		"avg_latency":     nullFloat64ToInterface(summary.AvgLatency),
-		"max_latency":     nullFloat64ToInterface(summary.MaxLatency),
-		"total_errors":    nullInt64ToInterface(summary.TotalErrors),
-		"avg_utilization": nullFloat64ToInterface(summary.AvgUtilization),
-		"max_utilization": nullFloat64ToInterface(summary.MaxUtilization),
-		"avg_temperature": nullFloat64ToInterface(summary.AvgTemperature),
-		"max_temperature": nullFloat64ToInterface(summary.MaxTemperature),
+	summary := &models.SwitchSummary{
+		SwitchID:    switchID,
+		Window:      window.String(),
+		SampleCount: sampleCount,
+		Metrics:     make(map[string]models.MetricSummary, len(models.AllMetricTypes)),
+		Timestamp:   time.Now(),
+	}
+	for i, metricType := range models.AllMetricTypes {
+		summary.Metrics[string(metricType)] = models.MetricSummary{
+			Avg: nullFloat64ToPtr(values[i*4]),
+			Max: nullFloat64ToPtr(values[i*4+1]),
+			P50: nullFloat64ToPtr(values[i*4+2]),
+			P99: nullFloat64ToPtr(values[i*4+3]),
+		}
 	}
 
-	return result, nil
+	return summary, nil
+}
+
+// GetTopSwitches ranks switches by a metric reduced over the trailing query.Window
+func (r *PostgreSQLRepository) GetTopSwitches(ctx context.Context, query models.TopSwitchesQuery) ([]models.RankedSwitch, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	if query.Window <= 0 {
+		return nil, fmt.Errorf("ranking window must be positive")
+	}
+
+	expr, err := aggregateExpression(query.Aggregation, query.Metric)
+	if err != nil {
+		return nil, err
+	}
+
+	order := "ASC"
+	if query.Descending {
+		order = "DESC"
+	}
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT switch_id, %s AS value, MAX(timestamp) AS last_seen
+		FROM telemetry_metrics
+		WHERE timestamp > NOW() - make_interval(secs => $1)
+		GROUP BY switch_id
+		ORDER BY value %s, switch_id ASC
+		LIMIT $2
+	`, expr, order)
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, query.Window.Seconds(), query.K)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top switches: %w", err)
+	}
+	defer rows.Close()
+
+	ranked := []models.RankedSwitch{}
+	for rows.Next() {
+		var item models.RankedSwitch
+		if err := rows.Scan(&item.SwitchID, &item.Value, &item.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan top switch row: %w", err)
+		}
+		ranked = append(ranked, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating top switch rows: %w", err)
+	}
+
+	return ranked, nil
 }
 
 // Health check for database connectivity
 func (r *PostgreSQLRepository) HealthCheck(ctx context.Context) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	query := `SELECT 1`
 
 	var result int
@@ -429,17 +681,48 @@ func (r *PostgreSQLRepository) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
-// Helper functions for handling SQL null values
-func nullFloat64ToInterface(nf sql.NullFloat64) interface{} {
-	if nf.Valid {
-		return nf.Float64
-	}
-	return nil
+// metricColumns maps metric types to their telemetry_metrics column.
+// Only names from this table are ever interpolated into SQL.
+var metricColumns = map[models.MetricType]string{
+	models.MetricBandwidth:    "bandwidth_mbps",
+	models.MetricLatency:      "latency_ms",
+	models.MetricPacketErrors: "packet_errors",
+	models.MetricUtilization:  "utilization_pct",
+	models.MetricTemperature:  "temperature_c",
 }
 
-func nullInt64ToInterface(ni sql.NullInt64) interface{} {
-	if ni.Valid {
-		return ni.Int64
+// aggregateExpression builds the SQL reducer for a metric column
+func aggregateExpression(agg models.Aggregation, metricType models.MetricType) (string, error) {
+	column, ok := metricColumns[metricType]
+	if !ok {
+		return "", fmt.Errorf("unknown metric type: %s", metricType)
+	}
+
+	var expr string
+	switch agg {
+	case models.AggregationAvg, "":
+		expr = fmt.Sprintf("AVG(%s)", column)
+	case models.AggregationMin:
+		expr = fmt.Sprintf("MIN(%s)", column)
+	case models.AggregationMax:
+		expr = fmt.Sprintf("MAX(%s)", column)
+	case models.AggregationSum:
+		expr = fmt.Sprintf("SUM(%s)", column)
+	case models.AggregationP95:
+		expr = fmt.Sprintf("percentile_cont(0.95) WITHIN GROUP (ORDER BY %s)", column)
+	case models.AggregationLast:
+		expr = fmt.Sprintf("(array_agg(%s ORDER BY timestamp DESC))[1]", column)
+	default:
+		return "", fmt.Errorf("unknown aggregation: %s", agg)
+	}
+
+	return expr + "::double precision", nil
+}
+
+// Helper functions for handling SQL null values
+func nullFloat64ToPtr(nf sql.NullFloat64) *float64 {
+	if nf.Valid {
+		return &nf.Float64
 	}
 	return nil
 }
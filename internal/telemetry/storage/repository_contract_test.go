@@ -0,0 +1,369 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// testRepositoryContract runs the behaviour every TelemetryRepository must share
+// against a fresh, empty repository returned by newRepo.
+func testRepositoryContract(t *testing.T, newRepo func(t *testing.T) TelemetryRepository) {
+	ctx := context.Background()
+
+	t.Run("Switches", func(t *testing.T) {
+		repo := newRepo(t)
+
+		require.NoError(t, repo.CreateSwitch(ctx, models.Switch{ID: "switch-002", Name: "Second", Location: "rack-2"}))
+		created := time.Now().Add(-time.Hour).UTC().Truncate(time.Microsecond)
+		require.NoError(t, repo.CreateSwitch(ctx, models.Switch{ID: "switch-001", Name: "First", Created: created}))
+
+		// Re-creating a switch updates it in place and keeps its creation time
+		require.NoError(t, repo.CreateSwitch(ctx, models.Switch{ID: "switch-001", Name: "Renamed", Location: "rack-1"}))
+
+		sw, err := repo.GetSwitch(ctx, "switch-001")
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", sw.Name)
+		assert.Equal(t, "rack-1", sw.Location)
+		assert.True(t, created.Equal(sw.Created), "created %v, got %v", created, sw.Created)
+
+		switches, err := repo.ListSwitches(ctx)
+		require.NoError(t, err)
+		require.Len(t, switches, 2)
+		assert.Equal(t, "switch-001", switches[0].ID)
+		assert.Equal(t, "switch-002", switches[1].ID)
+
+		_, err = repo.GetSwitch(ctx, "switch-404")
+		assert.EqualError(t, err, "switch switch-404 not found")
+	})
+
+	t.Run("StoreAndLatest", func(t *testing.T) {
+		repo := newRepo(t)
+		createSwitches(t, repo, "switch-001")
+
+		base := time.Now().Add(-time.Minute).UTC().Truncate(time.Microsecond)
+		require.NoError(t, repo.StoreMetrics(ctx, []models.TelemetryData{
+			{SwitchID: "switch-001", Timestamp: base.Add(2 * time.Second), TemperatureC: 42},
+			{SwitchID: "switch-001", Timestamp: base, TemperatureC: 40},
+		}))
+
+		latest, err := repo.GetLatestMetrics(ctx, "switch-001")
+		require.NoError(t, err)
+		assert.Equal(t, 42.0, latest.TemperatureC)
+		assert.True(t, base.Add(2*time.Second).Equal(latest.Timestamp))
+		assert.NotZero(t, latest.ID)
+		assert.False(t, latest.CreatedAt.IsZero())
+
+		count, err := repo.GetMetricsCount(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+
+		_, err = repo.GetLatestMetrics(ctx, "switch-002")
+		assert.EqualError(t, err, "no metrics found for switch switch-002")
+
+		assert.ErrorIs(t, repo.StoreMetrics(ctx, []models.TelemetryData{{SwitchID: "switch-404"}}), ErrInvalidData)
+	})
+
+	t.Run("HistoryPagination", func(t *testing.T) {
+		repo := newRepo(t)
+		createSwitches(t, repo, "switch-001", "switch-002")
+
+		base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+		var samples []models.TelemetryData
+		for i := 0; i < 5; i++ {
+			samples = append(samples,
+				models.TelemetryData{SwitchID: "switch-001", Timestamp: base.Add(time.Duration(i) * time.Second), LatencyMs: float64(i)},
+				models.TelemetryData{SwitchID: "switch-002", Timestamp: base.Add(time.Duration(i) * time.Second), LatencyMs: 100},
+			)
+		}
+		require.NoError(t, repo.StoreMetrics(ctx, samples))
+
+		query := models.HistoryQuery{SwitchID: "switch-001", From: base, To: base.Add(3 * time.Second), Limit: 3}
+		page, err := repo.GetHistoricalMetrics(ctx, query)
+		require.NoError(t, err)
+		assert.Equal(t, []float64{0, 1, 2}, latencies(page.Data))
+		require.NotNil(t, page.NextCursor)
+
+		query.After = page.NextCursor
+		page, err = repo.GetHistoricalMetrics(ctx, query)
+		require.NoError(t, err)
+		assert.Equal(t, []float64{3}, latencies(page.Data), "To is inclusive")
+		assert.Nil(t, page.NextCursor)
+	})
+
+	t.Run("AggregatedMetrics", func(t *testing.T) {
+		repo := newRepo(t)
+		createSwitches(t, repo, "switch-001")
+
+		base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+		var samples []models.TelemetryData
+		for i, value := range []float64{10, 20, 30, 40, 50} {
+			samples = append(samples, models.TelemetryData{
+				SwitchID:     "switch-001",
+				Timestamp:    base.Add(time.Duration(i) * 20 * time.Second),
+				TemperatureC: value,
+			})
+		}
+		require.NoError(t, repo.StoreMetrics(ctx, samples))
+
+		query := models.HistoryQuery{
+			SwitchID:    "switch-001",
+			From:        base,
+			To:          base.Add(time.Hour),
+			Metrics:     []models.MetricType{models.MetricTemperature},
+			Step:        time.Minute,
+			Aggregation: models.AggregationAvg,
+		}
+		buckets, err := repo.GetAggregatedMetrics(ctx, query)
+		require.NoError(t, err)
+		require.Len(t, buckets, 2)
+		assert.True(t, base.Equal(buckets[0].Start))
+		assert.True(t, base.Add(time.Minute).Equal(buckets[1].Start))
+		assert.Equal(t, 20.0, buckets[0].Values[models.MetricTemperature])
+		assert.Equal(t, 45.0, buckets[1].Values[models.MetricTemperature])
+
+		query.Aggregation = models.AggregationLast
+		buckets, err = repo.GetAggregatedMetrics(ctx, query)
+		require.NoError(t, err)
+		assert.Equal(t, 30.0, buckets[0].Values[models.MetricTemperature])
+		assert.Equal(t, 50.0, buckets[1].Values[models.MetricTemperature])
+
+		query.Aggregation = models.AggregationP95
+		buckets, err = repo.GetAggregatedMetrics(ctx, query)
+		require.NoError(t, err)
+		assert.InDelta(t, 29.0, buckets[0].Values[models.MetricTemperature], 1e-9)
+
+		query.Step = 0
+		_, err = repo.GetAggregatedMetrics(ctx, query)
+		assert.Error(t, err)
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		repo := newRepo(t)
+		createSwitches(t, repo, "switch-001")
+
+		now := time.Now().UTC()
+		require.NoError(t, repo.StoreMetrics(ctx, []models.TelemetryData{
+			{SwitchID: "switch-001", Timestamp: now.Add(-3 * time.Second), UtilizationPct: 10},
+			{SwitchID: "switch-001", Timestamp: now.Add(-2 * time.Second), UtilizationPct: 20},
+			{SwitchID: "switch-001", Timestamp: now.Add(-time.Second), UtilizationPct: 60},
+			{SwitchID: "switch-001", Timestamp: now.Add(-2 * time.Hour), UtilizationPct: 99},
+		}))
+
+		summary, err := repo.GetSwitchMetricsSummary(ctx, "switch-001", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), summary.SampleCount)
+		utilization := summary.Metrics[string(models.MetricUtilization)]
+		require.NotNil(t, utilization.Avg)
+		assert.InDelta(t, 30.0, *utilization.Avg, 1e-9)
+		assert.Equal(t, 60.0, *utilization.Max)
+		assert.InDelta(t, 20.0, *utilization.P50, 1e-9)
+		assert.InDelta(t, 59.2, *utilization.P99, 1e-9)
+
+		empty, err := repo.GetSwitchMetricsSummary(ctx, "switch-404", time.Minute)
+		require.NoError(t, err)
+		assert.Zero(t, empty.SampleCount)
+		assert.Nil(t, empty.Metrics[string(models.MetricUtilization)].Avg)
+
+		// A single sample is its own percentile
+		single, err := repo.GetSwitchMetricsSummary(ctx, "switch-001", 1500*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), single.SampleCount)
+		assert.Equal(t, "1.5s", single.Window)
+		assert.InDelta(t, 60.0, *single.Metrics[string(models.MetricUtilization)].P99, 1e-9)
+
+		_, err = repo.GetSwitchMetricsSummary(ctx, "switch-001", 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("TopSwitches", func(t *testing.T) {
+		repo := newRepo(t)
+		createSwitches(t, repo, "switch-001", "switch-002", "switch-003")
+
+		now := time.Now().UTC()
+		require.NoError(t, repo.StoreMetrics(ctx, []models.TelemetryData{
+			{SwitchID: "switch-001", Timestamp: now.Add(-2 * time.Second), TemperatureC: 40},
+			{SwitchID: "switch-001", Timestamp: now.Add(-time.Second), TemperatureC: 60},
+			{SwitchID: "switch-002", Timestamp: now.Add(-time.Second), TemperatureC: 55},
+			{SwitchID: "switch-003", Timestamp: now.Add(-time.Second), TemperatureC: 50},
+			{SwitchID: "switch-003", Timestamp: now.Add(-2 * time.Hour), TemperatureC: 90},
+		}))
+
+		top, err := repo.GetTopSwitches(ctx, models.TopSwitchesQuery{
+			Metric:      models.MetricTemperature,
+			K:           2,
+			Descending:  true,
+			Window:      time.Minute,
+			Aggregation: models.AggregationMax,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"switch-001", "switch-002"}, rankedIDs(top))
+		assert.Equal(t, 60.0, top[0].Value)
+
+		bottom, err := repo.GetTopSwitches(ctx, models.TopSwitchesQuery{
+			Metric:      models.MetricTemperature,
+			K:           3,
+			Window:      time.Minute,
+			Aggregation: models.AggregationAvg,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"switch-001", "switch-003", "switch-002"}, rankedIDs(bottom))
+	})
+
+	t.Run("DeleteOldMetrics", func(t *testing.T) {
+		repo := newRepo(t)
+		createSwitches(t, repo, "switch-001")
+
+		now := time.Now().UTC()
+		require.NoError(t, repo.StoreMetrics(ctx, []models.TelemetryData{
+			{SwitchID: "switch-001", Timestamp: now.Add(-2 * time.Hour), CreatedAt: now.Add(-2 * time.Hour), TemperatureC: 40},
+		}))
+		require.NoError(t, repo.StoreMetrics(ctx, []models.TelemetryData{
+			{SwitchID: "switch-001", Timestamp: now.Add(-3 * time.Hour), CreatedAt: now, TemperatureC: 41},
+			{SwitchID: "switch-001", Timestamp: now, CreatedAt: now.Add(-2 * time.Hour), TemperatureC: 42},
+		}))
+
+		require.NoError(t, repo.DeleteOldMetrics(ctx, now.Add(-time.Hour)))
+
+		count, err := repo.GetMetricsCount(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+
+		latest, err := repo.GetLatestMetrics(ctx, "switch-001")
+		require.NoError(t, err)
+		assert.Equal(t, 41.0, latest.TemperatureC)
+	})
+}
+
+func createSwitches(t *testing.T, repo TelemetryRepository, switchIDs ...string) {
+	t.Helper()
+	for _, switchID := range switchIDs {
+		require.NoError(t, repo.CreateSwitch(context.Background(), models.Switch{ID: switchID, Name: switchID}))
+	}
+}
+
+func latencies(rows []models.TelemetryData) []float64 {
+	values := make([]float64, 0, len(rows))
+	for _, row := range rows {
+		values = append(values, row.LatencyMs)
+	}
+	return values
+}
+
+func TestSQLiteRepository_Contract(t *testing.T) {
+	testRepositoryContract(t, func(t *testing.T) TelemetryRepository {
+		db, err := OpenSQLiteDB(filepath.Join(t.TempDir(), "telemetry.db"))
+		require.NoError(t, err)
+		repo, err := NewSQLiteRepository(context.Background(), db)
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestSegmentRepository_Contract(t *testing.T) {
+	testRepositoryContract(t, func(t *testing.T) TelemetryRepository {
+		// A one byte limit rotates after every batch so queries span several segments
+		repo, err := NewSegmentRepository(t.TempDir(), 1)
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestMemoryRepository_Contract(t *testing.T) {
+	testRepositoryContract(t, func(t *testing.T) TelemetryRepository {
+		return NewMemoryRepository(0)
+	})
+}
+
+// TestPostgreSQLRepository_Contract runs against a disposable database named by
+// UFM_TEST_DATABASE_URL with the migrations applied. Its tables are truncated.
+func TestPostgreSQLRepository_Contract(t *testing.T) {
+	dsn := os.Getenv("UFM_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("UFM_TEST_DATABASE_URL not set")
+	}
+
+	testRepositoryContract(t, func(t *testing.T) TelemetryRepository {
+		db, err := sql.Open("postgres", dsn)
+		require.NoError(t, err)
+		_, err = db.Exec(`TRUNCATE switches, telemetry_metrics, telemetry_rollup_1m, telemetry_rollup_1h`)
+		require.NoError(t, err)
+		repo := NewPostgreSQLRepository(db)
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestSegmentRepository_ReopenRecoversTornWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	repo, err := NewSegmentRepository(dir, 0)
+	require.NoError(t, err)
+	createSwitches(t, repo, "switch-001")
+	require.NoError(t, repo.StoreMetrics(ctx, []models.TelemetryData{
+		{SwitchID: "switch-001", TemperatureC: 40},
+		{SwitchID: "switch-001", TemperatureC: 41},
+	}))
+	require.NoError(t, repo.Close())
+
+	// Simulate a crash part way through appending a row
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+segmentFileExt))
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	file, err := os.OpenFile(segments[0], os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"id":3,"switch_id":"swi`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	repo, err = NewSegmentRepository(dir, 0)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	sw, err := repo.GetSwitch(ctx, "switch-001")
+	require.NoError(t, err)
+	assert.Equal(t, "switch-001", sw.Name)
+
+	count, err := repo.GetMetricsCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// New rows continue the ID sequence after the torn record is discarded
+	require.NoError(t, repo.StoreMetrics(ctx, []models.TelemetryData{{SwitchID: "switch-001", TemperatureC: 42}}))
+	latest, err := repo.GetLatestMetrics(ctx, "switch-001")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), latest.ID)
+	assert.Equal(t, 42.0, latest.TemperatureC)
+}
+
+func TestMemoryRepository_EvictsOldestWhenFull(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository(3)
+	createSwitches(t, repo, "switch-001")
+
+	base := time.Now().Add(-time.Minute).UTC()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, repo.StoreMetrics(ctx, []models.TelemetryData{
+			{SwitchID: "switch-001", Timestamp: base.Add(time.Duration(i) * time.Second), LatencyMs: float64(i)},
+		}))
+	}
+
+	count, err := repo.GetMetricsCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	page, err := repo.GetHistoricalMetrics(ctx, models.HistoryQuery{SwitchID: "switch-001", From: base, To: base.Add(time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{2, 3, 4}, latencies(page.Data))
+	assert.Equal(t, int64(5), page.Data[2].ID)
+}
@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// RollupTier identifies one resolution of stored telemetry
+type RollupTier string
+
+const (
+	// RawTier is telemetry_metrics, one row per sample
+	RawTier RollupTier = "raw"
+	// MinuteTier is telemetry_rollup_1m, one row per switch per minute
+	MinuteTier RollupTier = "1m"
+	// HourTier is telemetry_rollup_1h, one row per switch per hour
+	HourTier RollupTier = "1h"
+)
+
+// Step returns the bucket width of the tier, zero for raw samples
+func (t RollupTier) Step() time.Duration {
+	switch t {
+	case MinuteTier:
+		return time.Minute
+	case HourTier:
+		return time.Hour
+	default:
+		return 0
+	}
+}
+
+// Source returns the tier a rollup tier is computed from
+func (t RollupTier) Source() RollupTier {
+	if t == HourTier {
+		return MinuteTier
+	}
+	return RawTier
+}
+
+// RollupStore is implemented by repositories that keep downsampled copies of
+// telemetry and can prune each tier in bounded batches
+type RollupStore interface {
+	// RollupMetrics rebuilds the tier's buckets starting in [from, to) from its source tier
+	// and returns the number of buckets written
+	RollupMetrics(ctx context.Context, tier RollupTier, from, to time.Time) (int64, error)
+	// LatestRollup returns the start of the newest bucket in the tier, zero when it is empty
+	LatestRollup(ctx context.Context, tier RollupTier) (time.Time, error)
+	// EarliestMetric returns the oldest sample or bucket time in the tier, zero when it is empty
+	EarliestMetric(ctx context.Context, tier RollupTier) (time.Time, error)
+	// PruneMetrics deletes at most limit rows older than olderThan from the tier
+	PruneMetrics(ctx context.Context, tier RollupTier, olderThan time.Time, limit int) (int64, error)
+}
+
+// rollupTables maps tiers to their table and time column
+var rollupTables = map[RollupTier]struct{ table, timeColumn string }{
+	RawTier:    {"telemetry_metrics", "timestamp"},
+	MinuteTier: {"telemetry_rollup_1m", "bucket"},
+	HourTier:   {"telemetry_rollup_1h", "bucket"},
+}
+
+// RollupMetrics aggregates raw samples into minute buckets, or minute buckets into
+// hour buckets. Existing buckets in the range are overwritten, so re-running a
+// range after late samples arrive is safe.
+func (r *PostgreSQLRepository) RollupMetrics(ctx context.Context, tier RollupTier, from, to time.Time) (int64, error) {
+	query, err := rollupQuery(tier)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.db.ExecContext(ctx, query, from, to, int64(tier.Step()/time.Second))
+	if err != nil {
+		return 0, fmt.Errorf("failed to roll up %s metrics: %w", tier, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// LatestRollup returns the start of the newest bucket in a rollup tier
+func (r *PostgreSQLRepository) LatestRollup(ctx context.Context, tier RollupTier) (time.Time, error) {
+	if tier.Step() == 0 {
+		return time.Time{}, fmt.Errorf("unknown rollup tier: %s", tier)
+	}
+	return r.boundaryTime(ctx, tier, "MAX")
+}
+
+// EarliestMetric returns the oldest sample or bucket time in a tier
+func (r *PostgreSQLRepository) EarliestMetric(ctx context.Context, tier RollupTier) (time.Time, error) {
+	return r.boundaryTime(ctx, tier, "MIN")
+}
+
+// PruneMetrics deletes one batch of rows older than olderThan. Deleting in
+// batches keeps each transaction, and the locks it holds, short. When
+// telemetry_metrics is partitioned, raw retention drops whole expired partitions
+// instead and only deletes row by row from the default partition; rows in the
+// partition straddling olderThan are kept until all of it has expired.
+func (r *PostgreSQLRepository) PruneMetrics(ctx context.Context, tier RollupTier, olderThan time.Time, limit int) (int64, error) {
+	target, ok := rollupTables[tier]
+	if !ok {
+		return 0, fmt.Errorf("unknown rollup tier: %s", tier)
+	}
+	if limit <= 0 {
+		return 0, fmt.Errorf("prune batch size must be positive")
+	}
+
+	var dropped int64
+	if tier == RawTier {
+		partitioned, err := r.isPartitioned(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if partitioned {
+			if dropped, err = r.dropPartitionsBefore(ctx, olderThan); err != nil {
+				return dropped, err
+			}
+			// ctid is only unique within one table, so delete from a single partition
+			target.table = defaultPartition
+		}
+	}
+
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s WHERE ctid IN (
+			SELECT ctid FROM %[1]s WHERE %[2]s < $1 LIMIT $2
+		)`, target.table, target.timeColumn)
+
+	result, err := r.db.ExecContext(ctx, query, olderThan, limit)
+	if err != nil {
+		return dropped, fmt.Errorf("failed to prune %s metrics: %w", tier, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return dropped, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return dropped + rowsAffected, nil
+}
+
+// boundaryTime returns MIN or MAX of a tier's time column, zero when the tier is empty
+func (r *PostgreSQLRepository) boundaryTime(ctx context.Context, tier RollupTier, reducer string) (time.Time, error) {
+	target, ok := rollupTables[tier]
+	if !ok {
+		return time.Time{}, fmt.Errorf("unknown rollup tier: %s", tier)
+	}
+
+	query := fmt.Sprintf(`SELECT %s(%s) FROM %s`, reducer, target.timeColumn, target.table)
+
+	var boundary sql.NullTime
+	if err := r.db.QueryRowContext(ctx, query).Scan(&boundary); err != nil {
+		return time.Time{}, fmt.Errorf("failed to read %s time range: %w", tier, err)
+	}
+	if !boundary.Valid {
+		return time.Time{}, nil
+	}
+	return boundary.Time, nil
+}
+
+// rollupQuery builds the upsert that fills a rollup tier from its source. $1 and
+// $2 bound the source time range and $3 is the bucket width in seconds; buckets
+// are aligned to the Unix epoch so they do not depend on the session time zone.
+func rollupQuery(tier RollupTier) (string, error) {
+	if tier.Step() == 0 {
+		return "", fmt.Errorf("unknown rollup tier: %s", tier)
+	}
+	target := rollupTables[tier]
+	source := rollupTables[tier.Source()]
+
+	columns := []string{"switch_id", "bucket", "sample_count"}
+	selects := []string{
+		"switch_id",
+		fmt.Sprintf("to_timestamp(floor(extract(epoch FROM %s) / $3) * $3) AS rollup_bucket", source.timeColumn),
+	}
+	updates := []string{"sample_count = EXCLUDED.sample_count"}
+
+	if tier.Source() == RawTier {
+		selects = append(selects, "COUNT(*)")
+	} else {
+		selects = append(selects, "SUM(sample_count)")
+	}
+
+	for _, metricType := range models.AllMetricTypes {
+		column := metricColumns[metricType]
+		if tier.Source() == RawTier {
+			selects = append(selects,
+				fmt.Sprintf("AVG(%s)", column),
+				fmt.Sprintf("MIN(%s)", column),
+				fmt.Sprintf("MAX(%s)", column))
+		} else {
+			// Weight each minute by its sample count so the hourly mean matches the raw mean
+			selects = append(selects,
+				fmt.Sprintf("SUM(%[1]s_avg * sample_count) / SUM(sample_count)", column),
+				fmt.Sprintf("MIN(%s_min)", column),
+				fmt.Sprintf("MAX(%s_max)", column))
+		}
+		for _, suffix := range []string{"_avg", "_min", "_max"} {
+			columns = append(columns, column+suffix)
+			updates = append(updates, fmt.Sprintf("%[1]s = EXCLUDED.%[1]s", column+suffix))
+		}
+	}
+
+	return fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		SELECT %s
+		FROM %s
+		WHERE %s >= $1 AND %s < $2
+		GROUP BY switch_id, rollup_bucket
+		ON CONFLICT (switch_id, bucket) DO UPDATE SET %s`,
+		target.table, strings.Join(columns, ", "),
+		strings.Join(selects, ", "),
+		source.table,
+		source.timeColumn, source.timeColumn,
+		strings.Join(updates, ", ")), nil
+}
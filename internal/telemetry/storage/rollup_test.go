@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ufm/internal/telemetry/models"
+)
+
+func TestRollupQuery(t *testing.T) {
+	minute, err := rollupQuery(MinuteTier)
+	require.NoError(t, err)
+	assert.Contains(t, minute, "INSERT INTO telemetry_rollup_1m")
+	assert.Contains(t, minute, "FROM telemetry_metrics")
+	assert.Contains(t, minute, "AVG(temperature_c)")
+
+	hour, err := rollupQuery(HourTier)
+	require.NoError(t, err)
+	assert.Contains(t, hour, "INSERT INTO telemetry_rollup_1h")
+	assert.Contains(t, hour, "FROM telemetry_rollup_1m")
+	assert.Contains(t, hour, "SUM(temperature_c_avg * sample_count) / SUM(sample_count)")
+
+	_, err = rollupQuery(RawTier)
+	assert.Error(t, err)
+}
+
+// TestPostgreSQLRepository_Rollups runs against the database named by
+// UFM_TEST_DATABASE_URL with the migrations applied. Its tables are truncated.
+func TestPostgreSQLRepository_Rollups(t *testing.T) {
+	dsn := os.Getenv("UFM_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("UFM_TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec(`TRUNCATE switches, telemetry_metrics, telemetry_rollup_1m, telemetry_rollup_1h`)
+	require.NoError(t, err)
+	repo := NewPostgreSQLRepository(db)
+	defer repo.Close()
+
+	createSwitches(t, repo, "switch-001")
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+	require.NoError(t, repo.StoreMetrics(ctx, []models.TelemetryData{
+		{SwitchID: "switch-001", Timestamp: hour.Add(10 * time.Second), TemperatureC: 40},
+		{SwitchID: "switch-001", Timestamp: hour.Add(20 * time.Second), TemperatureC: 50},
+		{SwitchID: "switch-001", Timestamp: hour.Add(time.Minute), TemperatureC: 60},
+	}))
+
+	written, err := repo.RollupMetrics(ctx, MinuteTier, hour, hour.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), written)
+
+	latest, err := repo.LatestRollup(ctx, MinuteTier)
+	require.NoError(t, err)
+	assert.True(t, latest.Equal(hour.Add(time.Minute)))
+
+	written, err = repo.RollupMetrics(ctx, HourTier, hour, hour.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), written)
+
+	// The hourly mean is weighted by samples, not by minutes
+	var count int64
+	var avg, minValue, maxValue float64
+	require.NoError(t, db.QueryRowContext(ctx,
+		`SELECT sample_count, temperature_c_avg, temperature_c_min, temperature_c_max FROM telemetry_rollup_1h`,
+	).Scan(&count, &avg, &minValue, &maxValue))
+	assert.Equal(t, int64(3), count)
+	assert.InDelta(t, 50, avg, 0.001)
+	assert.Equal(t, 40.0, minValue)
+	assert.Equal(t, 60.0, maxValue)
+
+	deleted, err := repo.PruneMetrics(ctx, RawTier, hour.Add(time.Minute), 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	deleted, err = repo.PruneMetrics(ctx, RawTier, hour.Add(time.Minute), 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	earliest, err := repo.EarliestMetric(ctx, RawTier)
+	require.NoError(t, err)
+	assert.True(t, earliest.Equal(hour.Add(time.Minute)))
+}
@@ -0,0 +1,624 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ufm/internal/telemetry/models"
+)
+
+const (
+	// DefaultSegmentMaxBytes is the size at which the active segment file is rotated
+	DefaultSegmentMaxBytes int64 = 64 << 20
+
+	segmentFileExt   = ".seg"
+	switchesFileName = "switches.jsonl"
+)
+
+var errSegmentRepositoryClosed = errors.New("segment repository is closed")
+
+// segment describes one append-only file of JSON-encoded telemetry rows
+type segment struct {
+	path         string
+	size         int64
+	count        int64
+	minTimestamp time.Time
+	maxTimestamp time.Time
+	minCreatedAt time.Time
+	maxCreatedAt time.Time
+}
+
+// observe widens the segment's time bounds to include metric
+func (s *segment) observe(metric models.TelemetryData) {
+	if s.count == 0 || metric.Timestamp.Before(s.minTimestamp) {
+		s.minTimestamp = metric.Timestamp
+	}
+	if s.count == 0 || metric.Timestamp.After(s.maxTimestamp) {
+		s.maxTimestamp = metric.Timestamp
+	}
+	if s.count == 0 || metric.CreatedAt.Before(s.minCreatedAt) {
+		s.minCreatedAt = metric.CreatedAt
+	}
+	if s.count == 0 || metric.CreatedAt.After(s.maxCreatedAt) {
+		s.maxCreatedAt = metric.CreatedAt
+	}
+	s.count++
+}
+
+// overlaps reports whether the segment may hold rows with from <= timestamp <= to.
+// A zero bound is open.
+func (s *segment) overlaps(from, to time.Time) bool {
+	if s.count == 0 {
+		return false
+	}
+	if !from.IsZero() && s.maxTimestamp.Before(from) {
+		return false
+	}
+	if !to.IsZero() && s.minTimestamp.After(to) {
+		return false
+	}
+	return true
+}
+
+// SegmentRepository implements TelemetryRepository on top of append-only
+// JSON-lines files in a single directory. Rows are appended to the newest
+// segment, which is rotated once it reaches the configured size; switches are
+// kept in a separate log where the last record for an ID wins. Segment time
+// bounds are held in memory so queries only read files that can match.
+// Intended for edge deployments and local development without PostgreSQL.
+type SegmentRepository struct {
+	dir             string
+	maxSegmentBytes int64
+
+	mu        sync.RWMutex
+	switches  map[string]models.Switch
+	switchLog *os.File
+	segments  []*segment
+	active    *os.File // Open handle on the last segment, nil until the first write
+	latest    map[string]models.TelemetryData
+	nextID    int64
+	nextSeq   int64
+	closed    bool
+}
+
+// NewSegmentRepository opens or creates a segment store in dir. A row or switch
+// record torn by a crash mid-write is truncated away on open.
+func NewSegmentRepository(dir string, maxSegmentBytes int64) (*SegmentRepository, error) {
+	if maxSegmentBytes <= 0 {
+		maxSegmentBytes = DefaultSegmentMaxBytes
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create segment directory %s: %w", dir, err)
+	}
+
+	r := &SegmentRepository{
+		dir:             dir,
+		maxSegmentBytes: maxSegmentBytes,
+		switches:        make(map[string]models.Switch),
+		latest:          make(map[string]models.TelemetryData),
+		nextID:          1,
+	}
+
+	if err := r.loadSwitches(); err != nil {
+		return nil, err
+	}
+	if err := r.loadSegments(); err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Close closes the open switch log and segment handles
+func (r *SegmentRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	var errs []error
+	if r.switchLog != nil {
+		errs = append(errs, r.switchLog.Close())
+		r.switchLog = nil
+	}
+	if r.active != nil {
+		errs = append(errs, r.active.Close())
+		r.active = nil
+	}
+	return errors.Join(errs...)
+}
+
+// CreateSwitch creates a switch or updates the name and location of an existing one
+func (r *SegmentRepository) CreateSwitch(ctx context.Context, sw models.Switch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return errSegmentRepositoryClosed
+	}
+	if existing, ok := r.switches[sw.ID]; ok {
+		sw.Created = existing.Created
+	} else if sw.Created.IsZero() {
+		sw.Created = time.Now()
+	}
+
+	line, err := json.Marshal(sw)
+	if err != nil {
+		return fmt.Errorf("failed to encode switch %s: %w", sw.ID, err)
+	}
+	if err := appendAndSync(r.switchLog, append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to create switch %s: %w", sw.ID, err)
+	}
+
+	r.switches[sw.ID] = sw
+	return nil
+}
+
+// GetSwitch retrieves a switch by ID
+func (r *SegmentRepository) GetSwitch(ctx context.Context, switchID string) (*models.Switch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sw, ok := r.switches[switchID]
+	if !ok {
+		return nil, fmt.Errorf("switch %s not found", switchID)
+	}
+	return &sw, nil
+}
+
+// ListSwitches retrieves all switches ordered by creation time
+func (r *SegmentRepository) ListSwitches(ctx context.Context) ([]models.Switch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	switches := make([]models.Switch, 0, len(r.switches))
+	for _, sw := range r.switches {
+		switches = append(switches, sw)
+	}
+	sort.Slice(switches, func(i, j int) bool {
+		if !switches[i].Created.Equal(switches[j].Created) {
+			return switches[i].Created.Before(switches[j].Created)
+		}
+		return switches[i].ID < switches[j].ID
+	})
+	return switches, nil
+}
+
+// StoreMetrics appends metrics to the active segment as a single write
+func (r *SegmentRepository) StoreMetrics(ctx context.Context, metrics []models.TelemetryData) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return errSegmentRepositoryClosed
+	}
+	if err := r.rotateIfFull(); err != nil {
+		return err
+	}
+	active := r.segments[len(r.segments)-1]
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	rows := make([]models.TelemetryData, 0, len(metrics))
+	now := time.Now()
+	for i, metric := range metrics {
+		if _, ok := r.switches[metric.SwitchID]; !ok {
+			return fmt.Errorf("failed to insert metric for switch %s: %w: switch not found", metric.SwitchID, ErrInvalidData)
+		}
+
+		row := withDefaultTimes(metric, now)
+		row.ID = r.nextID + int64(i)
+		if err := encoder.Encode(row); err != nil {
+			return fmt.Errorf("failed to encode metric for switch %s: %w", metric.SwitchID, err)
+		}
+		rows = append(rows, row)
+	}
+
+	if err := appendAndSync(r.active, buf.Bytes()); err != nil {
+		// Drop any partial write so the next append starts on a record boundary
+		r.active.Truncate(active.size)
+		return fmt.Errorf("failed to append metrics to %s: %w", active.path, err)
+	}
+
+	active.size += int64(buf.Len())
+	for _, row := range rows {
+		active.observe(row)
+		r.observeLatest(row)
+	}
+	r.nextID += int64(len(rows))
+	return nil
+}
+
+// GetLatestMetrics retrieves the most recent metrics for a switch
+func (r *SegmentRepository) GetLatestMetrics(ctx context.Context, switchID string) (*models.TelemetryData, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	metric, ok := r.latest[switchID]
+	if !ok {
+		return nil, fmt.Errorf("no metrics found for switch %s", switchID)
+	}
+	return &metric, nil
+}
+
+// GetHistoricalMetrics retrieves one page of metrics for a switch within a time range
+func (r *SegmentRepository) GetHistoricalMetrics(ctx context.Context, query models.HistoryQuery) (*models.HistoryPage, error) {
+	rows, err := r.scan(ctx, query.From, query.To, func(metric *models.TelemetryData) bool {
+		return metric.SwitchID == query.SwitchID
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query historical metrics: %w", err)
+	}
+	return selectHistoryPage(rows, query), nil
+}
+
+// GetAggregatedMetrics downsamples a switch's metrics into windows of query.Step aligned to query.From
+func (r *SegmentRepository) GetAggregatedMetrics(ctx context.Context, query models.HistoryQuery) ([]models.MetricBucket, error) {
+	if query.Step <= 0 {
+		return nil, fmt.Errorf("aggregation step must be positive")
+	}
+
+	rows, err := r.scan(ctx, query.From, query.To, func(metric *models.TelemetryData) bool {
+		return metric.SwitchID == query.SwitchID
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aggregated metrics: %w", err)
+	}
+	return aggregateRows(rows, query)
+}
+
+// GetSwitchMetricsSummary returns avg/max/p50/p99 per metric for a switch over the trailing window
+func (r *SegmentRepository) GetSwitchMetricsSummary(ctx context.Context, switchID string, window time.Duration) (*models.SwitchSummary, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("summary window must be positive")
+	}
+
+	since := time.Now().Add(-window)
+	rows, err := r.scan(ctx, since, time.Time{}, func(metric *models.TelemetryData) bool {
+		return metric.SwitchID == switchID && metric.Timestamp.After(since)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get switch metrics summary: %w", err)
+	}
+	return summarizeRows(switchID, window, rows), nil
+}
+
+// GetTopSwitches ranks switches by a metric reduced over the trailing query.Window
+func (r *SegmentRepository) GetTopSwitches(ctx context.Context, query models.TopSwitchesQuery) ([]models.RankedSwitch, error) {
+	if query.Window <= 0 {
+		return nil, fmt.Errorf("ranking window must be positive")
+	}
+
+	since := time.Now().Add(-query.Window)
+	rows, err := r.scan(ctx, since, time.Time{}, func(metric *models.TelemetryData) bool {
+		return metric.Timestamp.After(since)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top switches: %w", err)
+	}
+	return rankRows(rows, query)
+}
+
+// DeleteOldMetrics removes metrics created before olderThan. Segments that are
+// entirely older are deleted; segments that straddle the cutoff are rewritten.
+func (r *SegmentRepository) DeleteOldMetrics(ctx context.Context, olderThan time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := make([]*segment, 0, len(r.segments))
+	changed := false
+	for i, seg := range r.segments {
+		isActive := i == len(r.segments)-1
+		switch {
+		case seg.count == 0 || !seg.minCreatedAt.Before(olderThan):
+			kept = append(kept, seg)
+			continue
+		case seg.maxCreatedAt.Before(olderThan) && !isActive:
+			if err := os.Remove(seg.path); err != nil {
+				return fmt.Errorf("failed to delete segment %s: %w", seg.path, err)
+			}
+		default:
+			if err := r.compactSegment(seg, olderThan, isActive); err != nil {
+				return err
+			}
+			kept = append(kept, seg)
+		}
+		changed = true
+	}
+	r.segments = kept
+
+	if changed {
+		return r.rebuildLatest(ctx)
+	}
+	return nil
+}
+
+// GetMetricsCount returns the total number of stored metrics
+func (r *SegmentRepository) GetMetricsCount(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, seg := range r.segments {
+		count += seg.count
+	}
+	return count, nil
+}
+
+// scan returns the rows of every segment overlapping [from, to] that pass match
+func (r *SegmentRepository) scan(ctx context.Context, from, to time.Time, match func(*models.TelemetryData) bool) ([]models.TelemetryData, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var rows []models.TelemetryData
+	for _, seg := range r.segments {
+		if !seg.overlaps(from, to) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		_, err := readSegment(seg.path, func(metric models.TelemetryData) {
+			if !from.IsZero() && metric.Timestamp.Before(from) {
+				return
+			}
+			if !to.IsZero() && metric.Timestamp.After(to) {
+				return
+			}
+			if match(&metric) {
+				rows = append(rows, metric)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// rotateIfFull makes sure an active segment below the size limit is open for appends
+func (r *SegmentRepository) rotateIfFull() error {
+	if r.active != nil && r.segments[len(r.segments)-1].size < r.maxSegmentBytes {
+		return nil
+	}
+
+	if r.active != nil {
+		if err := r.active.Close(); err != nil {
+			return fmt.Errorf("failed to close segment: %w", err)
+		}
+		r.active = nil
+	}
+
+	r.nextSeq++
+	path := filepath.Join(r.dir, fmt.Sprintf("%020d%s", r.nextSeq, segmentFileExt))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create segment %s: %w", path, err)
+	}
+
+	r.active = file
+	r.segments = append(r.segments, &segment{path: path})
+	return nil
+}
+
+// compactSegment rewrites a segment without the rows created before olderThan
+func (r *SegmentRepository) compactSegment(seg *segment, olderThan time.Time, isActive bool) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	compacted := &segment{path: seg.path}
+
+	var encodeErr error
+	_, err := readSegment(seg.path, func(metric models.TelemetryData) {
+		if encodeErr != nil || metric.CreatedAt.Before(olderThan) {
+			return
+		}
+		encodeErr = encoder.Encode(metric)
+		compacted.observe(metric)
+	})
+	if err == nil {
+		err = encodeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compact segment %s: %w", seg.path, err)
+	}
+
+	tmpPath := seg.path + ".tmp"
+	if err := writeFileSync(tmpPath, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to compact segment %s: %w", seg.path, err)
+	}
+
+	// The active handle would keep appending to the replaced inode, so reopen it
+	if isActive && r.active != nil {
+		r.active.Close()
+		r.active = nil
+	}
+	if err := os.Rename(tmpPath, seg.path); err != nil {
+		return fmt.Errorf("failed to replace segment %s: %w", seg.path, err)
+	}
+	if isActive {
+		file, err := os.OpenFile(seg.path, os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to reopen segment %s: %w", seg.path, err)
+		}
+		r.active = file
+	}
+
+	compacted.size = int64(buf.Len())
+	*seg = *compacted
+	return nil
+}
+
+// rebuildLatest recomputes the latest row per switch after rows were deleted
+func (r *SegmentRepository) rebuildLatest(ctx context.Context) error {
+	r.latest = make(map[string]models.TelemetryData)
+	for _, seg := range r.segments {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := readSegment(seg.path, r.observeLatest); err != nil {
+			return fmt.Errorf("failed to read segment %s: %w", seg.path, err)
+		}
+	}
+	return nil
+}
+
+func (r *SegmentRepository) observeLatest(metric models.TelemetryData) {
+	if current, ok := r.latest[metric.SwitchID]; !ok || !metric.Timestamp.Before(current.Timestamp) {
+		r.latest[metric.SwitchID] = metric
+	}
+}
+
+// loadSwitches replays the switch log and opens it for appends
+func (r *SegmentRepository) loadSwitches() error {
+	path := filepath.Join(r.dir, switchesFileName)
+	validSize, err := readLines(path, func(line []byte) error {
+		var sw models.Switch
+		if err := json.Unmarshal(line, &sw); err != nil {
+			return err
+		}
+		r.switches[sw.ID] = sw
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to load switches from %s: %w", path, err)
+	}
+
+	file, err := openForAppend(path, validSize)
+	if err != nil {
+		return fmt.Errorf("failed to open switch log %s: %w", path, err)
+	}
+	r.switchLog = file
+	return nil
+}
+
+// loadSegments indexes the existing segments and reopens the newest for appends
+func (r *SegmentRepository) loadSegments() error {
+	paths, err := filepath.Glob(filepath.Join(r.dir, "*"+segmentFileExt))
+	if err != nil {
+		return fmt.Errorf("failed to list segments: %w", err)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		var seq int64
+		if _, err := fmt.Sscanf(strings.TrimSuffix(filepath.Base(path), segmentFileExt), "%d", &seq); err != nil {
+			return fmt.Errorf("unexpected segment file name %s", path)
+		}
+
+		seg := &segment{path: path}
+		validSize, err := readSegment(path, func(metric models.TelemetryData) {
+			seg.observe(metric)
+			r.observeLatest(metric)
+			if metric.ID >= r.nextID {
+				r.nextID = metric.ID + 1
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load segment %s: %w", path, err)
+		}
+		seg.size = validSize
+
+		r.segments = append(r.segments, seg)
+		r.nextSeq = seq
+	}
+
+	if len(r.segments) > 0 {
+		last := r.segments[len(r.segments)-1]
+		file, err := openForAppend(last.path, last.size)
+		if err != nil {
+			return fmt.Errorf("failed to open segment %s: %w", last.path, err)
+		}
+		r.active = file
+	}
+	return nil
+}
+
+// readSegment decodes every complete row of a segment file
+func readSegment(path string, fn func(models.TelemetryData)) (int64, error) {
+	return readLines(path, func(line []byte) error {
+		var metric models.TelemetryData
+		if err := json.Unmarshal(line, &metric); err != nil {
+			return err
+		}
+		fn(metric)
+		return nil
+	})
+}
+
+// readLines calls fn for each newline-terminated line of path and returns the
+// offset just past the last one. A trailing partial line is ignored.
+func readLines(path string, fn func([]byte) error) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+		if err := fn(line[:len(line)-1]); err != nil {
+			return offset, fmt.Errorf("corrupt record at offset %d: %w", offset, err)
+		}
+		offset += int64(len(line))
+	}
+}
+
+// openForAppend opens path for appends after truncating anything past validSize
+func openForAppend(path string, validSize int64) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Truncate(validSize); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+func appendAndSync(file *os.File, data []byte) error {
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,414 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ufm/internal/telemetry/models"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteSchema mirrors the PostgreSQL tables. Timestamps are stored as Unix
+// nanoseconds so range scans and ordering stay exact.
+const sqliteSchema = `
+	CREATE TABLE IF NOT EXISTS switches (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		location TEXT,
+		created_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS telemetry_metrics (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		switch_id TEXT NOT NULL REFERENCES switches(id) ON DELETE CASCADE,
+		timestamp INTEGER NOT NULL,
+		bandwidth_mbps REAL NOT NULL DEFAULT 0,
+		latency_ms REAL NOT NULL DEFAULT 0,
+		packet_errors INTEGER NOT NULL DEFAULT 0,
+		utilization_pct REAL NOT NULL DEFAULT 0,
+		temperature_c REAL NOT NULL DEFAULT 0,
+		created_at INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_telemetry_switch_time ON telemetry_metrics(switch_id, timestamp);
+	CREATE INDEX IF NOT EXISTS idx_telemetry_timestamp ON telemetry_metrics(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_telemetry_created_at ON telemetry_metrics(created_at);
+`
+
+const sqliteMetricColumns = `id, switch_id, timestamp, bandwidth_mbps, latency_ms,
+	packet_errors, utilization_pct, temperature_c, created_at`
+
+// OpenSQLiteDB opens a SQLite database file with foreign keys enforced and WAL
+// journaling. SQLite allows a single writer, so the pool is limited to one connection.
+func OpenSQLiteDB(path string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create sqlite directory: %w", err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database %s: %w", path, err)
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// SQLiteRepository implements the TelemetryRepository interface on SQLite.
+// Percentiles and downsampling are computed in Go from the selected rows.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a SQLite repository, creating its tables if needed
+func NewSQLiteRepository(ctx context.Context, db *sql.DB) (*SQLiteRepository, error) {
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+	return &SQLiteRepository{
+		db: db,
+	}, nil
+}
+
+// Stats returns the connection pool statistics
+func (r *SQLiteRepository) Stats() sql.DBStats {
+	return r.db.Stats()
+}
+
+// Close closes the database connection
+func (r *SQLiteRepository) Close() error {
+	if r.db != nil {
+		return r.db.Close()
+	}
+	return nil
+}
+
+// CreateSwitch creates a new switch in the database
+func (r *SQLiteRepository) CreateSwitch(ctx context.Context, sw models.Switch) error {
+	query := `
+		INSERT INTO switches (id, name, location, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			name = excluded.name,
+			location = excluded.location
+	`
+
+	createdAt := sw.Created
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	_, err := r.db.ExecContext(ctx, query, sw.ID, sw.Name, sw.Location, createdAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to create switch %s: %w", sw.ID, err)
+	}
+
+	return nil
+}
+
+// GetSwitch retrieves a switch by ID
+func (r *SQLiteRepository) GetSwitch(ctx context.Context, switchID string) (*models.Switch, error) {
+	query := `SELECT id, name, location, created_at FROM switches WHERE id = ?`
+
+	sw, err := scanSQLiteSwitch(r.db.QueryRowContext(ctx, query, switchID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("switch %s not found", switchID)
+		}
+		return nil, fmt.Errorf("failed to get switch %s: %w", switchID, err)
+	}
+
+	return sw, nil
+}
+
+// ListSwitches retrieves all switches
+func (r *SQLiteRepository) ListSwitches(ctx context.Context) ([]models.Switch, error) {
+	query := `SELECT id, name, location, created_at FROM switches ORDER BY created_at ASC, id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list switches: %w", err)
+	}
+	defer rows.Close()
+
+	var switches []models.Switch
+	for rows.Next() {
+		sw, err := scanSQLiteSwitch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan switch row: %w", err)
+		}
+		switches = append(switches, *sw)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating switch rows: %w", err)
+	}
+
+	return switches, nil
+}
+
+// sqliteDataError marks constraint violations, such as a sample of an unknown
+// switch, and type mismatches with ErrInvalidData
+func sqliteDataError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff { // primary result code
+		case sqlite3.SQLITE_CONSTRAINT, sqlite3.SQLITE_MISMATCH:
+			return fmt.Errorf("%w: %w", ErrInvalidData, err)
+		}
+	}
+	return err
+}
+
+// StoreMetrics stores multiple telemetry metrics in a single transaction
+func (r *SQLiteRepository) StoreMetrics(ctx context.Context, metrics []models.TelemetryData) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO telemetry_metrics
+		(switch_id, timestamp, bandwidth_mbps, latency_ms, packet_errors, utilization_pct, temperature_c, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, metric := range metrics {
+		metric = withDefaultTimes(metric, now)
+		_, err = stmt.ExecContext(ctx,
+			metric.SwitchID,
+			metric.Timestamp.UnixNano(),
+			metric.BandwidthMbps,
+			metric.LatencyMs,
+			metric.PacketErrors,
+			metric.UtilizationPct,
+			metric.TemperatureC,
+			metric.CreatedAt.UnixNano(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert metric for switch %s: %w", metric.SwitchID, sqliteDataError(err))
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", sqliteDataError(err))
+	}
+
+	return nil
+}
+
+// GetLatestMetrics retrieves the most recent metrics for a switch
+func (r *SQLiteRepository) GetLatestMetrics(ctx context.Context, switchID string) (*models.TelemetryData, error) {
+	query := `SELECT ` + sqliteMetricColumns + `
+		FROM telemetry_metrics
+		WHERE switch_id = ?
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 1
+	`
+
+	metric, err := scanSQLiteMetric(r.db.QueryRowContext(ctx, query, switchID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no metrics found for switch %s", switchID)
+		}
+		return nil, fmt.Errorf("failed to get latest metrics for switch %s: %w", switchID, err)
+	}
+
+	return metric, nil
+}
+
+// GetHistoricalMetrics retrieves one page of metrics for a switch within a time range.
+// Pages are ordered by (timestamp, id) ascending and resume after query.After.
+func (r *SQLiteRepository) GetHistoricalMetrics(ctx context.Context, query models.HistoryQuery) (*models.HistoryPage, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultHistoryPageSize
+	}
+
+	sqlQuery := `SELECT ` + sqliteMetricColumns + `
+		FROM telemetry_metrics
+		WHERE switch_id = ? AND timestamp >= ? AND timestamp <= ?
+	`
+	args := []interface{}{query.SwitchID, query.From.UnixNano(), query.To.UnixNano()}
+
+	if query.After != nil {
+		sqlQuery += ` AND (timestamp, id) > (?, ?)`
+		args = append(args, query.After.Timestamp.UnixNano(), query.After.ID)
+	}
+
+	// Fetch one extra row to find out whether another page exists
+	sqlQuery += ` ORDER BY timestamp ASC, id ASC LIMIT ?`
+	args = append(args, limit+1)
+
+	metrics, err := r.queryMetrics(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query historical metrics: %w", err)
+	}
+
+	return newHistoryPage(metrics, limit), nil
+}
+
+// GetAggregatedMetrics downsamples a switch's metrics into windows of query.Step
+// aligned to query.From, reducing each window with query.Aggregation. Windows
+// without samples are not returned.
+func (r *SQLiteRepository) GetAggregatedMetrics(ctx context.Context, query models.HistoryQuery) ([]models.MetricBucket, error) {
+	if query.Step <= 0 {
+		return nil, fmt.Errorf("aggregation step must be positive")
+	}
+
+	sqlQuery := `SELECT ` + sqliteMetricColumns + `
+		FROM telemetry_metrics
+		WHERE switch_id = ? AND timestamp >= ? AND timestamp <= ?
+	`
+
+	metrics, err := r.queryMetrics(ctx, sqlQuery, query.SwitchID, query.From.UnixNano(), query.To.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aggregated metrics: %w", err)
+	}
+
+	return aggregateRows(metrics, query)
+}
+
+// DeleteOldMetrics removes metrics older than the specified time
+func (r *SQLiteRepository) DeleteOldMetrics(ctx context.Context, olderThan time.Time) error {
+	query := `DELETE FROM telemetry_metrics WHERE created_at < ?`
+
+	if _, err := r.db.ExecContext(ctx, query, olderThan.UnixNano()); err != nil {
+		return fmt.Errorf("failed to delete old metrics: %w", err)
+	}
+
+	return nil
+}
+
+// GetMetricsCount returns the total number of metrics in the database
+func (r *SQLiteRepository) GetMetricsCount(ctx context.Context) (int64, error) {
+	query := `SELECT COUNT(*) FROM telemetry_metrics`
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get metrics count: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetSwitchMetricsSummary returns avg/max/p50/p99 per metric for a switch over the trailing window
+func (r *SQLiteRepository) GetSwitchMetricsSummary(ctx context.Context, switchID string, window time.Duration) (*models.SwitchSummary, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("summary window must be positive")
+	}
+
+	query := `SELECT ` + sqliteMetricColumns + `
+		FROM telemetry_metrics
+		WHERE switch_id = ? AND timestamp > ?
+	`
+
+	since := time.Now().Add(-window)
+	metrics, err := r.queryMetrics(ctx, query, switchID, since.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get switch metrics summary: %w", err)
+	}
+
+	return summarizeRows(switchID, window, metrics), nil
+}
+
+// GetTopSwitches ranks switches by a metric reduced over the trailing query.Window
+func (r *SQLiteRepository) GetTopSwitches(ctx context.Context, query models.TopSwitchesQuery) ([]models.RankedSwitch, error) {
+	if query.Window <= 0 {
+		return nil, fmt.Errorf("ranking window must be positive")
+	}
+
+	sqlQuery := `SELECT ` + sqliteMetricColumns + `
+		FROM telemetry_metrics
+		WHERE timestamp > ?
+	`
+
+	since := time.Now().Add(-query.Window)
+	metrics, err := r.queryMetrics(ctx, sqlQuery, since.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top switches: %w", err)
+	}
+
+	return rankRows(metrics, query)
+}
+
+func (r *SQLiteRepository) queryMetrics(ctx context.Context, query string, args ...interface{}) ([]models.TelemetryData, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metrics []models.TelemetryData
+	for rows.Next() {
+		metric, err := scanSQLiteMetric(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan metric row: %w", err)
+		}
+		metrics = append(metrics, *metric)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating metric rows: %w", err)
+	}
+
+	return metrics, nil
+}
+
+// sqliteScanner is satisfied by both *sql.Row and *sql.Rows
+type sqliteScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSQLiteSwitch(row sqliteScanner) (*models.Switch, error) {
+	var sw models.Switch
+	var location sql.NullString
+	var createdAt int64
+	if err := row.Scan(&sw.ID, &sw.Name, &location, &createdAt); err != nil {
+		return nil, err
+	}
+	sw.Location = location.String
+	sw.Created = time.Unix(0, createdAt).UTC()
+	return &sw, nil
+}
+
+func scanSQLiteMetric(row sqliteScanner) (*models.TelemetryData, error) {
+	var metric models.TelemetryData
+	var timestamp, createdAt int64
+	err := row.Scan(
+		&metric.ID,
+		&metric.SwitchID,
+		&timestamp,
+		&metric.BandwidthMbps,
+		&metric.LatencyMs,
+		&metric.PacketErrors,
+		&metric.UtilizationPct,
+		&metric.TemperatureC,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	metric.Timestamp = time.Unix(0, timestamp).UTC()
+	metric.CreatedAt = time.Unix(0, createdAt).UTC()
+	return &metric, nil
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/ufm/internal/telemetry/models"
+)
+
+// rankedHeap keeps the K best candidates seen so far with the weakest at the root,
+// so each new candidate is compared against the root and replaces it when better.
+type rankedHeap struct {
+	items      []models.RankedSwitch
+	descending bool
+}
+
+func (h *rankedHeap) Len() int { return len(h.items) }
+
+// Less orders the weakest candidate first
+func (h *rankedHeap) Less(i, j int) bool { return ranksBelow(h.items[i], h.items[j], h.descending) }
+
+func (h *rankedHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *rankedHeap) Push(x interface{}) { h.items = append(h.items, x.(models.RankedSwitch)) }
+
+func (h *rankedHeap) Pop() interface{} {
+	old := h.items
+	n := len(old)
+	item := old[n-1]
+	h.items = old[:n-1]
+	return item
+}
+
+// selectTopK returns the k best candidates in rank order using a bounded heap
+func selectTopK(candidates []models.RankedSwitch, k int, descending bool) []models.RankedSwitch {
+	if k <= 0 {
+		return []models.RankedSwitch{}
+	}
+
+	h := &rankedHeap{items: make([]models.RankedSwitch, 0, k), descending: descending}
+	for _, candidate := range candidates {
+		if h.Len() < k {
+			heap.Push(h, candidate)
+			continue
+		}
+		// Replace the weakest kept candidate if the new one ranks higher
+		if ranksBelow(h.items[0], candidate, descending) {
+			h.items[0] = candidate
+			heap.Fix(h, 0)
+		}
+	}
+
+	result := h.items
+	sort.Slice(result, func(i, j int) bool { return ranksBelow(result[j], result[i], descending) })
+	return result
+}
+
+// ranksBelow reports whether a ranks below b: a lower value when ranking
+// descending, a higher one when ascending. Ties fall back to switch ID.
+func ranksBelow(a, b models.RankedSwitch, descending bool) bool {
+	if a.Value != b.Value {
+		if descending {
+			return a.Value < b.Value
+		}
+		return a.Value > b.Value
+	}
+	return a.SwitchID > b.SwitchID
+}
+
+// TopSwitches ranks the latest cached sample of every switch by a metric and returns the top k
+func (c *InMemoryCache) TopSwitches(metricType models.MetricType, k int, descending bool) ([]models.RankedSwitch, error) {
+	allData := c.ListAllSwitches()
+
+	candidates := make([]models.RankedSwitch, 0, len(allData))
+	for switchID, data := range allData {
+		value, err := data.GetMetricFloat(metricType)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, models.RankedSwitch{
+			SwitchID:  switchID,
+			Value:     value,
+			Timestamp: data.Timestamp,
+		})
+	}
+
+	return selectTopK(candidates, k, descending), nil
+}
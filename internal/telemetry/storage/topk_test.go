@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+func TestSelectTopK(t *testing.T) {
+	candidates := []models.RankedSwitch{
+		{SwitchID: "switch-001", Value: 40},
+		{SwitchID: "switch-002", Value: 55},
+		{SwitchID: "switch-003", Value: 31},
+		{SwitchID: "switch-004", Value: 58},
+		{SwitchID: "switch-005", Value: 55},
+	}
+
+	top := selectTopK(candidates, 3, true)
+	assert.Equal(t, []string{"switch-004", "switch-002", "switch-005"}, rankedIDs(top))
+
+	bottom := selectTopK(candidates, 2, false)
+	assert.Equal(t, []string{"switch-003", "switch-001"}, rankedIDs(bottom))
+
+	assert.Len(t, selectTopK(candidates, 10, true), len(candidates))
+	assert.Empty(t, selectTopK(candidates, 0, true))
+}
+
+func TestInMemoryCache_TopSwitches(t *testing.T) {
+	cache := NewInMemoryCache()
+	_ = cache.UpdateMetrics("switch-001", models.TelemetryData{TemperatureC: 42})
+	_ = cache.UpdateMetrics("switch-002", models.TelemetryData{TemperatureC: 57})
+	_ = cache.UpdateMetrics("switch-003", models.TelemetryData{TemperatureC: 49})
+
+	top, err := cache.TopSwitches(models.MetricTemperature, 2, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"switch-002", "switch-003"}, rankedIDs(top))
+	assert.Equal(t, 57.0, top[0].Value)
+
+	_, err = cache.TopSwitches("unknown", 2, true)
+	assert.Error(t, err)
+}
+
+func rankedIDs(ranked []models.RankedSwitch) []string {
+	ids := make([]string, 0, len(ranked))
+	for _, r := range ranked {
+		ids = append(ids, r.SwitchID)
+	}
+	return ids
+}
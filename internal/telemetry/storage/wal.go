@@ -0,0 +1,424 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/monitoring/metrics"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+const (
+	// DefaultWALMaxBytes bounds the disk space used by the write-ahead log
+	DefaultWALMaxBytes int64 = 256 << 20
+	// DefaultWALSegmentBytes is the size at which the active WAL segment is rotated
+	DefaultWALSegmentBytes int64 = 8 << 20
+	// DefaultWALDrainInterval is how often buffered batches are retried
+	DefaultWALDrainInterval = 10 * time.Second
+
+	walFileExt = ".wal"
+)
+
+var errWALClosed = errors.New("write-ahead log is closed")
+
+// partialWriteError is returned by a Drain write function that handled the first
+// written rows of a batch before failing. Drain keeps only the rows after them.
+type partialWriteError struct {
+	written int
+	err     error
+}
+
+func (e *partialWriteError) Error() string { return e.err.Error() }
+
+func (e *partialWriteError) Unwrap() error { return e.err }
+
+// WALConfig controls the size of the write-ahead log
+type WALConfig struct {
+	MaxBytes     int64 // Oldest segments are evicted beyond this size
+	SegmentBytes int64 // Active segment is rotated at this size
+}
+
+// walSegment is one file of JSON-encoded batches, one batch per line
+type walSegment struct {
+	path    string
+	size    int64
+	batches int64
+}
+
+// WAL is an on-disk buffer of telemetry batches that could not be written to the
+// database yet. Batches are appended and fsynced to the newest segment; Drain
+// hands them back oldest first and deletes them once written. When the log
+// exceeds its size bound the oldest segments are evicted, so a long database
+// outage loses the oldest samples instead of filling the disk.
+type WAL struct {
+	dir    string
+	config WALConfig
+	logger log.Logger
+
+	drainMu  sync.Mutex // Serializes Drain calls
+	mu       sync.Mutex
+	segments []*walSegment // Oldest first, the last one is active
+	active   *os.File      // Open handle on the last segment, nil until the first append
+	nextSeq  int64
+	closed   bool
+}
+
+// OpenWAL opens or creates a write-ahead log in dir. Batches left by a previous
+// run are kept for the next Drain; a batch torn by a crash mid-write is
+// truncated away.
+func OpenWAL(dir string, config WALConfig, logger log.Logger) (*WAL, error) {
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = DefaultWALMaxBytes
+	}
+	if config.SegmentBytes <= 0 {
+		config.SegmentBytes = DefaultWALSegmentBytes
+	}
+	if config.SegmentBytes > config.MaxBytes {
+		config.SegmentBytes = config.MaxBytes
+	}
+	if logger == nil {
+		logger = log.DefaultLogger
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create WAL directory %s: %w", dir, err)
+	}
+
+	w := &WAL{dir: dir, config: config, logger: logger}
+	if err := w.load(); err != nil {
+		w.Close()
+		return nil, err
+	}
+	w.reportBacklog()
+
+	if batches := w.Batches(); batches > 0 {
+		logger.Infof("Write-ahead log holds %d pending telemetry batches (%d bytes)", batches, w.Size())
+	}
+	return w, nil
+}
+
+// Append durably stores one batch of metrics
+func (w *WAL) Append(batch []models.TelemetryData) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	line, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to encode WAL batch: %w", err)
+	}
+	line = append(line, '\n')
+	if int64(len(line)) > w.config.MaxBytes {
+		return fmt.Errorf("batch of %d bytes exceeds the WAL size limit", len(line))
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errWALClosed
+	}
+	if w.active == nil || w.activeSegment().size+int64(len(line)) > w.config.SegmentBytes {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	w.evict(int64(len(line)))
+
+	if err := appendAndSync(w.active, line); err != nil {
+		return fmt.Errorf("failed to append to WAL: %w", err)
+	}
+
+	seg := w.activeSegment()
+	seg.size += int64(len(line))
+	seg.batches++
+	metrics.WALBatchesTotal.WithLabelValues("written").Inc()
+	w.reportBacklogLocked()
+	return nil
+}
+
+// Drain passes every stored batch to write, oldest first, and removes the ones
+// it accepts. It stops at the first error, keeping that batch and everything
+// after it for the next call, and returns the number of batches drained.
+// A partialWriteError keeps only the rows of the batch that were not handled.
+// Appends made while draining go to a new segment and are not blocked.
+func (w *WAL) Drain(write func([]models.TelemetryData) error) (int, error) {
+	w.drainMu.Lock()
+	defer w.drainMu.Unlock()
+
+	// Seal the active segment so the segments drained below are no longer written to
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, errWALClosed
+	}
+	if len(w.segments) == 0 {
+		w.mu.Unlock()
+		return 0, nil
+	}
+	if w.activeSegment().batches > 0 {
+		if err := w.rotate(); err != nil {
+			w.mu.Unlock()
+			return 0, err
+		}
+	}
+	sealed := append([]*walSegment(nil), w.segments[:len(w.segments)-1]...)
+	w.mu.Unlock()
+
+	drained := 0
+	for _, seg := range sealed {
+		var batches [][]models.TelemetryData
+		_, err := readLines(seg.path, func(line []byte) error {
+			var batch []models.TelemetryData
+			if err := json.Unmarshal(line, &batch); err != nil {
+				return err
+			}
+			batches = append(batches, batch)
+			return nil
+		})
+		if errors.Is(err, fs.ErrNotExist) {
+			// Evicted while waiting for the drain lock
+			continue
+		}
+		if err != nil {
+			return drained, fmt.Errorf("failed to read WAL segment %s: %w", seg.path, err)
+		}
+
+		for i, batch := range batches {
+			if err := write(batch); err != nil {
+				var partial *partialWriteError
+				if errors.As(err, &partial) {
+					batches[i] = batch[partial.written:]
+				}
+				return drained, w.keep(seg, batches[i:], err)
+			}
+			drained++
+			metrics.WALBatchesTotal.WithLabelValues("drained").Inc()
+		}
+
+		w.mu.Lock()
+		w.remove(seg)
+		w.reportBacklogLocked()
+		w.mu.Unlock()
+	}
+
+	return drained, nil
+}
+
+// Batches returns the number of batches waiting to be drained
+func (w *WAL) Batches() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var batches int64
+	for _, seg := range w.segments {
+		batches += seg.batches
+	}
+	return batches
+}
+
+// Size returns the bytes used by the log on disk
+func (w *WAL) Size() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var size int64
+	for _, seg := range w.segments {
+		size += seg.size
+	}
+	return size
+}
+
+// Close closes the active segment. Pending batches stay on disk for the next run.
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	if w.active == nil {
+		return nil
+	}
+	err := w.active.Close()
+	w.active = nil
+	return err
+}
+
+// keep rewrites a partially drained segment with the batches that were not written
+func (w *WAL) keep(seg *walSegment, remaining [][]models.TelemetryData, cause error) error {
+	var buf bytes.Buffer
+	for _, batch := range remaining {
+		line, err := json.Marshal(batch)
+		if err != nil {
+			return fmt.Errorf("failed to encode WAL batch: %w", err)
+		}
+		buf.Write(append(line, '\n'))
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.contains(seg) {
+		return cause
+	}
+
+	// Write a temporary file and rename it so a crash leaves either version intact
+	tmp := seg.path + ".tmp"
+	if err := writeFileSync(tmp, buf.Bytes()); err != nil {
+		return errors.Join(cause, fmt.Errorf("failed to rewrite WAL segment %s: %w", seg.path, err))
+	}
+	if err := os.Rename(tmp, seg.path); err != nil {
+		return errors.Join(cause, fmt.Errorf("failed to rewrite WAL segment %s: %w", seg.path, err))
+	}
+
+	seg.size = int64(buf.Len())
+	seg.batches = int64(len(remaining))
+	w.reportBacklogLocked()
+	return cause
+}
+
+// rotate starts a new active segment. Callers hold w.mu.
+func (w *WAL) rotate() error {
+	if w.active != nil {
+		if err := w.active.Close(); err != nil {
+			return fmt.Errorf("failed to close WAL segment: %w", err)
+		}
+		w.active = nil
+		// An empty active segment is replaced rather than kept
+		if last := w.activeSegment(); last.batches == 0 {
+			os.Remove(last.path)
+			w.segments = w.segments[:len(w.segments)-1]
+		}
+	}
+
+	w.nextSeq++
+	path := filepath.Join(w.dir, fmt.Sprintf("%020d%s", w.nextSeq, walFileExt))
+	file, err := openForAppend(path, 0)
+	if err != nil {
+		return fmt.Errorf("failed to create WAL segment %s: %w", path, err)
+	}
+
+	w.active = file
+	w.segments = append(w.segments, &walSegment{path: path})
+	return nil
+}
+
+// evict deletes the oldest sealed segments until incoming bytes fit within the
+// size limit. Callers hold w.mu.
+func (w *WAL) evict(incoming int64) {
+	var size int64
+	for _, seg := range w.segments {
+		size += seg.size
+	}
+
+	for size+incoming > w.config.MaxBytes && len(w.segments) > 1 {
+		oldest := w.segments[0]
+		size -= oldest.size
+		w.remove(oldest)
+		metrics.WALBatchesTotal.WithLabelValues("evicted").Add(float64(oldest.batches))
+		w.logger.Warnf("Write-ahead log is full, discarded %d telemetry batches from %s", oldest.batches, filepath.Base(oldest.path))
+	}
+}
+
+// remove deletes a segment file and forgets it. Callers hold w.mu.
+func (w *WAL) remove(seg *walSegment) {
+	for i, candidate := range w.segments {
+		if candidate != seg {
+			continue
+		}
+		if err := os.Remove(seg.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			w.logger.Warnf("Failed to remove WAL segment %s: %v", seg.path, err)
+		}
+		w.segments = append(w.segments[:i], w.segments[i+1:]...)
+		return
+	}
+}
+
+// contains reports whether seg is still part of the log. Callers hold w.mu.
+func (w *WAL) contains(seg *walSegment) bool {
+	for _, candidate := range w.segments {
+		if candidate == seg {
+			return true
+		}
+	}
+	return false
+}
+
+// activeSegment returns the segment appends go to. Callers hold w.mu.
+func (w *WAL) activeSegment() *walSegment {
+	return w.segments[len(w.segments)-1]
+}
+
+// load indexes the existing segments and reopens the newest for appends
+func (w *WAL) load() error {
+	// Leftovers of a rewrite interrupted by a crash; the original is still in place
+	temps, _ := filepath.Glob(filepath.Join(w.dir, "*"+walFileExt+".tmp"))
+	for _, tmp := range temps {
+		os.Remove(tmp)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(w.dir, "*"+walFileExt))
+	if err != nil {
+		return fmt.Errorf("failed to list WAL segments: %w", err)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		var seq int64
+		if _, err := fmt.Sscanf(strings.TrimSuffix(filepath.Base(path), walFileExt), "%d", &seq); err != nil {
+			return fmt.Errorf("unexpected WAL file name %s", path)
+		}
+
+		seg := &walSegment{path: path}
+		validSize, err := readLines(path, func(line []byte) error {
+			if !json.Valid(line) {
+				return errors.New("invalid JSON")
+			}
+			seg.batches++
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load WAL segment %s: %w", path, err)
+		}
+		seg.size = validSize
+
+		w.segments = append(w.segments, seg)
+		w.nextSeq = seq
+	}
+
+	if len(w.segments) > 0 {
+		last := w.activeSegment()
+		file, err := openForAppend(last.path, last.size)
+		if err != nil {
+			return fmt.Errorf("failed to open WAL segment %s: %w", last.path, err)
+		}
+		w.active = file
+	}
+	return nil
+}
+
+// reportBacklog publishes the backlog gauges
+func (w *WAL) reportBacklog() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.reportBacklogLocked()
+}
+
+// reportBacklogLocked publishes the backlog gauges. Callers hold w.mu.
+func (w *WAL) reportBacklogLocked() {
+	var size, batches int64
+	for _, seg := range w.segments {
+		size += seg.size
+		batches += seg.batches
+	}
+	metrics.WALBacklogBytes.Set(float64(size))
+	metrics.WALBacklogBatches.Set(float64(batches))
+}
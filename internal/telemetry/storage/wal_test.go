@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ufm/internal/telemetry/models"
+)
+
+func walBatch(switchID string, temperatures ...float64) []models.TelemetryData {
+	batch := make([]models.TelemetryData, 0, len(temperatures))
+	for _, temperature := range temperatures {
+		batch = append(batch, models.TelemetryData{SwitchID: switchID, TemperatureC: temperature})
+	}
+	return batch
+}
+
+func TestWAL_AppendDrainAndReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	wal, err := OpenWAL(dir, WALConfig{}, nil)
+	require.NoError(t, err)
+	require.NoError(t, wal.Append(walBatch("switch-001", 40, 41)))
+	require.NoError(t, wal.Append(walBatch("switch-002", 50)))
+	require.NoError(t, wal.Close())
+
+	// Simulate a crash part way through appending a batch
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+walFileExt))
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	file, err := os.OpenFile(segments[0], os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`[{"switch_id":"swi`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	wal, err = OpenWAL(dir, WALConfig{}, nil)
+	require.NoError(t, err)
+	defer wal.Close()
+	assert.Equal(t, int64(2), wal.Batches())
+
+	var replayed [][]models.TelemetryData
+	drained, err := wal.Drain(func(batch []models.TelemetryData) error {
+		replayed = append(replayed, batch)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, drained)
+	require.Len(t, replayed, 2)
+	assert.Equal(t, 41.0, replayed[0][1].TemperatureC)
+	assert.Equal(t, "switch-002", replayed[1][0].SwitchID)
+
+	assert.Zero(t, wal.Batches())
+	assert.Zero(t, wal.Size())
+}
+
+func TestWAL_DrainKeepsUnwrittenBatches(t *testing.T) {
+	wal, err := OpenWAL(t.TempDir(), WALConfig{}, nil)
+	require.NoError(t, err)
+	defer wal.Close()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, wal.Append(walBatch("switch-001", float64(i))))
+	}
+
+	// The second batch fails, so it and the third stay in the log
+	calls := 0
+	drained, err := wal.Drain(func(batch []models.TelemetryData) error {
+		calls++
+		if calls == 2 {
+			return errors.New("database unavailable")
+		}
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, drained)
+	assert.Equal(t, int64(2), wal.Batches())
+
+	// Batches appended meanwhile are drained after the older ones
+	require.NoError(t, wal.Append(walBatch("switch-001", 3)))
+
+	var temperatures []float64
+	drained, err = wal.Drain(func(batch []models.TelemetryData) error {
+		temperatures = append(temperatures, batch[0].TemperatureC)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, drained)
+	assert.Equal(t, []float64{1, 2, 3}, temperatures)
+}
+
+func TestWAL_EvictsOldestSegmentsBeyondMaxBytes(t *testing.T) {
+	line, err := os.ReadFile(writeOneBatch(t))
+	require.NoError(t, err)
+
+	// Room for two segments of two batches each
+	batchBytes := int64(len(line))
+	wal, err := OpenWAL(t.TempDir(), WALConfig{MaxBytes: 4 * batchBytes, SegmentBytes: 2 * batchBytes}, nil)
+	require.NoError(t, err)
+	defer wal.Close()
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, wal.Append(walBatch("switch-001", float64(10+i))))
+		assert.LessOrEqual(t, wal.Size(), 4*batchBytes)
+	}
+
+	var temperatures []float64
+	_, err = wal.Drain(func(batch []models.TelemetryData) error {
+		temperatures = append(temperatures, batch[0].TemperatureC)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{14, 15, 16}, temperatures, "the oldest segments are evicted whole")
+}
+
+// writeOneBatch returns the path of a WAL segment holding a single test batch
+func writeOneBatch(t *testing.T) string {
+	dir := t.TempDir()
+	wal, err := OpenWAL(dir, WALConfig{}, nil)
+	require.NoError(t, err)
+	require.NoError(t, wal.Append(walBatch("switch-001", 10)))
+	require.NoError(t, wal.Close())
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+walFileExt))
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	return segments[0]
+}
+
+// flakyRepository fails every write while down or failWrites is set, and only
+// fails pings while down is set. With failFrom set, the write calls from that
+// one on fail as well.
+type flakyRepository struct {
+	*MemoryRepository
+	down       atomic.Bool
+	failWrites atomic.Bool
+	failFrom   atomic.Int64
+	writes     atomic.Int64
+}
+
+func (r *flakyRepository) Ping(ctx context.Context) error {
+	if r.down.Load() {
+		return errors.New("database unavailable")
+	}
+	return nil
+}
+
+func (r *flakyRepository) StoreMetrics(ctx context.Context, metrics []models.TelemetryData) error {
+	if r.down.Load() {
+		return errors.New("database unavailable")
+	}
+	if from := r.failFrom.Load(); r.failWrites.Load() || (from > 0 && r.writes.Add(1) >= from) {
+		return errors.New("statement timeout")
+	}
+	return r.MemoryRepository.StoreMetrics(ctx, metrics)
+}
+
+func TestHybridStore_BuffersInWALUntilDatabaseRecovers(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	repo := &flakyRepository{MemoryRepository: NewMemoryRepository(100)}
+	createSwitches(t, repo, "switch-001")
+	repo.down.Store(true)
+	config := DefaultHybridStoreConfig()
+	config.MaxRetries = 1
+	config.FlushInterval = 10 * time.Millisecond
+
+	wal, err := OpenWAL(dir, WALConfig{}, nil)
+	require.NoError(t, err)
+	store := NewHybridStore(NewInMemoryCache(), repo, config, nil, WithWAL(wal, 10*time.Millisecond))
+	require.NoError(t, store.Start(ctx))
+
+	require.NoError(t, store.UpdateMetrics("switch-001", models.TelemetryData{SwitchID: "switch-001", TemperatureC: 40}))
+	require.Eventually(t, func() bool { return wal.Batches() == 1 }, time.Second, 5*time.Millisecond)
+
+	// Once the database is back the batch is written, and one it rejects is
+	// discarded instead of blocking the log
+	repo.down.Store(false)
+	require.NoError(t, wal.Append(walBatch("switch-unknown", 99)))
+	require.Eventually(t, func() bool { return wal.Batches() == 0 }, time.Second, 5*time.Millisecond)
+	repo.down.Store(true)
+	require.NoError(t, wal.Append(walBatch("switch-001", 41)))
+
+	// Stopping keeps the batch on disk for the next run
+	require.NoError(t, store.Stop(ctx))
+
+	wal, err = OpenWAL(dir, WALConfig{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), wal.Batches())
+
+	store = NewHybridStore(NewInMemoryCache(), repo, config, nil, WithWAL(wal, 10*time.Millisecond))
+	require.NoError(t, store.Start(ctx))
+	defer store.Stop(ctx)
+
+	repo.down.Store(false)
+	require.Eventually(t, func() bool { return wal.Batches() == 0 }, time.Second, 5*time.Millisecond)
+
+	count, err := repo.GetMetricsCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestHybridStore_DrainDiscardsOnlyRejectedRows(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &flakyRepository{MemoryRepository: NewMemoryRepository(100)}
+	createSwitches(t, repo, "switch-001")
+	wal, err := OpenWAL(t.TempDir(), WALConfig{}, nil)
+	require.NoError(t, err)
+	store := NewHybridStore(NewInMemoryCache(), repo, DefaultHybridStoreConfig(), nil, WithWAL(wal, time.Hour))
+	store.ctx = ctx
+	defer wal.Close()
+
+	// A write failing for any other reason keeps the batch, even though the database answers pings
+	repo.failWrites.Store(true)
+	batch := append(walBatch("switch-001", 40), append(walBatch("switch-unknown", 99), walBatch("switch-001", 41)...)...)
+	require.NoError(t, wal.Append(batch))
+	store.drainWAL()
+	assert.Equal(t, int64(1), wal.Batches())
+
+	repo.failWrites.Store(false)
+	store.drainWAL()
+	assert.Equal(t, int64(0), wal.Batches())
+
+	count, err := repo.GetMetricsCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count, "only the row of the unknown switch is discarded")
+}
+
+func TestHybridStore_DrainKeepsOnlyUnwrittenRows(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &flakyRepository{MemoryRepository: NewMemoryRepository(100)}
+	createSwitches(t, repo, "switch-001")
+	wal, err := OpenWAL(t.TempDir(), WALConfig{}, nil)
+	require.NoError(t, err)
+	store := NewHybridStore(NewInMemoryCache(), repo, DefaultHybridStoreConfig(), nil, WithWAL(wal, time.Hour))
+	store.ctx = ctx
+	defer wal.Close()
+
+	// The batch is rejected, then rows 40 and 99 are handled one by one and the
+	// database times out on 41
+	batch := append(walBatch("switch-001", 40), append(walBatch("switch-unknown", 99), walBatch("switch-001", 41, 42)...)...)
+	require.NoError(t, wal.Append(batch))
+	repo.failFrom.Store(4)
+	store.drainWAL()
+	assert.Equal(t, int64(1), wal.Batches())
+
+	repo.failFrom.Store(0)
+	store.drainWAL()
+	assert.Equal(t, int64(0), wal.Batches())
+
+	count, err := repo.GetMetricsCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count, "rows written before the timeout are not written again")
+}
+
+func TestHybridStore_DrainDiscardsRowsRejectedBySQLite(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := OpenSQLiteDB(filepath.Join(t.TempDir(), "telemetry.db"))
+	require.NoError(t, err)
+	repo, err := NewSQLiteRepository(ctx, db)
+	require.NoError(t, err)
+	defer repo.Close()
+	createSwitches(t, repo, "switch-001")
+
+	wal, err := OpenWAL(t.TempDir(), WALConfig{}, nil)
+	require.NoError(t, err)
+	store := NewHybridStore(NewInMemoryCache(), repo, DefaultHybridStoreConfig(), nil, WithWAL(wal, time.Hour))
+	store.ctx = ctx
+	defer wal.Close()
+
+	// The unknown switch violates the foreign key and must not block the batch behind it
+	require.NoError(t, wal.Append(walBatch("switch-unknown", 99)))
+	require.NoError(t, wal.Append(walBatch("switch-001", 40)))
+	store.drainWAL()
+	assert.Equal(t, int64(0), wal.Batches())
+
+	count, err := repo.GetMetricsCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
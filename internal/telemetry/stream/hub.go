@@ -0,0 +1,290 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ufm/internal/log"
+	"github.com/ufm/internal/monitoring/metrics"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+const (
+	// DefaultBufferSize is the number of events buffered per subscriber before the oldest are dropped
+	DefaultBufferSize = 256
+	// DefaultMaxSubscribers bounds the number of concurrently connected stream clients
+	DefaultMaxSubscribers = 100
+)
+
+var (
+	// ErrClosed is returned by Next once the subscription or hub has been closed
+	ErrClosed = errors.New("stream subscription closed")
+	// ErrTooManySubscribers is returned by Subscribe when the hub is at capacity
+	ErrTooManySubscribers = errors.New("too many stream subscribers")
+)
+
+// Config holds configuration for the subscription hub
+type Config struct {
+	BufferSize     int // Events buffered per subscriber
+	MaxSubscribers int // Maximum concurrent subscribers, 0 means unlimited
+}
+
+// DefaultConfig returns sensible defaults
+func DefaultConfig() Config {
+	return Config{
+		BufferSize:     DefaultBufferSize,
+		MaxSubscribers: DefaultMaxSubscribers,
+	}
+}
+
+// Filter selects the switches and metric types a subscriber receives
+type Filter struct {
+	SwitchIDs   []string            // Empty means all switches
+	MetricTypes []models.MetricType // Empty means all metric types
+}
+
+func (f Filter) switchSet() map[string]bool {
+	if len(f.SwitchIDs) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(f.SwitchIDs))
+	for _, switchID := range f.SwitchIDs {
+		set[switchID] = true
+	}
+	return set
+}
+
+// Hub fans out telemetry updates to subscribers without ever blocking the publisher
+type Hub struct {
+	config Config
+	logger log.Logger
+
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	seq         uint64
+	closed      bool
+}
+
+// NewHub creates a subscription hub
+func NewHub(config Config, logger log.Logger) *Hub {
+	if logger == nil {
+		logger = log.DefaultLogger
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultBufferSize
+	}
+
+	return &Hub{
+		config:      config,
+		logger:      logger,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber for updates matching filter
+func (h *Hub) Subscribe(filter Filter) (*Subscription, error) {
+	for _, metricType := range filter.MetricTypes {
+		if !metricType.IsValid() {
+			return nil, fmt.Errorf("invalid metric type: %s", metricType)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+	if h.config.MaxSubscribers > 0 && len(h.subscribers) >= h.config.MaxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+
+	metricTypes := filter.MetricTypes
+	if len(metricTypes) == 0 {
+		metricTypes = models.AllMetricTypes
+	}
+
+	sub := &Subscription{
+		hub:         h,
+		switches:    filter.switchSet(),
+		metricTypes: metricTypes,
+		capacity:    h.config.BufferSize,
+		signal:      make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+	h.subscribers[sub] = struct{}{}
+	metrics.StreamSubscribers.Set(float64(len(h.subscribers)))
+	return sub, nil
+}
+
+// Publish delivers samples to every matching subscriber. Slow subscribers lose
+// their oldest buffered events instead of delaying the caller.
+func (h *Hub) Publish(samples []models.TelemetryData) {
+	if len(samples) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.subscribers) == 0 {
+		return
+	}
+
+	now := time.Now()
+	for i := range samples {
+		sample := &samples[i]
+		timestamp := sample.Timestamp
+		if timestamp.IsZero() {
+			timestamp = now
+		}
+
+		h.seq++
+		for sub := range h.subscribers {
+			if sub.switches != nil && !sub.switches[sample.SwitchID] {
+				continue
+			}
+			sub.push(newEvent(h.seq, timestamp, sample, sub.metricTypes))
+		}
+	}
+}
+
+// SubscriberCount returns the number of connected subscribers
+func (h *Hub) SubscriberCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers)
+}
+
+// Close ends every subscription and rejects new ones
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for sub := range h.subscribers {
+		sub.close()
+		delete(h.subscribers, sub)
+	}
+	metrics.StreamSubscribers.Set(0)
+	h.logger.Infof("Stream hub closed")
+}
+
+func (h *Hub) unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+	delete(h.subscribers, sub)
+	sub.close()
+	metrics.StreamSubscribers.Set(float64(len(h.subscribers)))
+}
+
+func newEvent(seq uint64, timestamp time.Time, sample *models.TelemetryData, metricTypes []models.MetricType) models.StreamEvent {
+	values := make(map[string]float64, len(metricTypes))
+	for _, metricType := range metricTypes {
+		if value, err := sample.GetMetricFloat(metricType); err == nil {
+			values[string(metricType)] = value
+		}
+	}
+
+	return models.StreamEvent{
+		Seq:       seq,
+		SwitchID:  sample.SwitchID,
+		Timestamp: timestamp,
+		Metrics:   values,
+	}
+}
+
+// Subscription is a single client's bounded queue of stream events
+type Subscription struct {
+	hub         *Hub
+	switches    map[string]bool
+	metricTypes []models.MetricType
+	capacity    int
+
+	mu      sync.Mutex
+	queue   []models.StreamEvent
+	dropped uint64
+	closed  bool
+	signal  chan struct{} // Signalled when events are queued
+	done    chan struct{} // Closed when the subscription ends
+}
+
+// push queues an event, evicting the oldest one when the buffer is full
+func (s *Subscription) push(event models.StreamEvent) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	if len(s.queue) >= s.capacity {
+		s.queue = s.queue[1:]
+		s.dropped++
+		metrics.StreamEventsDroppedTotal.Inc()
+	}
+	s.queue = append(s.queue, event)
+	s.mu.Unlock()
+
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Next blocks until events are available and returns all of them along with the
+// number of events dropped since the previous call
+func (s *Subscription) Next(ctx context.Context) ([]models.StreamEvent, uint64, error) {
+	for {
+		s.mu.Lock()
+		if len(s.queue) > 0 || s.dropped > 0 {
+			events := s.queue
+			dropped := s.dropped
+			s.queue = make([]models.StreamEvent, 0, len(events))
+			s.dropped = 0
+			s.mu.Unlock()
+			return events, dropped, nil
+		}
+		closed := s.closed
+		s.mu.Unlock()
+
+		if closed {
+			return nil, 0, ErrClosed
+		}
+
+		select {
+		case <-s.signal:
+		case <-s.done:
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		}
+	}
+}
+
+// Done is closed when the subscription ends
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close unregisters the subscription from its hub
+func (s *Subscription) Close() {
+	s.hub.unsubscribe(s)
+}
+
+func (s *Subscription) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.done)
+}
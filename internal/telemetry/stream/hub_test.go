@@ -0,0 +1,114 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ufm/internal/telemetry/models"
+)
+
+func sample(switchID string, temperature float64) models.TelemetryData {
+	return models.TelemetryData{
+		SwitchID:      switchID,
+		Timestamp:     time.Now(),
+		BandwidthMbps: 1000,
+		TemperatureC:  temperature,
+	}
+}
+
+func next(t *testing.T, sub *Subscription) ([]models.StreamEvent, uint64) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	events, dropped, err := sub.Next(ctx)
+	require.NoError(t, err)
+	return events, dropped
+}
+
+func TestHub_FiltersBySwitchAndMetric(t *testing.T) {
+	hub := NewHub(DefaultConfig(), nil)
+	defer hub.Close()
+
+	sub, err := hub.Subscribe(Filter{
+		SwitchIDs:   []string{"switch-002"},
+		MetricTypes: []models.MetricType{models.MetricTemperature},
+	})
+	require.NoError(t, err)
+
+	hub.Publish([]models.TelemetryData{sample("switch-001", 40), sample("switch-002", 50)})
+
+	events, dropped := next(t, sub)
+	assert.Zero(t, dropped)
+	require.Len(t, events, 1)
+	assert.Equal(t, "switch-002", events[0].SwitchID)
+	assert.Equal(t, map[string]float64{string(models.MetricTemperature): 50}, events[0].Metrics)
+}
+
+func TestHub_DropsOldestWhenSubscriberFallsBehind(t *testing.T) {
+	hub := NewHub(Config{BufferSize: 3}, nil)
+	defer hub.Close()
+
+	sub, err := hub.Subscribe(Filter{})
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		hub.Publish([]models.TelemetryData{sample("switch-001", float64(40+i))})
+	}
+
+	events, dropped := next(t, sub)
+	assert.Equal(t, uint64(2), dropped)
+	require.Len(t, events, 3)
+	assert.Equal(t, 42.0, events[0].Metrics[string(models.MetricTemperature)])
+	assert.Equal(t, 44.0, events[2].Metrics[string(models.MetricTemperature)])
+	assert.Less(t, events[0].Seq, events[2].Seq)
+}
+
+func TestHub_MaxSubscribers(t *testing.T) {
+	hub := NewHub(Config{MaxSubscribers: 1}, nil)
+	defer hub.Close()
+
+	sub, err := hub.Subscribe(Filter{})
+	require.NoError(t, err)
+
+	_, err = hub.Subscribe(Filter{})
+	assert.ErrorIs(t, err, ErrTooManySubscribers)
+
+	sub.Close()
+	assert.Equal(t, 0, hub.SubscriberCount())
+	_, err = hub.Subscribe(Filter{})
+	assert.NoError(t, err)
+}
+
+func TestHub_CloseEndsSubscriptions(t *testing.T) {
+	hub := NewHub(DefaultConfig(), nil)
+	sub, err := hub.Subscribe(Filter{})
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := sub.Next(context.Background())
+		done <- err
+	}()
+
+	hub.Close()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrClosed)
+	case <-time.After(time.Second):
+		t.Fatal("Next did not return after hub was closed")
+	}
+
+	_, err = hub.Subscribe(Filter{})
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestHub_RejectsInvalidMetricType(t *testing.T) {
+	hub := NewHub(DefaultConfig(), nil)
+	defer hub.Close()
+
+	_, err := hub.Subscribe(Filter{MetricTypes: []models.MetricType{"unknown"}})
+	assert.Error(t, err)
+}
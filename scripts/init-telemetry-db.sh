@@ -36,45 +36,21 @@ echo -e "${YELLOW}Creating database '$DB_NAME' if it doesn't exist...${NC}"
 PGPASSWORD=$DB_PASSWORD psql -h $DB_HOST -p $DB_PORT -U $DB_USER -d postgres -c "CREATE DATABASE $DB_NAME;" 2>/dev/null || true
 echo -e "${GREEN}✓ Database '$DB_NAME' ready${NC}"
 
-# Run migrations
+# Run migrations through the server's embedded migration runner, which records
+# applied versions in schema_migrations so re-running this script is safe
 echo -e "${YELLOW}Running telemetry database migrations...${NC}"
-MIGRATION_DIR="$(dirname "$0")/migrations"
+DATABASE_URL="postgres://$DB_USER:$DB_PASSWORD@$DB_HOST:$DB_PORT/$DB_NAME?sslmode=disable"
 
-# Run first migration (create tables)
-MIGRATION_FILE_1="$MIGRATION_DIR/001_create_telemetry_tables.sql"
-if [ ! -f "$MIGRATION_FILE_1" ]; then
-    echo -e "${RED}Error: Migration file not found: $MIGRATION_FILE_1${NC}"
-    exit 1
-fi
-
-PGPASSWORD=$DB_PASSWORD psql -h $DB_HOST -p $DB_PORT -U $DB_USER -d $DB_NAME -f "$MIGRATION_FILE_1"
-
-if [ $? -eq 0 ]; then
-    echo -e "${GREEN}✓ Telemetry tables migration completed successfully${NC}"
-else
-    echo -e "${RED}✗ Tables migration failed${NC}"
-    exit 1
-fi
-
-# Run second migration (create user and permissions)
-MIGRATION_FILE_2="$MIGRATION_DIR/002_create_umf_user.sql"
-if [ ! -f "$MIGRATION_FILE_2" ]; then
-    echo -e "${RED}Error: Migration file not found: $MIGRATION_FILE_2${NC}"
-    exit 1
-fi
-
-PGPASSWORD=$DB_PASSWORD psql -h $DB_HOST -p $DB_PORT -U $DB_USER -d $DB_NAME -f "$MIGRATION_FILE_2"
-
-if [ $? -eq 0 ]; then
-    echo -e "${GREEN}✓ User creation and permissions migration completed successfully${NC}"
+if go run "$(dirname "$0")/../cmd/server" migrate -url "$DATABASE_URL" up; then
+    echo -e "${GREEN}✓ Migrations completed successfully${NC}"
 else
-    echo -e "${RED}✗ User migration failed${NC}"
+    echo -e "${RED}✗ Migrations failed${NC}"
     exit 1
 fi
 
 # Verify the setup
 echo -e "${YELLOW}Verifying database setup...${NC}"
-TABLE_COUNT=$(PGPASSWORD=$DB_PASSWORD psql -h $DB_HOST -p $DB_PORT -U $DB_USER -d $DB_NAME -t -c "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = 'public' AND table_name IN ('switches', 'telemetry_metrics');" | tr -d ' ')
+TABLE_COUNT=$(PGPASSWORD=$DB_PASSWORD psql -h $DB_HOST -p $DB_PORT -U $DB_USER -d $DB_NAME -t -c "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = 'public' AND table_name IN ('switches', 'telemetry_metrics', 'alert_events', 'telemetry_rollup_1m', 'telemetry_rollup_1h');" | tr -d ' ')
 
 echo -e "${GREEN}✓ Database verification complete${NC}"
 echo -e "${GREEN}✓ Tables created: $TABLE_COUNT${NC}"
@@ -0,0 +1,30 @@
+#!/bin/bash
+
+# Applies the schema migrations when the postgres container initializes an empty
+# data directory. Each version is recorded in schema_migrations in the same
+# transaction, so the server's migration runner only applies newer ones.
+
+set -euo pipefail
+
+MIGRATIONS_DIR=${MIGRATIONS_DIR:-/migrations}
+
+psql=(psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB")
+
+"${psql[@]}" <<'SQL'
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+SQL
+
+for file in "$MIGRATIONS_DIR"/[0-9]*_*.sql; do
+    base=$(basename "$file" .sql)
+    version=$((10#${base%%_*}))
+    name=${base#*_}
+
+    echo "Applying migration $base"
+    "${psql[@]}" --single-transaction \
+        -f "$file" \
+        -c "INSERT INTO schema_migrations (version, name) VALUES ($version, '$name')"
+done
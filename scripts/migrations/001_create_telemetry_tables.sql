@@ -29,21 +29,23 @@ CREATE INDEX IF NOT EXISTS idx_telemetry_timestamp ON telemetry_metrics(timestam
 CREATE INDEX IF NOT EXISTS idx_telemetry_switch_time ON telemetry_metrics(switch_id, timestamp DESC);
 CREATE INDEX IF NOT EXISTS idx_telemetry_created_at ON telemetry_metrics(created_at DESC);
 
--- Add constraints
-ALTER TABLE telemetry_metrics 
-ADD CONSTRAINT chk_bandwidth_positive CHECK (bandwidth_mbps >= 0);
-
-ALTER TABLE telemetry_metrics 
-ADD CONSTRAINT chk_latency_positive CHECK (latency_ms >= 0);
-
-ALTER TABLE telemetry_metrics 
-ADD CONSTRAINT chk_packet_errors_positive CHECK (packet_errors >= 0);
-
-ALTER TABLE telemetry_metrics 
-ADD CONSTRAINT chk_utilization_range CHECK (utilization_pct >= 0 AND utilization_pct <= 100);
-
-ALTER TABLE telemetry_metrics 
-ADD CONSTRAINT chk_temperature_range CHECK (temperature_c >= -50 AND temperature_c <= 150);
-
-
-
+-- Add constraints (guarded so re-running against an existing schema is a no-op)
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_bandwidth_positive') THEN
+        ALTER TABLE telemetry_metrics ADD CONSTRAINT chk_bandwidth_positive CHECK (bandwidth_mbps >= 0);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_latency_positive') THEN
+        ALTER TABLE telemetry_metrics ADD CONSTRAINT chk_latency_positive CHECK (latency_ms >= 0);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_packet_errors_positive') THEN
+        ALTER TABLE telemetry_metrics ADD CONSTRAINT chk_packet_errors_positive CHECK (packet_errors >= 0);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_utilization_range') THEN
+        ALTER TABLE telemetry_metrics ADD CONSTRAINT chk_utilization_range CHECK (utilization_pct >= 0 AND utilization_pct <= 100);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_temperature_range') THEN
+        ALTER TABLE telemetry_metrics ADD CONSTRAINT chk_temperature_range CHECK (temperature_c >= -50 AND temperature_c <= 150);
+    END IF;
+END
+$$;
@@ -0,0 +1,37 @@
+-- Migration: 003_create_alert_tables.sql
+-- Description: Create table for alert firing/resolved history
+-- Date: 2026-10-16
+
+-- Create alert_events table
+CREATE TABLE IF NOT EXISTS alert_events (
+    id BIGSERIAL PRIMARY KEY,
+    rule_name VARCHAR(100) NOT NULL,
+    switch_id VARCHAR(50) NOT NULL,
+    metric VARCHAR(50) NOT NULL,
+    comparator VARCHAR(2) NOT NULL,
+    threshold DOUBLE PRECISION NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    state VARCHAR(20) NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    active_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes for history queries
+CREATE INDEX IF NOT EXISTS idx_alert_events_timestamp ON alert_events(timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_alert_events_switch_time ON alert_events(switch_id, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_alert_events_rule_time ON alert_events(rule_name, timestamp DESC);
+
+-- Add constraints (guarded so re-running against an existing schema is a no-op)
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_alert_state') THEN
+        ALTER TABLE alert_events ADD CONSTRAINT chk_alert_state CHECK (state IN ('firing', 'resolved'));
+    END IF;
+END
+$$;
+
+-- Grant permissions to umf_user
+GRANT SELECT, INSERT, UPDATE, DELETE ON alert_events TO umf_user;
+GRANT USAGE, SELECT ON SEQUENCE alert_events_id_seq TO umf_user;
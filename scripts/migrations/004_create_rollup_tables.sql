@@ -0,0 +1,57 @@
+-- Migration: 004_create_rollup_tables.sql
+-- Description: Create downsampled telemetry tables maintained by the retention manager
+-- Date: 2026-10-16
+
+-- Create telemetry_rollup_1m table (one row per switch per minute)
+CREATE TABLE IF NOT EXISTS telemetry_rollup_1m (
+    switch_id VARCHAR(50) NOT NULL REFERENCES switches(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    sample_count BIGINT NOT NULL,
+    bandwidth_mbps_avg DOUBLE PRECISION NOT NULL,
+    bandwidth_mbps_min DOUBLE PRECISION NOT NULL,
+    bandwidth_mbps_max DOUBLE PRECISION NOT NULL,
+    latency_ms_avg DOUBLE PRECISION NOT NULL,
+    latency_ms_min DOUBLE PRECISION NOT NULL,
+    latency_ms_max DOUBLE PRECISION NOT NULL,
+    packet_errors_avg DOUBLE PRECISION NOT NULL,
+    packet_errors_min DOUBLE PRECISION NOT NULL,
+    packet_errors_max DOUBLE PRECISION NOT NULL,
+    utilization_pct_avg DOUBLE PRECISION NOT NULL,
+    utilization_pct_min DOUBLE PRECISION NOT NULL,
+    utilization_pct_max DOUBLE PRECISION NOT NULL,
+    temperature_c_avg DOUBLE PRECISION NOT NULL,
+    temperature_c_min DOUBLE PRECISION NOT NULL,
+    temperature_c_max DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (switch_id, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rollup_1m_bucket ON telemetry_rollup_1m(bucket DESC);
+
+-- Create telemetry_rollup_1h table (one row per switch per hour)
+CREATE TABLE IF NOT EXISTS telemetry_rollup_1h (
+    switch_id VARCHAR(50) NOT NULL REFERENCES switches(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    sample_count BIGINT NOT NULL,
+    bandwidth_mbps_avg DOUBLE PRECISION NOT NULL,
+    bandwidth_mbps_min DOUBLE PRECISION NOT NULL,
+    bandwidth_mbps_max DOUBLE PRECISION NOT NULL,
+    latency_ms_avg DOUBLE PRECISION NOT NULL,
+    latency_ms_min DOUBLE PRECISION NOT NULL,
+    latency_ms_max DOUBLE PRECISION NOT NULL,
+    packet_errors_avg DOUBLE PRECISION NOT NULL,
+    packet_errors_min DOUBLE PRECISION NOT NULL,
+    packet_errors_max DOUBLE PRECISION NOT NULL,
+    utilization_pct_avg DOUBLE PRECISION NOT NULL,
+    utilization_pct_min DOUBLE PRECISION NOT NULL,
+    utilization_pct_max DOUBLE PRECISION NOT NULL,
+    temperature_c_avg DOUBLE PRECISION NOT NULL,
+    temperature_c_min DOUBLE PRECISION NOT NULL,
+    temperature_c_max DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (switch_id, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rollup_1h_bucket ON telemetry_rollup_1h(bucket DESC);
+
+-- Grant permissions to umf_user
+GRANT SELECT, INSERT, UPDATE, DELETE ON telemetry_rollup_1m TO umf_user;
+GRANT SELECT, INSERT, UPDATE, DELETE ON telemetry_rollup_1h TO umf_user;
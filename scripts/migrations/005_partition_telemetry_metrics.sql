@@ -0,0 +1,70 @@
+-- Migration: 005_partition_telemetry_metrics.sql
+-- Description: Convert telemetry_metrics into a table range-partitioned by timestamp
+-- Date: 2026-10-16
+
+-- The server creates partitions ahead of time and drops expired ones; rows outside
+-- every partition land in telemetry_metrics_default. Existing rows are copied into
+-- daily (UTC) partitions. Skipped when the table is already partitioned.
+DO $$
+DECLARE
+    partition_day DATE;
+    last_day DATE;
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'telemetry_metrics'::regclass) THEN
+        RETURN;
+    END IF;
+
+    -- Move the unpartitioned table aside, keeping its id sequence for the new table
+    ALTER TABLE telemetry_metrics RENAME TO telemetry_metrics_unpartitioned;
+    ALTER INDEX telemetry_metrics_pkey RENAME TO telemetry_metrics_unpartitioned_pkey;
+    ALTER SEQUENCE telemetry_metrics_id_seq OWNED BY NONE;
+
+    -- The partition key must be part of the primary key
+    CREATE TABLE telemetry_metrics (
+        id BIGINT NOT NULL DEFAULT nextval('telemetry_metrics_id_seq'),
+        switch_id VARCHAR(50) NOT NULL REFERENCES switches(id) ON DELETE CASCADE,
+        timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+        bandwidth_mbps DECIMAL(10,2) NOT NULL DEFAULT 0,
+        latency_ms DECIMAL(8,3) NOT NULL DEFAULT 0,
+        packet_errors BIGINT NOT NULL DEFAULT 0,
+        utilization_pct DECIMAL(5,2) NOT NULL DEFAULT 0,
+        temperature_c DECIMAL(5,2) NOT NULL DEFAULT 0,
+        created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+        PRIMARY KEY (id, timestamp),
+        CONSTRAINT chk_bandwidth_positive CHECK (bandwidth_mbps >= 0),
+        CONSTRAINT chk_latency_positive CHECK (latency_ms >= 0),
+        CONSTRAINT chk_packet_errors_positive CHECK (packet_errors >= 0),
+        CONSTRAINT chk_utilization_range CHECK (utilization_pct >= 0 AND utilization_pct <= 100),
+        CONSTRAINT chk_temperature_range CHECK (temperature_c >= -50 AND temperature_c <= 150)
+    ) PARTITION BY RANGE (timestamp);
+    ALTER SEQUENCE telemetry_metrics_id_seq OWNED BY telemetry_metrics.id;
+
+    CREATE TABLE telemetry_metrics_default PARTITION OF telemetry_metrics DEFAULT;
+
+    -- One partition per UTC day that holds existing data
+    SELECT MIN(timestamp AT TIME ZONE 'UTC')::date, MAX(timestamp AT TIME ZONE 'UTC')::date
+    INTO partition_day, last_day
+    FROM telemetry_metrics_unpartitioned;
+
+    WHILE partition_day <= last_day LOOP
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF telemetry_metrics FOR VALUES FROM (%L) TO (%L)',
+            'telemetry_metrics_p' || to_char(partition_day, 'YYYYMMDD'),
+            partition_day::timestamp AT TIME ZONE 'UTC',
+            (partition_day + 1)::timestamp AT TIME ZONE 'UTC');
+        partition_day := partition_day + 1;
+    END LOOP;
+
+    INSERT INTO telemetry_metrics SELECT * FROM telemetry_metrics_unpartitioned;
+    DROP TABLE telemetry_metrics_unpartitioned;
+
+    -- Indexes on the parent are created on every partition, current and future
+    CREATE INDEX idx_telemetry_switch_id ON telemetry_metrics(switch_id);
+    CREATE INDEX idx_telemetry_timestamp ON telemetry_metrics(timestamp DESC);
+    CREATE INDEX idx_telemetry_switch_time ON telemetry_metrics(switch_id, timestamp DESC);
+    CREATE INDEX idx_telemetry_created_at ON telemetry_metrics(created_at DESC);
+
+    -- Access through the parent covers every partition
+    GRANT SELECT, INSERT, UPDATE, DELETE ON telemetry_metrics TO umf_user;
+END
+$$;
@@ -0,0 +1,5 @@
+-- Rollback: 001_create_telemetry_tables.sql
+-- Description: Drop telemetry tables and their data
+
+DROP TABLE IF EXISTS telemetry_metrics;
+DROP TABLE IF EXISTS switches;
@@ -0,0 +1,17 @@
+-- Rollback: 002_create_umf_user.sql
+-- Description: Revoke umf_user permissions and drop the role
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT FROM pg_catalog.pg_roles WHERE rolname = 'umf_user') THEN
+        ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM umf_user;
+        ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE USAGE, SELECT ON SEQUENCES FROM umf_user;
+        REVOKE ALL ON ALL TABLES IN SCHEMA public FROM umf_user;
+        REVOKE ALL ON ALL SEQUENCES IN SCHEMA public FROM umf_user;
+        REVOKE USAGE ON SCHEMA public FROM umf_user;
+        -- The rollback may run against a database with any name
+        EXECUTE format('REVOKE CONNECT ON DATABASE %I FROM umf_user', current_database());
+        DROP USER umf_user;
+    END IF;
+END
+$$;
@@ -0,0 +1,4 @@
+-- Rollback: 003_create_alert_tables.sql
+-- Description: Drop alert history table
+
+DROP TABLE IF EXISTS alert_events;
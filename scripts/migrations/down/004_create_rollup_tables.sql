@@ -0,0 +1,5 @@
+-- Rollback: 004_create_rollup_tables.sql
+-- Description: Drop downsampled telemetry tables
+
+DROP TABLE IF EXISTS telemetry_rollup_1h;
+DROP TABLE IF EXISTS telemetry_rollup_1m;
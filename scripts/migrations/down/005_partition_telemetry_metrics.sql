@@ -0,0 +1,43 @@
+-- Rollback: 005_partition_telemetry_metrics.sql
+-- Description: Copy partitioned telemetry_metrics back into a single table
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'telemetry_metrics'::regclass) THEN
+        RETURN;
+    END IF;
+
+    ALTER TABLE telemetry_metrics RENAME TO telemetry_metrics_partitioned;
+    ALTER INDEX telemetry_metrics_pkey RENAME TO telemetry_metrics_partitioned_pkey;
+    ALTER SEQUENCE telemetry_metrics_id_seq OWNED BY NONE;
+
+    CREATE TABLE telemetry_metrics (
+        id BIGINT PRIMARY KEY DEFAULT nextval('telemetry_metrics_id_seq'),
+        switch_id VARCHAR(50) NOT NULL REFERENCES switches(id) ON DELETE CASCADE,
+        timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+        bandwidth_mbps DECIMAL(10,2) NOT NULL DEFAULT 0,
+        latency_ms DECIMAL(8,3) NOT NULL DEFAULT 0,
+        packet_errors BIGINT NOT NULL DEFAULT 0,
+        utilization_pct DECIMAL(5,2) NOT NULL DEFAULT 0,
+        temperature_c DECIMAL(5,2) NOT NULL DEFAULT 0,
+        created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+        CONSTRAINT chk_bandwidth_positive CHECK (bandwidth_mbps >= 0),
+        CONSTRAINT chk_latency_positive CHECK (latency_ms >= 0),
+        CONSTRAINT chk_packet_errors_positive CHECK (packet_errors >= 0),
+        CONSTRAINT chk_utilization_range CHECK (utilization_pct >= 0 AND utilization_pct <= 100),
+        CONSTRAINT chk_temperature_range CHECK (temperature_c >= -50 AND temperature_c <= 150)
+    );
+    ALTER SEQUENCE telemetry_metrics_id_seq OWNED BY telemetry_metrics.id;
+
+    INSERT INTO telemetry_metrics SELECT * FROM telemetry_metrics_partitioned;
+    -- Drops every partition along with the parent
+    DROP TABLE telemetry_metrics_partitioned;
+
+    CREATE INDEX idx_telemetry_switch_id ON telemetry_metrics(switch_id);
+    CREATE INDEX idx_telemetry_timestamp ON telemetry_metrics(timestamp DESC);
+    CREATE INDEX idx_telemetry_switch_time ON telemetry_metrics(switch_id, timestamp DESC);
+    CREATE INDEX idx_telemetry_created_at ON telemetry_metrics(created_at DESC);
+
+    GRANT SELECT, INSERT, UPDATE, DELETE ON telemetry_metrics TO umf_user;
+END
+$$;
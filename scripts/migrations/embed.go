@@ -0,0 +1,12 @@
+// Package migrations embeds the PostgreSQL schema migrations so the server can
+// apply them itself. Up scripts are named NNN_name.sql; the matching rollback is
+// down/NNN_name.sql. The docker-compose postgres service applies the same files
+// through scripts/initdb, which records them in schema_migrations.
+package migrations
+
+import "embed"
+
+// FS holds the up and down migration scripts
+//
+//go:embed *.sql down/*.sql
+var FS embed.FS
@@ -213,9 +213,29 @@ func (m *MockRepository) GetLatestMetrics(ctx context.Context, switchID string)
 	}, nil
 }
 
-func (m *MockRepository) GetHistoricalMetrics(ctx context.Context, switchID string, from, to time.Time) ([]models.TelemetryData, error) {
+func (m *MockRepository) GetHistoricalMetrics(ctx context.Context, query models.HistoryQuery) (*models.HistoryPage, error) {
 	// Mock get historical metrics operation
-	return []models.TelemetryData{}, nil
+	return &models.HistoryPage{Data: []models.TelemetryData{}}, nil
+}
+
+func (m *MockRepository) GetAggregatedMetrics(ctx context.Context, query models.HistoryQuery) ([]models.MetricBucket, error) {
+	// Mock get aggregated metrics operation
+	return []models.MetricBucket{}, nil
+}
+
+func (m *MockRepository) GetSwitchMetricsSummary(ctx context.Context, switchID string, window time.Duration) (*models.SwitchSummary, error) {
+	// Mock get switch metrics summary operation
+	return &models.SwitchSummary{
+		SwitchID:  switchID,
+		Window:    window.String(),
+		Metrics:   map[string]models.MetricSummary{},
+		Timestamp: time.Now(),
+	}, nil
+}
+
+func (m *MockRepository) GetTopSwitches(ctx context.Context, query models.TopSwitchesQuery) ([]models.RankedSwitch, error) {
+	// Mock get top switches operation
+	return []models.RankedSwitch{}, nil
 }
 
 func (m *MockRepository) DeleteOldMetrics(ctx context.Context, olderThan time.Time) error {